@@ -0,0 +1,92 @@
+// Package arena implements the allocators of the objects of a
+// compilation, such as the nodes of the AST: arenas handing them out
+// from large slabs and dropping them all at once.
+package arena
+
+import "reflect"
+
+// Arenas
+//
+// A translation unit makes a node for each piece of syntax, which
+// allocated one by one are many small objects for the garbage collector
+// to track and are scattered in memory. An arena instead hands them out
+// from slabs, arrays of objects allocated at once, in the order they are
+// made, so that the nodes of a statement are next to each other. Tokens
+// need no arena, being kept in a single slice by the lexer. The first
+// slab is small, so that short programs stay cheap, and each slab is
+// twice the size of the previous one up to maxSlab objects.
+//
+// Go has no way to free memory, so freeing an arena drops its slabs,
+// which the garbage collector reclaims once nothing points into them
+// any more. Objects still in use, such as the nodes of an AST kept
+// after the compilation, remain valid. Arenas are not safe for
+// concurrent use.
+
+const (
+	minSlab = 64   // Objects in the first slab
+	maxSlab = 4096 // Objects in the largest slabs
+)
+
+// An allocator of objects of type T.
+type Arena[T any] struct {
+	slab  []T // Current slab, whose free objects are those after its length
+	slabs int // Number of slabs allocated since the arena was last freed
+}
+
+// Return a pointer to a new zero T, allocated from the heap if the
+// arena is nil.
+func (a *Arena[T]) New() *T {
+	if a == nil {
+		return new(T)
+	}
+	if len(a.slab) == cap(a.slab) {
+		size := minSlab
+		for i := 0; i < a.slabs && size < maxSlab; i++ {
+			size *= 2
+		}
+		a.slab = make([]T, 0, size)
+		a.slabs++
+	}
+	a.slab = a.slab[:len(a.slab)+1]
+	return &a.slab[len(a.slab)-1]
+}
+
+// Drop the slabs of the arena, which starts over with a small slab.
+func (a *Arena[T]) Free() {
+	a.slab, a.slabs = nil, 0
+}
+
+// Arenas of the objects of a compilation, one for each type. The zero
+// value is an empty set.
+type Set struct {
+	arenas map[reflect.Type]freer
+}
+
+type freer interface {
+	Free()
+}
+
+// Return the arena of the objects of type T in the set `s`, if `s` is
+// not nil, or nil otherwise.
+func Of[T any](s *Set) *Arena[T] {
+	if s == nil {
+		return nil
+	}
+	key := reflect.TypeOf((*T)(nil))
+	if a, ok := s.arenas[key]; ok {
+		return a.(*Arena[T])
+	}
+	if s.arenas == nil {
+		s.arenas = map[reflect.Type]freer{}
+	}
+	a := &Arena[T]{}
+	s.arenas[key] = a
+	return a
+}
+
+// Free the arenas of the set.
+func (s *Set) Free() {
+	for _, a := range s.arenas {
+		a.Free()
+	}
+}
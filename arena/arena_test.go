@@ -0,0 +1,42 @@
+package arena
+
+import "testing"
+
+type pair struct{ a, b int }
+
+func TestArena(t *testing.T) {
+	var s Set
+	a := Of[pair](&s)
+	if Of[pair](&s) != a {
+		t.Fatal("the set has two arenas of pairs")
+	}
+	var objects []*pair
+	for i := 0; i < minSlab+maxSlab; i++ {
+		p := a.New()
+		if *p != (pair{}) {
+			t.Fatalf("object %d is %v, want zero", i, *p)
+		}
+		p.a = i
+		objects = append(objects, p)
+	}
+	for i, p := range objects {
+		if p.a != i {
+			t.Fatalf("object %d was overwritten with %d", i, p.a)
+		}
+	}
+	if cap(a.slab) != maxSlab {
+		t.Errorf("the slab holds %d pairs after %d slabs, want %d", cap(a.slab), a.slabs, maxSlab)
+	}
+
+	s.Free()
+	if a.New(); a.slabs != 1 || cap(a.slab) != minSlab {
+		t.Errorf("a freed arena has %d slabs of %d pairs, want 1 of %d", a.slabs, cap(a.slab), minSlab)
+	}
+	if objects[0].a != 0 {
+		t.Error("freeing the arena changed its objects")
+	}
+
+	if p := Of[pair](nil).New(); p == nil || *p != (pair{}) {
+		t.Error("the nil arena does not allocate from the heap")
+	}
+}
@@ -0,0 +1,198 @@
+// Package asm assembles the x86-64 assembly printed by gocc into a
+// relocatable ELF64 object, so that object files can be produced
+// without an external assembler.
+//
+// Only the subset of the AT&T syntax gocc emits is understood. Line
+// information (.file/.loc) and call frame information (.cfi_*) are
+// accepted but not encoded yet.
+package asm
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A section being assembled.
+type section struct {
+	name   string
+	typ    uint32 // SHT_* type
+	flags  uint64 // SHF_* flags
+	align  uint64
+	data   []byte
+	relocs []reloc
+	index  int // Index in the section header table
+}
+
+// A relocation to apply to a section at link time.
+type reloc struct {
+	offset uint64
+	typ    uint32 // R_X86_64_* type
+	symbol string // Referenced symbol, or a section name for local labels
+	addend int64
+}
+
+// A label or symbol defined in the source.
+type symbol struct {
+	name    string
+	section *section
+	offset  uint64
+	global  bool
+	fn      bool // Whether .type marked it as a function
+	size    uint64
+	defined bool
+}
+
+// A reference to a label whose address is only known once
+// the whole source has been read.
+type fixup struct {
+	section *section
+	offset  uint64 // Offset of the 32-bit field to patch
+	label   string
+	line    int
+}
+
+type assembler struct {
+	sections []*section
+	curr     *section
+	symbols  map[string]*symbol
+	order    []string // Symbol names in order of first appearance
+	fixups   []fixup
+	line     int
+}
+
+// Assemble translates assembly source into an ELF64 relocatable object.
+func Assemble(src string) ([]byte, error) {
+	a := &assembler{symbols: map[string]*symbol{}}
+	a.curr = a.section(".text")
+	for i, line := range strings.Split(src, "\n") {
+		a.line = i + 1
+		if err := a.assembleLine(line); err != nil {
+			return nil, fmt.Errorf("line %d: %v", a.line, err)
+		}
+	}
+	if err := a.resolve(); err != nil {
+		return nil, err
+	}
+	return a.writeELF(), nil
+}
+
+// Return the section called `name`, creating it on first use.
+func (a *assembler) section(name string) *section {
+	for _, s := range a.sections {
+		if s.name == name {
+			return s
+		}
+	}
+	s := &section{name: name, typ: shtProgbits, align: 1}
+	switch name {
+	case ".text":
+		s.flags, s.align = shfAlloc|shfExecinstr, 16
+	case ".rodata":
+		s.flags = shfAlloc
+	}
+	a.sections = append(a.sections, s)
+	return s
+}
+
+func (a *assembler) symbol(name string) *symbol {
+	sym, ok := a.symbols[name]
+	if !ok {
+		sym = &symbol{name: name}
+		a.symbols[name] = sym
+		a.order = append(a.order, name)
+	}
+	return sym
+}
+
+func (a *assembler) assembleLine(line string) error {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil
+	}
+	if strings.HasSuffix(line, ":") {
+		sym := a.symbol(strings.TrimSuffix(line, ":"))
+		if sym.defined {
+			return fmt.Errorf("symbol %s is already defined", sym.name)
+		}
+		sym.section, sym.offset, sym.defined = a.curr, uint64(len(a.curr.data)), true
+		return nil
+	}
+	op, rest, _ := strings.Cut(line, " ")
+	var args []string
+	if rest = strings.TrimSpace(rest); rest != "" {
+		if op == ".ascii" {
+			args = []string{rest}
+		} else {
+			for _, arg := range strings.Split(rest, ",") {
+				args = append(args, strings.TrimSpace(arg))
+			}
+		}
+	}
+	if strings.HasPrefix(op, ".") {
+		return a.directive(op, args)
+	}
+	return a.instruction(op, args)
+}
+
+func (a *assembler) directive(op string, args []string) error {
+	switch op {
+	case ".text":
+		a.curr = a.section(".text")
+	case ".section":
+		if len(args) == 0 {
+			return fmt.Errorf(".section needs a name")
+		}
+		if args[0] != ".text" && args[0] != ".rodata" && args[0] != ".note.GNU-stack" {
+			return fmt.Errorf("unsupported section %s", args[0])
+		}
+		a.curr = a.section(args[0])
+	case ".globl":
+		a.symbol(args[0]).global = true
+	case ".type":
+		a.symbol(args[0]).fn = len(args) == 2 && args[1] == "@function"
+	case ".size":
+		sym := a.symbol(args[0])
+		if len(args) != 2 || args[1] != ".-"+sym.name || !sym.defined || sym.section != a.curr {
+			return fmt.Errorf("unsupported .size expression")
+		}
+		sym.size = uint64(len(a.curr.data)) - sym.offset
+	case ".ascii":
+		s, err := strconv.Unquote(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid string %s", args[0])
+		}
+		a.curr.data = append(a.curr.data, s...)
+	case ".file", ".loc":
+	default:
+		if !strings.HasPrefix(op, ".cfi_") {
+			return fmt.Errorf("unsupported directive %s", op)
+		}
+	}
+	return nil
+}
+
+// Patch the 32-bit PC-relative fields referring to local labels,
+// turning references across sections into relocations.
+func (a *assembler) resolve() error {
+	for _, f := range a.fixups {
+		sym, ok := a.symbols[f.label]
+		if !ok || !sym.defined {
+			return fmt.Errorf("line %d: undefined label %s", f.line, f.label)
+		}
+		// The displacement is relative to the end of the field.
+		if sym.section == f.section {
+			rel := int64(sym.offset) - int64(f.offset+4)
+			binary.LittleEndian.PutUint32(f.section.data[f.offset:], uint32(int32(rel)))
+			continue
+		}
+		f.section.relocs = append(f.section.relocs, reloc{
+			offset: f.offset,
+			typ:    rX86_64PC32,
+			symbol: sym.section.name,
+			addend: int64(sym.offset) - 4,
+		})
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package asm
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+)
+
+// Assemble `src` and read the result back as an ELF object.
+func assemble(t *testing.T, src string) *elf.File {
+	t.Helper()
+	obj, err := Assemble(src)
+	if err != nil {
+		t.Fatalf("cannot assemble %q: %v", src, err)
+	}
+	f, err := elf.NewFile(bytes.NewReader(obj))
+	if err != nil {
+		t.Fatalf("cannot read the object of %q: %v", src, err)
+	}
+	return f
+}
+
+// The instructions gocc emits, encoded like GNU as does, except for
+// `mov $42, %edi`, which is given its equally valid ModRM form.
+func TestEncoding(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"push %rbp", "55"},
+		{"pop %rbp", "5d"},
+		{"mov %rsp, %rbp", "4889e5"},
+		{"mov %rbp, %rsp", "4889ec"},
+		{"sub $16, %rsp", "4883ec10"},
+		{"sub $1024, %rsp", "4881ec00040000"},
+		{"add $16, %rsp", "4883c410"},
+		{"mov $42, %rax", "48c7c02a000000"},
+		{"mov $-1, %rax", "48c7c0ffffffff"},
+		{"mov $4294967296, %rax", "48b80000000001000000"},
+		{"mov $42, %edi", "c7c72a000000"},
+		{"mov %rax, %rdi", "4889c7"},
+		{"mov %rax, -8(%rbp)", "488945f8"},
+		{"mov -8(%rbp), %rax", "488b45f8"},
+		{"mov %eax, -4(%rbp)", "8945fc"},
+		{"mov %eax, 12(%rsp)", "8944240c"},
+		{"mov 8(%rsp), %rax", "488b442408"},
+		{"mov %eax, (%rdi)", "8907"},
+		{"mov (%rax), %rax", "488b00"},
+		{"mov %r8, %r9", "4d89c1"},
+		{"mov %r10d, -300(%rbp)", "448995d4feffff"},
+		{"movslq -4(%rbp), %rax", "486345fc"},
+		{"movslq 4(%rsp), %rax", "4863442404"},
+		{"movslq %eax, %rax", "4863c0"},
+		{"lea -8(%rbp), %rax", "488d45f8"},
+		{"lea 16(%rsp), %rax", "488d442410"},
+		{"add %edi, %eax", "01f8"},
+		{"sub %edi, %eax", "29f8"},
+		{"imul %edi, %eax", "0fafc7"},
+		{"cdq", "99"},
+		{"cqo", "4899"},
+		{"idiv %edi", "f7ff"},
+		{"idiv %rdi", "48f7ff"},
+		{"neg %eax", "f7d8"},
+		{"cmp %edi, %eax", "39f8"},
+		{"cmp $0, %rax", "4883f800"},
+		{"test %edi, %edi", "85ff"},
+		{"sete %al", "0f94c0"},
+		{"setne %al", "0f95c0"},
+		{"setl %al", "0f9cc0"},
+		{"setle %al", "0f9ec0"},
+		{"movzb %al, %rax", "480fb6c0"},
+		{"cmovne %rdi, %rax", "480f45c7"},
+		{"cmove %edi, %eax", "0f44c7"},
+		{"mov %fs:40, %rax", "64488b042528000000"},
+		{"sub %fs:40, %rdx", "64482b142528000000"},
+		{"ret", "c3"},
+	}
+	for _, test := range tests {
+		text, err := assemble(t, test.in+"\n").Section(".text").Data()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := hex.EncodeToString(text); got != test.want {
+			t.Errorf("%s is encoded as %s, want %s", test.in, got, test.want)
+		}
+	}
+}
+
+// The sections, symbols and relocations of a function with a string,
+// a branch, a GOT load and a call.
+func TestELF(t *testing.T) {
+	f := assemble(t, `	.text
+	.globl main
+	.type main, @function
+main:
+	push %rbp
+	lea .L.str(%rip), %rsi
+	mov __stack_chk_guard@GOTPCREL(%rip), %rcx
+	je .L.end
+	call write@PLT
+.L.end:
+	pop %rbp
+	ret
+	.size main, .-main
+	.section .rodata
+.L.str:
+	.ascii "hi\n"
+	.section .note.GNU-stack,"",@progbits
+`)
+	if f.Class != elf.ELFCLASS64 || f.Type != elf.ET_REL || f.Machine != elf.EM_X86_64 {
+		t.Errorf("the object is a %v %v for %v, want a 64-bit relocatable for x86-64", f.Class, f.Type, f.Machine)
+	}
+
+	sections := []struct {
+		name  string
+		typ   elf.SectionType
+		flags elf.SectionFlag
+		align uint64
+	}{
+		{"", elf.SHT_NULL, 0, 0},
+		{".text", elf.SHT_PROGBITS, elf.SHF_ALLOC | elf.SHF_EXECINSTR, 16},
+		{".rodata", elf.SHT_PROGBITS, elf.SHF_ALLOC, 1},
+		{".note.GNU-stack", elf.SHT_PROGBITS, 0, 1},
+		{".rela.text", elf.SHT_RELA, elf.SHF_INFO_LINK, 8},
+		{".symtab", elf.SHT_SYMTAB, 0, 8},
+		{".strtab", elf.SHT_STRTAB, 0, 1},
+		{".shstrtab", elf.SHT_STRTAB, 0, 1},
+	}
+	if len(f.Sections) != len(sections) {
+		t.Fatalf("the object has %d sections, want %d", len(f.Sections), len(sections))
+	}
+	for i, want := range sections {
+		s := f.Sections[i]
+		if s.Name != want.name || s.Type != want.typ || s.Flags != want.flags || s.Addralign != want.align {
+			t.Errorf("section %d is %s %v %v aligned to %d, want %s %v %v aligned to %d",
+				i, s.Name, s.Type, s.Flags, s.Addralign, want.name, want.typ, want.flags, want.align)
+		}
+	}
+	rela := f.Section(".rela.text")
+	if rela.Link != 5 || rela.Info != 1 || rela.Entsize != 24 {
+		t.Errorf(".rela.text links %d, applies to %d and has entries of %d bytes, want 5, 1 and 24", rela.Link, rela.Info, rela.Entsize)
+	}
+	if rodata, _ := f.Section(".rodata").Data(); string(rodata) != "hi\n" {
+		t.Errorf(".rodata holds %q, want %q", rodata, "hi\n")
+	}
+
+	// push, lea, mov, je and call precede .L.end, whose jump is
+	// resolved in place.
+	text, _ := f.Section(".text").Data()
+	const end = 1 + 7 + 7 + 6 + 5
+	if len(text) != end+2 {
+		t.Fatalf(".text holds %d bytes, want %d", len(text), end+2)
+	}
+	if disp := int32(binary.LittleEndian.Uint32(text[17:])); disp != 5 {
+		t.Errorf("je jumps %d bytes ahead, want 5", disp)
+	}
+
+	symbols, err := f.Symbols()
+	if err != nil {
+		t.Fatal(err)
+	}
+	type symbol struct {
+		name    string
+		info    byte
+		section elf.SectionIndex
+		value   uint64
+		size    uint64
+	}
+	local := func(typ elf.SymType) byte { return elf.ST_INFO(elf.STB_LOCAL, typ) }
+	global := func(typ elf.SymType) byte { return elf.ST_INFO(elf.STB_GLOBAL, typ) }
+	wantSymbols := []symbol{
+		{"", local(elf.STT_SECTION), 1, 0, 0},
+		{"", local(elf.STT_SECTION), 2, 0, 0},
+		{"", local(elf.STT_SECTION), 3, 0, 0},
+		{"main", global(elf.STT_FUNC), 1, 0, end + 2},
+		{"__stack_chk_guard", global(elf.STT_NOTYPE), elf.SHN_UNDEF, 0, 0},
+		{"write", global(elf.STT_NOTYPE), elf.SHN_UNDEF, 0, 0},
+	}
+	if len(symbols) != len(wantSymbols) {
+		t.Fatalf("the object has symbols %v, want %v", symbols, wantSymbols)
+	}
+	for i, want := range wantSymbols {
+		s := symbols[i]
+		if got := (symbol{s.Name, s.Info, s.Section, s.Value, s.Size}); got != want {
+			t.Errorf("symbol %d is %v, want %v", i+1, got, want)
+		}
+	}
+
+	// Symbol indexes count the null symbol, which Symbols leaves out.
+	relocs := []elf.Rela64{
+		{Off: 4, Info: elf.R_INFO(2, uint32(elf.R_X86_64_PC32)), Addend: -4},
+		{Off: 11, Info: elf.R_INFO(5, uint32(elf.R_X86_64_GOTPCREL)), Addend: -4},
+		{Off: 22, Info: elf.R_INFO(6, uint32(elf.R_X86_64_PLT32)), Addend: -4},
+	}
+	data, _ := rela.Data()
+	if len(data) != len(relocs)*24 {
+		t.Fatalf(".rela.text holds %d bytes, want %d relocations", len(data), len(relocs))
+	}
+	for i, want := range relocs {
+		var got elf.Rela64
+		binary.Read(bytes.NewReader(data[i*24:]), binary.LittleEndian, &got)
+		if got != want {
+			t.Errorf("relocation %d is %+v, want %+v", i, got, want)
+		}
+	}
+}
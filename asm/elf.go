@@ -0,0 +1,249 @@
+package asm
+
+import (
+	"encoding/binary"
+	"sort"
+)
+
+// ELF constants, from the System V ABI and its x86-64 supplement
+const (
+	shtNull     = 0
+	shtProgbits = 1
+	shtSymtab   = 2
+	shtStrtab   = 3
+	shtRela     = 4
+
+	shfAlloc     = 0x2
+	shfExecinstr = 0x4
+	shfInfoLink  = 0x40
+
+	stbLocal  = 0
+	stbGlobal = 1
+
+	sttNotype  = 0
+	sttFunc    = 2
+	sttSection = 3
+
+	rX86_64PC32     = 2
+	rX86_64GOTPCREL = 9
+	rX86_64PLT32    = 4
+
+	ehdrSize = 64
+	shdrSize = 64
+	symSize  = 24
+	relaSize = 24
+)
+
+// A string table under construction.
+type strtab struct {
+	data []byte
+}
+
+func (t *strtab) add(s string) uint32 {
+	if t.data == nil {
+		t.data = []byte{0}
+	}
+	if s == "" {
+		return 0
+	}
+	off := uint32(len(t.data))
+	t.data = append(append(t.data, s...), 0)
+	return off
+}
+
+type elfSym struct {
+	name  uint32
+	info  byte
+	shndx uint16
+	value uint64
+	size  uint64
+}
+
+type shdr struct {
+	name      uint32
+	typ       uint32
+	flags     uint64
+	offset    uint64
+	size      uint64
+	link      uint32
+	info      uint32
+	addralign uint64
+	entsize   uint64
+}
+
+// Lay out the sections, symbol table and relocations as an ELF64 object.
+func (a *assembler) writeELF() []byte {
+	var shstr, str strtab
+	shstr.add("")
+	str.add("")
+
+	// Section indexes: 0 is reserved, the assembled sections follow.
+	for i, s := range a.sections {
+		s.index = i + 1
+	}
+
+	// Local symbols must precede global ones.
+	syms := []elfSym{{}}
+	index := map[string]int{}
+	for _, s := range a.sections {
+		index[s.name] = len(syms)
+		syms = append(syms, elfSym{info: stbLocal<<4 | sttSection, shndx: uint16(s.index)})
+	}
+	names := append([]string(nil), a.order...)
+	locals := len(syms)
+	for _, name := range names {
+		sym := a.symbols[name]
+		if !sym.global && !sym.defined {
+			// Undefined symbols are external, like with GNU as.
+			sym.global = true
+		}
+	}
+	sort.SliceStable(names, func(i, j int) bool {
+		return !a.symbols[names[i]].global && a.symbols[names[j]].global
+	})
+	for _, name := range names {
+		sym := a.symbols[name]
+		if !sym.global && len(name) > 2 && name[:2] == ".L" {
+			continue
+		}
+		typ := byte(sttNotype)
+		if sym.fn {
+			typ = sttFunc
+		}
+		bind := byte(stbLocal)
+		if sym.global {
+			bind = stbGlobal
+		} else {
+			locals++
+		}
+		es := elfSym{name: str.add(name), info: bind<<4 | typ, size: sym.size}
+		if sym.defined {
+			es.shndx, es.value = uint16(sym.section.index), sym.offset
+		}
+		index[name] = len(syms)
+		syms = append(syms, es)
+	}
+
+	var headers []shdr
+	var body []byte
+	place := func(data []byte, align uint64) uint64 {
+		for uint64(ehdrSize+len(body))%align != 0 {
+			body = append(body, 0)
+		}
+		off := uint64(ehdrSize + len(body))
+		body = append(body, data...)
+		return off
+	}
+
+	headers = append(headers, shdr{})
+	for _, s := range a.sections {
+		headers = append(headers, shdr{
+			name:      shstr.add(s.name),
+			typ:       s.typ,
+			flags:     s.flags,
+			offset:    place(s.data, s.align),
+			size:      uint64(len(s.data)),
+			addralign: s.align,
+		})
+	}
+	symtabIndex := uint32(len(headers) + countRela(a.sections))
+	for _, s := range a.sections {
+		if len(s.relocs) == 0 {
+			continue
+		}
+		// Label fixups are resolved last, so sort by offset like GNU as.
+		sort.SliceStable(s.relocs, func(i, j int) bool { return s.relocs[i].offset < s.relocs[j].offset })
+		var data []byte
+		for _, r := range s.relocs {
+			data = binary.LittleEndian.AppendUint64(data, r.offset)
+			data = binary.LittleEndian.AppendUint64(data, uint64(index[r.symbol])<<32|uint64(r.typ))
+			data = binary.LittleEndian.AppendUint64(data, uint64(r.addend))
+		}
+		headers = append(headers, shdr{
+			name:      shstr.add(".rela" + s.name),
+			typ:       shtRela,
+			flags:     shfInfoLink,
+			offset:    place(data, 8),
+			size:      uint64(len(data)),
+			link:      symtabIndex,
+			info:      uint32(s.index),
+			addralign: 8,
+			entsize:   relaSize,
+		})
+	}
+
+	var symdata []byte
+	for _, s := range syms {
+		symdata = binary.LittleEndian.AppendUint32(symdata, s.name)
+		symdata = append(symdata, s.info, 0)
+		symdata = binary.LittleEndian.AppendUint16(symdata, s.shndx)
+		symdata = binary.LittleEndian.AppendUint64(symdata, s.value)
+		symdata = binary.LittleEndian.AppendUint64(symdata, s.size)
+	}
+	headers = append(headers, shdr{
+		name:      shstr.add(".symtab"),
+		typ:       shtSymtab,
+		offset:    place(symdata, 8),
+		size:      uint64(len(symdata)),
+		link:      symtabIndex + 1,
+		info:      uint32(locals),
+		addralign: 8,
+		entsize:   symSize,
+	})
+	headers = append(headers, shdr{
+		name:      shstr.add(".strtab"),
+		typ:       shtStrtab,
+		offset:    place(str.data, 1),
+		size:      uint64(len(str.data)),
+		addralign: 1,
+	})
+	shstrtabName := shstr.add(".shstrtab")
+	headers = append(headers, shdr{
+		name:      shstrtabName,
+		typ:       shtStrtab,
+		offset:    place(shstr.data, 1),
+		size:      uint64(len(shstr.data)),
+		addralign: 1,
+	})
+	shoff := place(nil, 8)
+
+	out := []byte{0x7f, 'E', 'L', 'F', 2, 1, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	out = binary.LittleEndian.AppendUint16(out, 1)  // ET_REL
+	out = binary.LittleEndian.AppendUint16(out, 62) // EM_X86_64
+	out = binary.LittleEndian.AppendUint32(out, 1)  // EV_CURRENT
+	out = binary.LittleEndian.AppendUint64(out, 0)  // Entry point
+	out = binary.LittleEndian.AppendUint64(out, 0)  // Program header offset
+	out = binary.LittleEndian.AppendUint64(out, shoff)
+	out = binary.LittleEndian.AppendUint32(out, 0) // Flags
+	out = binary.LittleEndian.AppendUint16(out, ehdrSize)
+	out = binary.LittleEndian.AppendUint16(out, 0) // Program header size
+	out = binary.LittleEndian.AppendUint16(out, 0) // Program header count
+	out = binary.LittleEndian.AppendUint16(out, shdrSize)
+	out = binary.LittleEndian.AppendUint16(out, uint16(len(headers)))
+	out = binary.LittleEndian.AppendUint16(out, uint16(len(headers)-1))
+	out = append(out, body...)
+	for _, h := range headers {
+		out = binary.LittleEndian.AppendUint32(out, h.name)
+		out = binary.LittleEndian.AppendUint32(out, h.typ)
+		out = binary.LittleEndian.AppendUint64(out, h.flags)
+		out = binary.LittleEndian.AppendUint64(out, 0) // Address
+		out = binary.LittleEndian.AppendUint64(out, h.offset)
+		out = binary.LittleEndian.AppendUint64(out, h.size)
+		out = binary.LittleEndian.AppendUint32(out, h.link)
+		out = binary.LittleEndian.AppendUint32(out, h.info)
+		out = binary.LittleEndian.AppendUint64(out, h.addralign)
+		out = binary.LittleEndian.AppendUint64(out, h.entsize)
+	}
+	return out
+}
+
+// Count the sections needing a relocation section.
+func countRela(sections []*section) int {
+	n := 0
+	for _, s := range sections {
+		if len(s.relocs) > 0 {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,421 @@
+package asm
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Operand kinds
+const (
+	opReg = iota // %rax
+	opImm        // $42
+	opMem        // -8(%rbp), sym(%rip) or %fs:40
+	opSym        // label, for jumps and calls
+)
+
+type operand struct {
+	kind  int
+	reg   int   // Register number, or base register of a memory operand
+	size  int   // Register size in bytes
+	value int64 // Immediate value or displacement
+
+	rip    bool   // Whether a memory operand is relative to %rip
+	fs     bool   // Whether a memory operand is an absolute %fs address
+	symbol string // Symbol of a %rip-relative operand or a jump target
+	got    bool   // Whether `symbol` is referenced through the GOT
+}
+
+var registers = map[string]struct{ num, size int }{}
+
+func init() {
+	names64 := []string{"rax", "rcx", "rdx", "rbx", "rsp", "rbp", "rsi", "rdi"}
+	names32 := []string{"eax", "ecx", "edx", "ebx", "esp", "ebp", "esi", "edi"}
+	names8 := []string{"al", "cl", "dl", "bl"}
+	for i := range names64 {
+		registers[names64[i]] = struct{ num, size int }{i, 8}
+		registers[names32[i]] = struct{ num, size int }{i, 4}
+	}
+	for i := range names8 {
+		registers[names8[i]] = struct{ num, size int }{i, 1}
+	}
+	for i := 8; i < 16; i++ {
+		registers[fmt.Sprintf("r%d", i)] = struct{ num, size int }{i, 8}
+		registers[fmt.Sprintf("r%dd", i)] = struct{ num, size int }{i, 4}
+	}
+}
+
+func parseOperand(s string) (operand, error) {
+	switch {
+	case strings.HasPrefix(s, "$"):
+		v, err := strconv.ParseInt(s[1:], 10, 64)
+		if err != nil {
+			return operand{}, fmt.Errorf("invalid immediate %s", s)
+		}
+		return operand{kind: opImm, value: v}, nil
+	case strings.HasPrefix(s, "%fs:"):
+		v, err := strconv.ParseInt(s[4:], 10, 32)
+		if err != nil {
+			return operand{}, fmt.Errorf("invalid operand %s", s)
+		}
+		return operand{kind: opMem, fs: true, value: v}, nil
+	case strings.HasPrefix(s, "%"):
+		r, ok := registers[s[1:]]
+		if !ok {
+			return operand{}, fmt.Errorf("unknown register %s", s)
+		}
+		return operand{kind: opReg, reg: r.num, size: r.size}, nil
+	case strings.HasSuffix(s, ")"):
+		open := strings.IndexByte(s, '(')
+		if open < 0 {
+			return operand{}, fmt.Errorf("invalid operand %s", s)
+		}
+		disp, base := s[:open], s[open+1:len(s)-1]
+		if base == "%rip" {
+			op := operand{kind: opMem, rip: true, symbol: disp}
+			if name, ok := strings.CutSuffix(disp, "@GOTPCREL"); ok {
+				op.symbol, op.got = name, true
+			}
+			return op, nil
+		}
+		r, ok := registers[strings.TrimPrefix(base, "%")]
+		if !ok || r.size != 8 {
+			return operand{}, fmt.Errorf("invalid base register %s", base)
+		}
+		op := operand{kind: opMem, reg: r.num}
+		if disp != "" {
+			v, err := strconv.ParseInt(disp, 10, 32)
+			if err != nil {
+				return operand{}, fmt.Errorf("invalid displacement %s", disp)
+			}
+			op.value = v
+		}
+		return op, nil
+	}
+	return operand{kind: opSym, symbol: s}, nil
+}
+
+// Condition codes, as added to the base opcode of jcc, setcc and cmovcc.
+var conditions = map[string]byte{
+	"o": 0, "no": 1, "b": 2, "ae": 3, "e": 4, "ne": 5, "be": 6, "a": 7,
+	"s": 8, "ns": 9, "p": 10, "np": 11, "l": 12, "ge": 13, "le": 14, "g": 15,
+}
+
+// Opcodes of the two-operand arithmetic instructions: the register to
+// r/m form, the r/m to register form and the /digit of the immediate form.
+var arith = map[string]struct{ mr, rm, digit byte }{
+	"add": {0x01, 0x03, 0},
+	"sub": {0x29, 0x2b, 5},
+	"cmp": {0x39, 0x3b, 7},
+}
+
+// An instruction being encoded.
+type encoding struct {
+	prefix []byte
+	rex    byte
+	opcode []byte
+	modrm  []byte // ModRM, SIB and displacement bytes
+	imm    []byte
+
+	// Offset within `modrm` of a displacement needing a relocation
+	dispAt int
+	disp   *operand
+}
+
+// Encode the r/m operand `rm` with `reg` in the reg field of the ModRM byte.
+func (e *encoding) setModRM(reg int, rm operand) {
+	if reg >= 8 {
+		e.rex |= 0x44
+	}
+	r := byte(reg&7) << 3
+	switch {
+	case rm.kind == opReg:
+		if rm.reg >= 8 {
+			e.rex |= 0x41
+		}
+		e.modrm = []byte{0xc0 | r | byte(rm.reg&7)}
+	case rm.fs:
+		e.prefix = append(e.prefix, 0x64)
+		e.modrm = binary.LittleEndian.AppendUint32([]byte{r | 4, 0x25}, uint32(rm.value))
+	case rm.rip:
+		e.modrm = []byte{r | 5, 0, 0, 0, 0}
+		e.dispAt, e.disp = 1, &rm
+	default:
+		if rm.reg >= 8 {
+			e.rex |= 0x41
+		}
+		base := byte(rm.reg & 7)
+		e.modrm = []byte{r | base}
+		if base == 4 {
+			// %rsp and %r12 can only be a base through a SIB byte.
+			e.modrm = append(e.modrm, 0x24)
+		}
+		switch {
+		case rm.value == 0 && base != 5:
+		case rm.value >= -128 && rm.value <= 127:
+			e.modrm[0] |= 0x40
+			e.modrm = append(e.modrm, byte(rm.value))
+		default:
+			e.modrm[0] |= 0x80
+			e.modrm = binary.LittleEndian.AppendUint32(e.modrm, uint32(rm.value))
+		}
+	}
+}
+
+func (e *encoding) wide(size int) {
+	if size == 8 {
+		e.rex |= 0x48
+	}
+}
+
+// Return the size of the register operands of an instruction.
+func operandSize(ops []operand) (int, error) {
+	size := 0
+	for _, op := range ops {
+		if op.kind == opReg {
+			if size != 0 && size != op.size {
+				return 0, fmt.Errorf("operand size mismatch")
+			}
+			size = op.size
+		}
+	}
+	if size == 0 {
+		return 0, fmt.Errorf("ambiguous operand size")
+	}
+	return size, nil
+}
+
+func fitsInt8(v int64) bool  { return v >= -128 && v <= 127 }
+func fitsInt32(v int64) bool { return v >= -1<<31 && v < 1<<31 }
+
+func (a *assembler) instruction(op string, args []string) error {
+	ops := make([]operand, len(args))
+	for i, arg := range args {
+		var err error
+		if ops[i], err = parseOperand(arg); err != nil {
+			return err
+		}
+	}
+	if len(ops) == 1 && ops[0].kind == opSym {
+		return a.branch(op, ops[0].symbol)
+	}
+	e := &encoding{}
+	if err := encode(e, op, ops); err != nil {
+		return err
+	}
+	s := a.curr
+	if e.rex != 0 {
+		e.prefix = append(e.prefix, e.rex)
+	}
+	s.data = append(s.data, e.prefix...)
+	s.data = append(s.data, e.opcode...)
+	start := uint64(len(s.data))
+	s.data = append(s.data, e.modrm...)
+	s.data = append(s.data, e.imm...)
+	if d := e.disp; d != nil {
+		offset := start + uint64(e.dispAt)
+		// %rip points after the immediate, if there is one.
+		addend := -4 - int64(len(e.imm))
+		switch {
+		case d.got:
+			s.relocs = append(s.relocs, reloc{offset: offset, typ: rX86_64GOTPCREL, symbol: d.symbol, addend: addend})
+			a.symbol(d.symbol)
+		case len(e.imm) == 0:
+			a.fixups = append(a.fixups, fixup{section: s, offset: offset, label: d.symbol, line: a.line})
+		default:
+			return fmt.Errorf("unsupported %%rip-relative operand with an immediate")
+		}
+	}
+	return nil
+}
+
+// Encode a jump or call to `target`. Jumps always use 32-bit
+// displacements, so instruction sizes never depend on label addresses.
+func (a *assembler) branch(op string, target string) error {
+	s := a.curr
+	switch {
+	case op == "call":
+		s.data = append(s.data, 0xe8, 0, 0, 0, 0)
+		name := strings.TrimSuffix(target, "@PLT")
+		s.relocs = append(s.relocs, reloc{offset: uint64(len(s.data) - 4), typ: rX86_64PLT32, symbol: name, addend: -4})
+		a.symbol(name)
+		return nil
+	case op == "jmp":
+		s.data = append(s.data, 0xe9)
+	case strings.HasPrefix(op, "j"):
+		cc, ok := conditions[op[1:]]
+		if !ok {
+			return fmt.Errorf("unknown instruction %s", op)
+		}
+		s.data = append(s.data, 0x0f, 0x80+cc)
+	default:
+		return fmt.Errorf("unsupported operands for %s", op)
+	}
+	a.fixups = append(a.fixups, fixup{section: s, offset: uint64(len(s.data)), label: target, line: a.line})
+	s.data = append(s.data, 0, 0, 0, 0)
+	return nil
+}
+
+func encode(e *encoding, op string, ops []operand) error {
+	switch op {
+	case "ret":
+		e.opcode = []byte{0xc3}
+		return nil
+	case "cqo":
+		e.rex, e.opcode = 0x48, []byte{0x99}
+		return nil
+	case "cdq":
+		e.opcode = []byte{0x99}
+		return nil
+	}
+	if len(ops) == 0 {
+		return fmt.Errorf("unknown instruction %s", op)
+	}
+	switch op {
+	case "push", "pop":
+		if len(ops) != 1 || ops[0].kind != opReg || ops[0].size != 8 {
+			return fmt.Errorf("unsupported operands for %s", op)
+		}
+		if ops[0].reg >= 8 {
+			e.rex = 0x41
+		}
+		base := byte(0x50)
+		if op == "pop" {
+			base = 0x58
+		}
+		e.opcode = []byte{base + byte(ops[0].reg&7)}
+		return nil
+	case "neg", "idiv":
+		if len(ops) != 1 || ops[0].kind == opImm {
+			return fmt.Errorf("unsupported operands for %s", op)
+		}
+		size, err := operandSize(ops)
+		if err != nil {
+			return err
+		}
+		e.wide(size)
+		e.opcode = []byte{0xf7}
+		digit := 3
+		if op == "idiv" {
+			digit = 7
+		}
+		e.setModRM(digit, ops[0])
+		return nil
+	}
+	if cc, ok := conditions[strings.TrimPrefix(op, "set")]; ok && strings.HasPrefix(op, "set") {
+		if len(ops) != 1 || ops[0].kind == opImm || ops[0].kind == opReg && ops[0].size != 1 {
+			return fmt.Errorf("unsupported operands for %s", op)
+		}
+		e.opcode = []byte{0x0f, 0x90 + cc}
+		e.setModRM(0, ops[0])
+		return nil
+	}
+	if len(ops) != 2 {
+		return fmt.Errorf("unsupported operands for %s", op)
+	}
+	src, dst := ops[0], ops[1]
+	switch op {
+	case "movslq":
+		if dst.kind != opReg || dst.size != 8 || src.kind == opImm || src.kind == opReg && src.size != 4 {
+			return fmt.Errorf("unsupported operands for movslq")
+		}
+		e.rex, e.opcode = 0x48, []byte{0x63}
+		e.setModRM(dst.reg, src)
+		return nil
+	case "movzb":
+		if dst.kind != opReg || src.kind == opImm || src.kind == opReg && src.size != 1 {
+			return fmt.Errorf("unsupported operands for movzb")
+		}
+		e.wide(dst.size)
+		e.opcode = []byte{0x0f, 0xb6}
+		e.setModRM(dst.reg, src)
+		return nil
+	case "lea":
+		if src.kind != opMem || dst.kind != opReg {
+			return fmt.Errorf("unsupported operands for lea")
+		}
+		e.wide(dst.size)
+		e.opcode = []byte{0x8d}
+		e.setModRM(dst.reg, src)
+		return nil
+	case "imul":
+		if dst.kind != opReg || src.kind == opImm {
+			return fmt.Errorf("unsupported operands for imul")
+		}
+		e.wide(dst.size)
+		e.opcode = []byte{0x0f, 0xaf}
+		e.setModRM(dst.reg, src)
+		return nil
+	}
+	size, err := operandSize(ops)
+	if err != nil {
+		return err
+	}
+	e.wide(size)
+	if cc, ok := conditions[strings.TrimPrefix(op, "cmov")]; ok && strings.HasPrefix(op, "cmov") {
+		if dst.kind != opReg || src.kind == opImm || size == 1 {
+			return fmt.Errorf("unsupported operands for %s", op)
+		}
+		e.opcode = []byte{0x0f, 0x40 + cc}
+		e.setModRM(dst.reg, src)
+		return nil
+	}
+	switch op {
+	case "mov":
+		switch {
+		case src.kind == opImm && dst.kind == opReg && size == 8 && !fitsInt32(src.value):
+			e.opcode = []byte{0xb8 + byte(dst.reg&7)}
+			if dst.reg >= 8 {
+				e.rex |= 0x41
+			}
+			e.imm = binary.LittleEndian.AppendUint64(nil, uint64(src.value))
+		case src.kind == opImm:
+			if !fitsInt32(src.value) && size != 4 || src.value >= 1<<32 {
+				return fmt.Errorf("immediate out of range")
+			}
+			e.opcode = []byte{0xc7}
+			e.setModRM(0, dst)
+			e.imm = binary.LittleEndian.AppendUint32(nil, uint32(src.value))
+		case src.kind == opReg:
+			e.opcode = []byte{0x89}
+			e.setModRM(src.reg, dst)
+		case dst.kind == opReg:
+			e.opcode = []byte{0x8b}
+			e.setModRM(dst.reg, src)
+		default:
+			return fmt.Errorf("unsupported operands for mov")
+		}
+		return nil
+	case "test":
+		if src.kind != opReg {
+			return fmt.Errorf("unsupported operands for test")
+		}
+		e.opcode = []byte{0x85}
+		e.setModRM(src.reg, dst)
+		return nil
+	}
+	ar, ok := arith[op]
+	if !ok {
+		return fmt.Errorf("unknown instruction %s", op)
+	}
+	switch {
+	case src.kind == opImm && fitsInt8(src.value):
+		e.opcode = []byte{0x83}
+		e.setModRM(int(ar.digit), dst)
+		e.imm = []byte{byte(src.value)}
+	case src.kind == opImm && fitsInt32(src.value):
+		e.opcode = []byte{0x81}
+		e.setModRM(int(ar.digit), dst)
+		e.imm = binary.LittleEndian.AppendUint32(nil, uint32(src.value))
+	case src.kind == opReg:
+		e.opcode = []byte{ar.mr}
+		e.setModRM(src.reg, dst)
+	case dst.kind == opReg:
+		e.opcode = []byte{ar.rm}
+		e.setModRM(dst.reg, src)
+	default:
+		return fmt.Errorf("unsupported operands for %s", op)
+	}
+	return nil
+}
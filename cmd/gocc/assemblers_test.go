@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/youngfr/gocc/codegen"
+	"github.com/youngfr/gocc/lexer"
+	"github.com/youngfr/gocc/parser"
+	"github.com/youngfr/gocc/token"
+)
+
+// An external assembler, run on the file `src` for the LLVM `triple`
+// to write the object file `object`.
+type assembler struct {
+	name    string
+	triples map[string]bool // Triples it supports, nil for any
+	args    func(triple string, src string, object string) []string
+}
+
+var assemblers = []assembler{
+	{"as", map[string]bool{"x86_64-linux-gnu": true}, func(triple string, src string, object string) []string {
+		return []string{"-o", object, src}
+	}},
+	{"aarch64-linux-gnu-as", map[string]bool{"aarch64-linux-gnu": true}, func(triple string, src string, object string) []string {
+		return []string{"-o", object, src}
+	}},
+	{"clang", nil, func(triple string, src string, object string) []string {
+		return []string{"-target", triple, "-c", "-x", "assembler", "-o", object, src}
+	}},
+	// The integrated assembler of clang, on its own.
+	{"llvm-mc", nil, func(triple string, src string, object string) []string {
+		return []string{"-triple", triple, "-filetype=obj", "-o", object, src}
+	}},
+}
+
+// Triples of the targets with an assembly output.
+var assemblyTriples = map[string]string{
+	"x86_64-linux":  "x86_64-linux-gnu",
+	"x86_64-darwin": "x86_64-apple-darwin",
+	"aarch64-linux": "aarch64-linux-gnu",
+}
+
+// The assembly of each target must assemble without warnings under GNU
+// as and clang alike, whatever the options and the file name.
+func TestAssemblers(t *testing.T) {
+	var found []assembler
+	for _, a := range assemblers {
+		if _, err := exec.LookPath(a.name); err == nil {
+			found = append(found, a)
+		}
+	}
+	if len(found) == 0 {
+		t.Skip("no assembler to test with")
+	}
+	options := []*codegen.Options{
+		{DebugInfo: true, PIC: true, StackProtector: codegen.ProtectAll, SanitizeUndefined: true, VerboseAsm: true, OptLevel: 1},
+		{OmitFramePointer: true, NoRedZone: true, SanitizeUndefined: true},
+	}
+	dir := t.TempDir()
+	src := filepath.Join(dir, "prog.s")
+	object := filepath.Join(dir, "prog.o")
+	for target, triple := range assemblyTriples {
+		for i, c := range e2eCases {
+			file := token.NewSession().AddFile("dir/\xc3\xa9 \"q\"\\.c", "", c.src)
+			tok, _ := lexer.Tokenize(file)
+			program, err := parser.Parse(tok)
+			if err != nil {
+				t.Fatalf("cannot compile %q", c.src)
+			}
+			for j, opts := range options {
+				var asm bytes.Buffer
+				if err := codegen.Targets[target](&asm, opts).Gen(program); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(src, asm.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				for _, a := range found {
+					if a.triples != nil && !a.triples[triple] {
+						continue
+					}
+					name := fmt.Sprintf("%s/%s/%d/%d", a.name, target, i, j)
+					out, err := exec.Command(a.name, a.args(triple, src, object)...).CombinedOutput()
+					if err != nil || len(out) != 0 {
+						t.Errorf("%s: %q: %v\n%s\n%s", name, c.src, err, out, asm.Bytes())
+					}
+				}
+			}
+		}
+	}
+}
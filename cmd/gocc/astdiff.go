@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/youngfr/gocc/lexer"
+	"github.com/youngfr/gocc/parser"
+	"github.com/youngfr/gocc/token"
+)
+
+// AST differences
+//
+// "gocc astdiff old.c new.c" parses both files and prints how the tree
+// of the second differs from that of the first, see parser.Diff, one
+// difference per line located in the file it is found in:
+//
+//	old.c:2:9: new.c:2:9: changed Num "1" to Num "2"
+//	old.c:3:1: removed Return "return a;"
+//	new.c:4:1: added If "if (a) {"
+//	old.c:5:8: new.c:6:8: changed the type of Var "p" from 'int' to 'int*'
+//
+// so that layout and comments do not count, for example when grading
+// submissions against a solution or checking that a refactoring kept
+// the program as it was. Like diff, it exits with 0 if there are no
+// differences and 1 otherwise. A file with errors is reported like when
+// compiling, and the status is that of its errors.
+
+// Print the differences between the files of the command line `args`,
+// and exit with 1 if there are any.
+func astdiff(args []string) {
+	var inputs []input
+	for _, arg := range args {
+		switch {
+		case frontEndOption(arg):
+		case strings.HasPrefix(arg, "-") && arg != "-":
+			usage()
+		default:
+			inputs = append(inputs, input{path: arg})
+		}
+	}
+	if len(inputs) != 2 {
+		usage()
+	}
+	if err := config.Apply(session); err != nil {
+		fatalStatus(token.ExitUsage, err.Error())
+	}
+	var programs [2]*parser.Function
+	for i, in := range inputs {
+		file := openInput(in)
+		tok, _ := lexer.Tokenize(file)
+		program, err := parser.Parse(tok)
+		file.Report()
+		if err != nil {
+			os.Exit(file.ExitStatus())
+		}
+		programs[i] = program
+	}
+	diffs := parser.Diff(programs[0], programs[1])
+	for _, d := range diffs {
+		fmt.Println(differenceString(d))
+	}
+	if len(diffs) > 0 {
+		os.Exit(token.ExitFailure)
+	}
+}
+
+// Return the line printing the difference `d`.
+func differenceString(d parser.Difference) string {
+	switch {
+	case d.New == nil:
+		return nodeLocation(d.Old) + session.Tr("removed %s", nodeString(d.Old))
+	case d.Old == nil:
+		return nodeLocation(d.New) + session.Tr("added %s", nodeString(d.New))
+	}
+	location := nodeLocation(d.Old) + nodeLocation(d.New)
+	if old, new := nodeString(d.Old), nodeString(d.New); old != new {
+		return location + session.Tr("changed %s to %s", old, new)
+	}
+	return location + session.Tr("changed the type of %s from '%s' to '%s'", nodeString(d.Old), d.Old.Type, d.New.Type)
+}
+
+// Return "file:line:column: " for the beginning of the source of `node`.
+func nodeLocation(node *parser.Node) string {
+	first, _ := node.Span()
+	line, column := first.Position()
+	return fmt.Sprintf("%s:%d:%d: ", first.File.Name, line, column)
+}
+
+// Return the kind of `node` and the first line of its source.
+func nodeString(node *parser.Node) string {
+	src := node.Source()
+	if i := strings.IndexByte(src, '\n'); i >= 0 {
+		src = strings.TrimSpace(src[:i])
+	}
+	return fmt.Sprintf("%s %q", node.Kind, src)
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/youngfr/gocc/codegen"
+	"github.com/youngfr/gocc/lexer"
+	"github.com/youngfr/gocc/parser"
+	"github.com/youngfr/gocc/token"
+)
+
+// Benchmarks of each phase on large generated programs. Besides the
+// time per program, they report the tokens or AST nodes processed per
+// second, which stay comparable when the generator changes. Run them with
+//
+//	go test -run XXX -bench .
+
+// Return a program of about `lines` lines, the same for the same `seed`,
+// using most constructs gocc supports. It compiles without errors.
+func generateProgram(lines int, seed int64) string {
+	g := &generator{rand: rand.New(rand.NewSource(seed))}
+	g.printf("int v0 = 1;\n")
+	g.vars = 1
+	for g.lines < lines {
+		g.stmt()
+	}
+	g.printf("return v0;\n")
+	return g.out.String()
+}
+
+type generator struct {
+	rand  *rand.Rand
+	out   strings.Builder
+	lines int
+	vars  int // Variables v0 to v<vars-1> are declared
+	ptrs  int // Pointers p0 to p<ptrs-1> are declared
+}
+
+func (g *generator) printf(format string, args ...any) {
+	fmt.Fprintf(&g.out, format, args...)
+	g.lines += strings.Count(format, "\n")
+}
+
+// Return the name of a declared variable.
+func (g *generator) variable() string {
+	return fmt.Sprintf("v%d", g.rand.Intn(g.vars))
+}
+
+// Return an expression of at most `depth` levels of operators.
+func (g *generator) expr(depth int) string {
+	if depth == 0 || g.rand.Intn(3) == 0 {
+		if g.rand.Intn(2) == 0 {
+			return fmt.Sprint(g.rand.Intn(100))
+		}
+		return g.variable()
+	}
+	switch g.rand.Intn(6) {
+	case 0:
+		return "- " + g.expr(depth-1)
+	case 1:
+		return "(" + g.expr(depth-1) + ")"
+	case 2:
+		return g.expr(depth-1) + " * " + g.expr(depth-1)
+	case 3:
+		return g.expr(depth-1) + " - " + g.expr(depth-1)
+	case 4:
+		return "(" + g.expr(depth-1) + " < " + g.expr(depth-1) + ")"
+	}
+	return g.expr(depth-1) + " + " + g.expr(depth-1)
+}
+
+func (g *generator) stmt() {
+	switch g.rand.Intn(6) {
+	case 0:
+		g.printf("int v%d = %s;\n", g.vars, g.expr(3))
+		g.vars++
+	case 1:
+		g.printf("if (%s == %s)\n\t%s = %s;\nelse\n\t%s = %s;\n",
+			g.expr(2), g.expr(2), g.variable(), g.expr(2), g.variable(), g.expr(2))
+	case 2:
+		g.printf("for (v0 = 0; v0 < 10; v0 = v0 + 1) {\n\t%s = %s;\n}\n", g.variable(), g.expr(3))
+	case 3:
+		g.printf("int *p%d = &%s;\n*p%d = *p%d + %s;\n", g.ptrs, g.variable(), g.ptrs, g.ptrs, g.expr(2))
+		g.ptrs++
+	default:
+		g.printf("%s = %s;\n", g.variable(), g.expr(3))
+	}
+}
+
+// Sizes of the generated programs, in lines.
+var benchmarkSizes = []int{1000, 10000}
+
+func benchmarkInput(lines int) string {
+	return generateProgram(lines, 1)
+}
+
+func BenchmarkTokenize(b *testing.B) {
+	for _, lines := range benchmarkSizes {
+		src := benchmarkInput(lines)
+		b.Run(fmt.Sprintf("lines=%d", lines), func(b *testing.B) {
+			b.SetBytes(int64(len(src)))
+			tokens := 0
+			for i := 0; i < b.N; i++ {
+				tok, _ := lexer.Tokenize(token.NewSession().AddFile("<bench>", "", src))
+				tokens = len(tok) - 1
+			}
+			b.ReportMetric(float64(tokens)*float64(b.N)/b.Elapsed().Seconds(), "tokens/s")
+		})
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	for _, lines := range benchmarkSizes {
+		src := benchmarkInput(lines)
+		b.Run(fmt.Sprintf("lines=%d", lines), func(b *testing.B) {
+			nodes := 0
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				tok, _ := lexer.Tokenize(token.NewSession().AddFile("<bench>", "", src))
+				b.StartTimer()
+				program, err := parser.Parse(tok)
+				if err != nil {
+					b.Fatal("the generated program does not compile")
+				}
+				nodes = countNodes(program.Body)
+			}
+			b.ReportMetric(float64(nodes)*float64(b.N)/b.Elapsed().Seconds(), "nodes/s")
+		})
+	}
+}
+
+func BenchmarkCodegen(b *testing.B) {
+	for _, target := range []string{"x86_64-linux", "arm64-linux", "wasm32"} {
+		for _, lines := range benchmarkSizes {
+			src := benchmarkInput(lines)
+			b.Run(fmt.Sprintf("target=%s/lines=%d", target, lines), func(b *testing.B) {
+				nodes := 0
+				for i := 0; i < b.N; i++ {
+					// The passes and the backends annotate
+					// the AST, so each gets a fresh one.
+					b.StopTimer()
+					tok, _ := lexer.Tokenize(token.NewSession().AddFile("<bench>", "", src))
+					program, _ := parser.Parse(tok)
+					nodes = countNodes(program.Body)
+					b.StartTimer()
+					opts := &codegen.Options{}
+					codegen.RunASTPasses(program, opts)
+					codegen.Targets[target](io.Discard, opts).Gen(program)
+				}
+				b.ReportMetric(float64(nodes)*float64(b.N)/b.Elapsed().Seconds(), "nodes/s")
+			})
+		}
+	}
+}
+
+func TestGenerateProgram(t *testing.T) {
+	for seed := int64(1); seed <= 5; seed++ {
+		src := generateProgram(2000, seed)
+		if n := strings.Count(src, "\n"); n < 2000 {
+			t.Errorf("seed %d: %d lines, want at least 2000", seed, n)
+		}
+		file := token.NewSession().AddFile("<generated>", "", src)
+		tok, _ := lexer.Tokenize(file)
+		program, err := parser.Parse(tok)
+		if err != nil {
+			file.PrintDiagnostics(os.Stderr)
+			t.Fatalf("seed %d: the generated program does not compile", seed)
+		}
+		if err := parser.Verify(program, "parsing"); err != nil {
+			t.Fatalf("seed %d: %v", seed, err)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/youngfr/gocc"
+	"github.com/youngfr/gocc/token"
+)
+
+// Compilation databases
+//
+// Build systems such as CMake, Meson and Bear write the command that
+// compiles each file of a project to compile_commands.json. With -p,
+// naming that file or the directory holding it like clang tools do,
+// "gocc tags" and "gocc highlight" read each file in a session of its
+// own, configured the way its entry compiles it, and so does "gocc lsp"
+// for each document. Without -p, "gocc lsp" uses the compile_commands.json
+// found in the directory of the document or the closest of its parents,
+// since editors start it without knowing where the build is.
+//
+// Only the options gocc knows are taken from the entries: -std=, -W,
+// -w, and the -I, -D and -U of the preprocessor. Standards and warnings
+// gocc does not know are left to the compiler of the build. The options
+// of the command line of gocc come after them, so they take precedence.
+
+// Name of the file of compilation databases.
+const compileDBName = "compile_commands.json"
+
+// An entry of a compilation database.
+type compileCommand struct {
+	Directory string   `json:"directory"`
+	File      string   `json:"file"`
+	Command   string   `json:"command"`   // Command line, for a shell
+	Arguments []string `json:"arguments"` // Command line, split, instead of Command
+}
+
+// The entries of a compilation database, by the absolute path of their
+// file. A nil database has no entry.
+type compileDB map[string]compileCommand
+
+// Read the compilation database at `path`, which is either the file or
+// the directory holding it.
+func loadCompileDB(path string) (compileDB, error) {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		path = filepath.Join(path, compileDBName)
+	}
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.New(session.Tr("cannot read %s: %v", path, err))
+	}
+	var entries []compileCommand
+	if err := json.Unmarshal(contents, &entries); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	db := compileDB{}
+	for _, e := range entries {
+		file := e.File
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(e.Directory, file)
+		}
+		// Like clang tools, use the first entry of a file
+		// compiled several times.
+		if _, ok := db[filepath.Clean(file)]; !ok {
+			db[filepath.Clean(file)] = e
+		}
+	}
+	return db, nil
+}
+
+// Return the compilation database in `dir` or the closest of its
+// parents, or nil if there is none or it cannot be read.
+func findCompileDB(dir string) compileDB {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil
+	}
+	for {
+		if db, err := loadCompileDB(filepath.Join(dir, compileDBName)); err == nil {
+			return db
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil
+		}
+		dir = parent
+	}
+}
+
+// Return the configuration to read the file at `path` with: that of the
+// command line, after the options of the entry of the file, if any.
+func (db compileDB) configFor(path string) gocc.Config {
+	c := config
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return c
+	}
+	e, ok := db[abs]
+	if !ok {
+		return c
+	}
+	args := e.Arguments
+	if args == nil {
+		if args, err = splitResponseFile(e.Command); err != nil {
+			return c
+		}
+	}
+	var entry gocc.Config
+	// The first argument is the compiler.
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		if option, ok := preprocessorOption(arg); ok {
+			value := arg[len(option):]
+			if value == "" && i+1 < len(args) {
+				value = args[i+1]
+				i++
+			}
+			if option != "-I" {
+				entry.Macros = append(entry.Macros, option+value)
+			} else if filepath.IsAbs(value) {
+				entry.IncludePaths = append(entry.IncludePaths, value)
+			} else {
+				entry.IncludePaths = append(entry.IncludePaths, filepath.Join(e.Directory, value))
+			}
+			continue
+		}
+		switch {
+		case strings.HasPrefix(arg, "-std="):
+			if _, ok := token.Standards[arg[len("-std="):]]; ok {
+				entry.Standard = arg[len("-std="):]
+			}
+		case arg == "-w":
+			entry.SuppressWarnings = true
+		case strings.HasPrefix(arg, "-W") && token.IsWarningOption(arg[len("-W"):]):
+			entry.Warnings = append(entry.Warnings, arg[len("-W"):])
+		}
+	}
+	if c.Standard == "" {
+		c.Standard = entry.Standard
+	}
+	c.Warnings = append(entry.Warnings, c.Warnings...)
+	c.SuppressWarnings = c.SuppressWarnings || entry.SuppressWarnings
+	c.IncludePaths = append(entry.IncludePaths, c.IncludePaths...)
+	c.Macros = append(entry.Macros, c.Macros...)
+	return c
+}
+
+// Add the program of `in` to a session of its own, configured for its
+// file by `db`, whose messages are those of the session of the command
+// line. Without a database, it is added to the session of the command line.
+func (db compileDB) openInput(in input) *token.File {
+	if db == nil {
+		return openInput(in)
+	}
+	s := token.NewSession()
+	s.Language, s.Color, s.Reporter = session.Language, session.Color, session.Reporter
+	c := db.configFor(in.path)
+	if err := c.Apply(s); err != nil {
+		fatalStatus(token.ExitUsage, err.Error())
+	}
+	return addInput(s, in)
+}
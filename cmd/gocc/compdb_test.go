@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompileDB(t *testing.T) {
+	dir := t.TempDir()
+	build := filepath.Join(dir, "build")
+	src := filepath.Join(dir, "src")
+	if err := os.MkdirAll(filepath.Join(src, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	db := fmt.Sprintf(`[
+		{"directory": %q, "file": "../src/a.c", "command": "cc -I../include -I /abs -DX=1 -U Y -std=c89 -Wall -Wno-such -Wl,-z -c \"../src/a.c\""},
+		{"directory": %q, "file": %q, "arguments": ["clang", "-w", "-std=c++17", "-DZ", "-o", "b.o", "b.c"]},
+		{"directory": %q, "file": %q, "arguments": ["cc", "-DSECOND"]}
+	]`, build, src, filepath.Join(src, "b.c"), src, filepath.Join(src, "b.c"))
+	if err := os.WriteFile(filepath.Join(dir, compileDBName), []byte(db), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	compdb, err := loadCompileDB(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := config
+	defer func() { config = saved }()
+	config.Warnings = []string{"no-shadow"}
+	config.Macros = []string{"-DCMD"}
+
+	c := compdb.configFor(filepath.Join(src, "a.c"))
+	want := fmt.Sprintf("c89 [all no-shadow] false [%s /abs] [-DX=1 -UY -DCMD]", filepath.Join(dir, "include"))
+	if got := fmt.Sprint(c.Standard, " ", c.Warnings, " ", c.SuppressWarnings, " ", c.IncludePaths, " ", c.Macros); got != want {
+		t.Errorf("a.c is read with %s, want %s", got, want)
+	}
+	c = compdb.configFor(filepath.Join(src, "sub", "..", "b.c"))
+	want = "[no-shadow] true [] [-DZ -DCMD]"
+	if got := fmt.Sprint(c.Standard, c.Warnings, " ", c.SuppressWarnings, " ", c.IncludePaths, " ", c.Macros); got != want {
+		t.Errorf("b.c is read with %s, want %s", got, want)
+	}
+	c = compdb.configFor(filepath.Join(src, "c.c"))
+	if got := fmt.Sprint(c.Warnings, c.Macros); got != "[no-shadow] [-DCMD]" {
+		t.Errorf("c.c, which has no entry, is read with %s", got)
+	}
+
+	if found := findCompileDB(filepath.Join(src, "sub")); len(found) != 2 {
+		t.Errorf("found a database of %d entries, want 2", len(found))
+	}
+}
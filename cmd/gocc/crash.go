@@ -0,0 +1,149 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
+	"strings"
+
+	"github.com/youngfr/gocc/lexer"
+	"github.com/youngfr/gocc/parser"
+	"github.com/youngfr/gocc/token"
+)
+
+// Internal compiler errors
+//
+// A panic anywhere in the compiler is a bug in gocc. Instead of a Go
+// stack trace, the user is told what was being compiled and where, and
+// the state of the compiler is written to a file to attach to a bug
+// report: the command line, the stack, and the tokens and the AST of
+// the file being compiled.
+
+// Where the compilation is, for the report of a crash.
+var progress struct {
+	phase   string           // Such as "parsing"
+	file    *token.File      // File being compiled
+	tokens  []token.Token    // Tokens of the file, once tokenized
+	program *parser.Function // AST of the file, once parsed
+}
+
+// Record that `phase` of compiling `file` begins.
+func enterPhase(phase string, file *token.File) {
+	if file != progress.file {
+		progress.tokens, progress.program = nil, nil
+	}
+	progress.phase, progress.file, session.CurrentStatement = phase, file, nil
+	if timeReport {
+		measurePhase(phase)
+	}
+}
+
+// Where to report bugs.
+const bugURL = "https://github.com/YoungFr/gocc/issues"
+
+// Return the version of gocc, from the information the
+// Go toolchain embeds in the binary.
+func version() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	v := info.Main.Version
+	for _, s := range info.Settings {
+		if s.Key == "vcs.revision" {
+			v += " " + s.Value
+		}
+	}
+	return v
+}
+
+// Report a panic, if any, as an internal compiler error and exit.
+// Deferred by main.
+func handleCrash() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	stack := debug.Stack()
+	where := ""
+	if progress.file != nil {
+		where = session.Tr(", in %s", progress.file.Name)
+	}
+	if t := session.CurrentStatement; t != nil {
+		line, column := t.Position()
+		where = session.Tr(", at %s:%d:%d", t.File.Name, line, column)
+	}
+	fmt.Fprintf(os.Stderr, "gocc: %s %v\n", session.Colored(token.SeverityColors[token.SeverityError], session.Translate("internal compiler error")+":"), r)
+	if progress.phase != "" {
+		fmt.Fprintln(os.Stderr, session.Tr("while %s%s", session.Translate(progress.phase), where))
+	}
+	fmt.Fprintln(os.Stderr, session.Tr("gocc version %s", version()))
+	if name, err := writeCrashDump(r, stack); err == nil {
+		fmt.Fprintln(os.Stderr, session.Tr("The state of the compiler was written to %s.", name))
+	}
+	fmt.Fprintln(os.Stderr, session.Tr("Please report this bug at %s,\nwith the input and the file above.", bugURL))
+	os.Exit(token.ExitInternal)
+}
+
+// Write everything known about the crash to a temporary file and return its name.
+func writeCrashDump(r any, stack []byte) (string, error) {
+	f, err := os.CreateTemp("", "gocc-crash-*.txt")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	fmt.Fprintf(f, "gocc version %s\n", version())
+	fmt.Fprintf(f, "command line: %s\n", strings.Join(os.Args, " "))
+	fmt.Fprintf(f, "phase: %s\n", progress.phase)
+	fmt.Fprintf(f, "panic: %v\n\n%s\n", r, stack)
+	if progress.file != nil {
+		dumpSection(f, "source", func(out io.Writer) {
+			fmt.Fprintln(out, progress.file.Contents)
+		})
+	}
+	if progress.tokens != nil {
+		dumpSection(f, "tokens", func(out io.Writer) {
+			lexer.DumpTokens(out, progress.tokens)
+		})
+	}
+	if progress.program != nil {
+		dumpSection(f, "AST", func(out io.Writer) {
+			parser.DumpAST(out, progress.program)
+		})
+	}
+	return f.Name(), nil
+}
+
+// Largest dump section written, in bytes. A broken AST may
+// link back to itself, which would make its dump endless.
+const crashDumpLimit = 1 << 22
+
+var errDumpLimit = errors.New("dump truncated")
+
+// A writer panicking once `n` more bytes were written.
+type limitWriter struct {
+	w io.Writer
+	n int
+}
+
+func (l *limitWriter) Write(p []byte) (int, error) {
+	if len(p) > l.n {
+		panic(errDumpLimit)
+	}
+	l.n -= len(p)
+	return l.w.Write(p)
+}
+
+// Write a section of the crash dump with `dump`. The state being dumped
+// may be what caused the crash, so a panic in it only ends the section.
+func dumpSection(out io.Writer, title string, dump func(out io.Writer)) {
+	fmt.Fprintf(out, "\n=== %s\n", title)
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Fprintf(out, "\n(%v)\n", r)
+		}
+	}()
+	dump(&limitWriter{out, crashDumpLimit})
+}
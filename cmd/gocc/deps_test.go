@@ -0,0 +1,51 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCppHeaders(t *testing.T) {
+	out := "# 0 \"x.c\"\n# 0 \"<built-in>\"\n# 0 \"<command-line>\"\n# 1 \"x.c\"\n" +
+		"# 1 \"/usr/include/stdio.h\" 1 3 4\n# 2 \"x.c\" 2\n# 1 \"dir\\\\a b.h\" 1\nint a;\n" +
+		"# 3 \"x.c\" 2\n# 1 \"dir\\\\a b.h\" 1\n# 4 \"x.c\" 2\nreturn a;\n"
+	got := cppHeaders(out, "x.c", false)
+	if want := []string{"dir\\a b.h"}; strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("headers are %q, want %q", got, want)
+	}
+	// -MD lists system headers as well.
+	got = cppHeaders(out, "x.c", true)
+	if want := []string{"/usr/include/stdio.h", "dir\\a b.h"}; strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("headers with system headers are %q, want %q", got, want)
+	}
+}
+
+// With --use-external-cpp, -MMD lists the headers the program includes.
+func TestDepsWithHeaders(t *testing.T) {
+	if _, err := exec.LookPath("cpp"); err != nil {
+		t.Skip("no cpp to preprocess with")
+	}
+	dir := t.TempDir()
+	header := filepath.Join(dir, "a.h")
+	path := filepath.Join(dir, "x.c")
+	if err := os.WriteFile(header, []byte("int a = 1;\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("#include \"a.h\"\nreturn a;\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	d := &driver{mode: modeObject, deps: true, depOutput: filepath.Join(dir, "x.d"), cpp: "cpp"}
+	if err := d.writeDeps(d.preprocess(input{path: path})); err != nil {
+		t.Fatal(err)
+	}
+	rule, err := os.ReadFile(d.depOutput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "x.o: " + path + " \\\n " + header + "\n"; string(rule) != want {
+		t.Errorf("rule is %q, want %q", rule, want)
+	}
+}
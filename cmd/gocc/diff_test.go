@@ -0,0 +1,20 @@
+package main
+
+import "testing"
+
+func TestUnifiedDiff(t *testing.T) {
+	for _, c := range []struct {
+		a, b string
+		want string
+	}{
+		{"a\nb\n", "a\nb\n", ""},
+		{"1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n", "1\n2\n3\nfour\n5\n6\n7\n8\n9\n10\n11\n",
+			"--- x.orig\n+++ x\n@@ -1,7 +1,7 @@\n 1\n 2\n 3\n-4\n+four\n 5\n 6\n 7\n@@ -9,4 +9,3 @@\n 9\n 10\n 11\n-12\n"},
+		{"", "a\n", "--- x.orig\n+++ x\n@@ -0,0 +1 @@\n+a\n"},
+		{"a", "a\n", "--- x.orig\n+++ x\n@@ -1 +1 @@\n-a\n\\ No newline at end of file\n+a\n"},
+	} {
+		if got := unifiedDiff("x.orig", "x", c.a, c.b); got != c.want {
+			t.Errorf("diff of %q and %q is\n%s\nwant\n%s", c.a, c.b, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Differential testing
+//
+// "gocc difftest" compiles each program of a corpus with gocc and with a
+// reference C compiler, runs both executables, and reports the programs
+// whose exit status or output differ, to catch miscompilations that no
+// test expects. A program is the body of main, as for gocc, so it is
+// wrapped in a main function for the reference compiler. Programs must
+// not rely on behavior C leaves undefined, such as the layout of locals,
+// nor fall off the end of main, which returns 0 in C but the value of
+// the last expression statement in gocc.
+//
+// With -interp, the reference is the interpreter of "gocc -run" instead,
+// which shares the front end with the compiler but not the backend, so
+// programs may fall off the end of main. Runtime errors make it exit
+// with the status a shell reports for an executable killed by a signal.
+
+// How long each executable may run before it is considered hung.
+const difftestTimeout = 10 * time.Second
+
+// Run the difference tests for the command line `args`, and exit with
+// status 1 if any program diverged.
+func difftest(args []string) {
+	cc := os.Getenv("CC")
+	if cc == "" {
+		cc = "cc"
+	}
+	verbose := false
+	interpret := false
+	var options, paths []string
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "-cc":
+			if i+1 == len(args) {
+				usage()
+			}
+			cc = args[i+1]
+			i++
+		case args[i] == "-v":
+			verbose = true
+		case args[i] == "-interp":
+			interpret = true
+		case strings.HasPrefix(args[i], "-"):
+			options = append(options, args[i])
+		default:
+			paths = append(paths, args[i])
+		}
+	}
+	if len(paths) == 0 {
+		usage()
+	}
+	programs, err := difftestPrograms(paths)
+	if err != nil {
+		fatal(err.Error())
+	}
+	self, err := os.Executable()
+	if err != nil {
+		fatal(err.Error())
+	}
+	dir, err := os.MkdirTemp("", "gocc-difftest-*")
+	if err != nil {
+		fatal(err.Error())
+	}
+	defer os.RemoveAll(dir)
+	diverged, skipped := 0, 0
+	for _, program := range programs {
+		var status, message string
+		if interpret {
+			status, message = difftestInterp(program, self, options, dir)
+		} else {
+			status, message = difftestProgram(program, self, options, cc, dir)
+		}
+		switch status {
+		case "skipped":
+			skipped++
+		case "diverged":
+			diverged++
+		}
+		if status != "ok" || verbose {
+			fmt.Printf("%s: %s%s\n", program, status, message)
+		}
+	}
+	fmt.Printf("%d programs, %d diverged, %d skipped\n", len(programs), diverged, skipped)
+	if diverged > 0 {
+		os.RemoveAll(dir)
+		os.Exit(1)
+	}
+}
+
+// Return the programs of `paths`, taking the .c files of directories.
+func difftestPrograms(paths []string) ([]string, error) {
+	var programs []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			programs = append(programs, path)
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(path, "*.c"))
+		if err != nil {
+			return nil, err
+		}
+		programs = append(programs, matches...)
+	}
+	return programs, nil
+}
+
+// What running an executable did.
+type difftestRun struct {
+	status int // -1 if it was killed by a signal
+	signal int // Number of the signal that killed it, if any
+	stdout string
+	hung   bool
+}
+
+func (r difftestRun) String() string {
+	if r.hung {
+		return "timed out"
+	}
+	return fmt.Sprintf("exited with %d and printed %q", r.status, r.stdout)
+}
+
+// Compile and run `program` with gocc, the executable `self` given
+// `options`, and with the reference compiler `cc`, in the directory
+// `dir`. Return "ok", "diverged" or "skipped", and what happened if
+// it was not ok.
+func difftestProgram(program string, self string, options []string, cc string, dir string) (string, string) {
+	src, err := os.ReadFile(program)
+	if err != nil {
+		return "skipped", ": " + err.Error()
+	}
+	reference := filepath.Join(dir, "reference.c")
+	if err := os.WriteFile(reference, []byte("int main() {\n"+string(src)+"\n}\n"), 0o644); err != nil {
+		return "skipped", ": " + err.Error()
+	}
+	ccOutput := filepath.Join(dir, "reference")
+	if out, err := exec.Command(cc, "-w", "-o", ccOutput, reference).CombinedOutput(); err != nil {
+		return "skipped", strings.TrimSpace(fmt.Sprintf(", %s does not compile it: %v\n%s", cc, err, out))
+	}
+	expected, err := difftestExec(ccOutput)
+	if err != nil {
+		return "skipped", ": " + err.Error()
+	}
+	return difftestCompare(program, self, options, dir, expected, "the "+cc+" one", false)
+}
+
+// Run `program` with the interpreter of gocc, the executable `self`
+// given `options`, and compare it with the compiled one in the directory
+// `dir`, like difftestProgram.
+func difftestInterp(program string, self string, options []string, dir string) (string, string) {
+	args := append(append([]string{}, options...), "-run", program)
+	expected, err := difftestExec(self, args...)
+	if err != nil {
+		return "skipped", ": " + err.Error()
+	}
+	return difftestCompare(program, self, options, dir, expected, "the interpreter", true)
+}
+
+// Compile and run `program` with gocc, the executable `self` given
+// `options`, in the directory `dir`, and compare what it did with
+// what the `reference` did, `expected`. If `shell` is set, the
+// reference reports signals in its exit status like a shell does.
+func difftestCompare(program string, self string, options []string, dir string, expected difftestRun, reference string, shell bool) (string, string) {
+	goccOutput := filepath.Join(dir, "gocc")
+	args := append(append([]string{}, options...), "-o", goccOutput, program)
+	if out, err := exec.Command(self, args...).CombinedOutput(); err != nil {
+		return "diverged", strings.TrimSpace(fmt.Sprintf(", gocc does not compile it: %v\n%s", err, out))
+	}
+	actual, err := difftestExec(goccOutput)
+	if err != nil {
+		return "diverged", ": " + err.Error()
+	}
+	if shell && actual.status < 0 {
+		actual = difftestRun{status: 128 + actual.signal, stdout: actual.stdout}
+	}
+	if actual != expected {
+		return "diverged", fmt.Sprintf(", the gocc executable %s, %s %s", actual, reference, expected)
+	}
+	return "ok", ""
+}
+
+// Run the executable `path` with `args`, and return what it did.
+func difftestExec(path string, args ...string) (difftestRun, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), difftestTimeout)
+	defer cancel()
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Stdout = &stdout
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		return difftestRun{hung: true}, nil
+	}
+	var exit *exec.ExitError
+	if err != nil && !errors.As(err, &exit) {
+		return difftestRun{}, err
+	}
+	run := difftestRun{status: cmd.ProcessState.ExitCode(), stdout: stdout.String()}
+	if status, ok := cmd.ProcessState.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		run.signal = int(status.Signal())
+	}
+	return run, nil
+}
@@ -0,0 +1,582 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/youngfr/gocc/asm"
+
+	"github.com/youngfr/gocc/codegen"
+	"github.com/youngfr/gocc/interp"
+	"github.com/youngfr/gocc/lexer"
+	"github.com/youngfr/gocc/parser"
+	"github.com/youngfr/gocc/token"
+)
+
+// Compiler driver
+//
+// By default the program is assembled and linked into an executable.
+// With -S the assembly is printed instead, and with -c it is only
+// assembled into an object file, while with -run it is not compiled
+// at all but run by the interp package. With -E it is only preprocessed,
+// see lexer.WritePreprocessed. Assembling uses the system assembler
+// or, when that is missing or -fintegrated-as was given, the asm
+// package. Linking uses the system C compiler driver, or ld with the C
+// runtime objects when there is none. With -emit-qbe, the program is
+// compiled to QBE IL, which -S prints and qbe otherwise compiles to the
+// assembly to assemble.
+
+const (
+	modeExec       = iota // Link into an executable
+	modeAsm               // -S: print the assembly
+	modeObject            // -c: assemble into an object file
+	modeRun               // -run: interpret the program
+	modePreprocess        // -E: print the preprocessed program
+)
+
+// Targets whose output the asm package can assemble.
+var integratedTargets = map[string]bool{
+	"x86_64-linux": true,
+	"amd64-linux":  true,
+}
+
+// Return the file the output of `mode` is written to without -o. Like
+// with cc, it is named after the input file, except for executables.
+// The assembly of programs read from -e or the standard input, given
+// with an empty `input`, is printed. Go source is named like Go files.
+func defaultOutput(mode int, input string) string {
+	base := strings.TrimSuffix(filepath.Base(input), filepath.Ext(input))
+	switch {
+	case mode == modeExec:
+		return "a.out"
+	case input == "" && mode == modeObject:
+		return "a.o"
+	case input == "":
+		return ""
+	case mode == modeObject:
+		return base + ".o"
+	case config.TargetTriple() == "go":
+		return base + ".go"
+	case mode == modeAsm && config.EmitQBE:
+		return base + ".ssa"
+	}
+	return base + ".s"
+}
+
+// Options of one invocation.
+type driver struct {
+	mode       int
+	integrated bool
+	output     string // Output file given with -o, if any
+	dumpTokens bool   // Whether to print the tokens instead of compiling
+	dumpAST    string // Format to print the AST in instead of compiling, if any
+	deps       bool   // Whether -MMD or -MD was given
+	systemDeps bool   // Whether -MD was given, which also lists system headers
+	depOutput  string // Dependency file given with -MF, if any
+	depTarget  string // Target of the dependency rule given with -MT, if any
+	cpp        string // Preprocessor given with --use-external-cpp, if any
+	listing    bool   // Whether -flisting was given
+
+	// Headers the preprocessor included in each file, for -MMD and -MD.
+	headers map[*token.File][]string
+
+	// Object files, archives and linker options given on the command
+	// line, in order. They follow the objects compiled from C files.
+	linkInputs []string
+}
+
+// Return a fresh backend for one translation unit, writing to `out`.
+func (d *driver) backend(out io.Writer) codegen.Backend {
+	backend, err := config.NewBackend(out)
+	if err != nil {
+		fatalStatus(token.ExitUsage, err.Error())
+	}
+	return backend
+}
+
+// A C program to compile: a file, the standard input
+// if the path is "-", or a program given with -e.
+type input struct {
+	path    string
+	program string // Program given with -e, if there is no path
+}
+
+// Add the program of `in` to the files of the session.
+func openInput(in input) *token.File {
+	return addInput(session, in)
+}
+
+// Add the program of `in` to the files of `s`.
+func addInput(s *token.Session, in input) *token.File {
+	switch in.path {
+	case "":
+		return s.AddFile("<command-line>", "", in.program)
+	case "-":
+		file, err := s.ReadFile("<stdin>", "", os.Stdin)
+		if err != nil {
+			fatal(session.Tr("cannot read the standard input: %v", err))
+		}
+		return file
+	}
+	f, err := os.Open(in.path)
+	if err != nil {
+		fatal(session.Tr("cannot read %s: %v", in.path, err))
+	}
+	defer f.Close()
+	file, err := s.ReadFile(in.path, in.path, f)
+	if err != nil {
+		fatal(session.Tr("cannot read %s: %v", in.path, err))
+	}
+	return file
+}
+
+// Add the program of `in` to the files of the session once preprocessed
+// by the external preprocessor, which reports its own errors. The file
+// keeps the name of the input, and the line markers of the preprocessor
+// map its lines to those of the source.
+func (d *driver) preprocess(in input) *token.File {
+	var args []string
+	for _, dir := range config.IncludePaths {
+		args = append(args, "-I"+dir)
+	}
+	args = append(args, config.Macros...)
+	if config.Standard != "" {
+		args = append(args, "-std="+config.Standard)
+	}
+	cmd := exec.Command(d.cpp, append(args, "-")...)
+	name := in.path
+	switch in.path {
+	case "":
+		name = "<command-line>"
+		cmd.Stdin = strings.NewReader(in.program)
+	case "-":
+		name = "<stdin>"
+		cmd.Stdin = os.Stdin
+	default:
+		cmd.Args[len(cmd.Args)-1] = in.path
+	}
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		fatal(session.Tr("preprocessor failed: %v", err))
+	}
+	file := session.AddFile(name, in.path, string(out))
+	if d.deps {
+		if d.headers == nil {
+			d.headers = map[*token.File][]string{}
+		}
+		d.headers[file] = cppHeaders(string(out), in.path, d.systemDeps)
+	}
+	return file
+}
+
+// Return the headers named by the line markers of `out`, the output of
+// the preprocessor for the file `path`, in the order they were first
+// included. Pseudo-files such as "<built-in>" are left out, and so are
+// system headers, which markers flag with 3, unless `system` is set.
+func cppHeaders(out string, path string, system bool) []string {
+	var headers []string
+	seen := map[string]bool{path: true}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || fields[0] != "#" {
+			continue
+		}
+		if _, err := strconv.Atoi(fields[1]); err != nil {
+			continue
+		}
+		rest := line[strings.IndexByte(line, '#')+1:]
+		i := strings.IndexByte(rest, '"')
+		if i < 0 {
+			continue
+		}
+		quoted, err := strconv.QuotedPrefix(rest[i:])
+		if err != nil {
+			continue
+		}
+		name, err := strconv.Unquote(quoted)
+		if err != nil || seen[name] || strings.HasPrefix(name, "<") {
+			continue
+		}
+		isSystem := false
+		for _, flag := range strings.Fields(rest[i+len(quoted):]) {
+			isSystem = isSystem || flag == "3"
+		}
+		if system || !isSystem {
+			seen[name] = true
+			headers = append(headers, name)
+		}
+	}
+	return headers
+}
+
+// Run `program` with the interpreter and exit with the exit status
+// the compiled program would have. Runtime errors are printed like
+// the sanitizer prints them.
+func interpret(program *parser.Function) {
+	value, err := interp.Run(program, interp.Options{SanitizeUndefined: config.SanitizeUndefined})
+	var runtimeErr *interp.RuntimeError
+	if errors.As(err, &runtimeErr) {
+		fmt.Fprintln(os.Stderr, runtimeErr)
+		os.Exit(runtimeErr.Status)
+	}
+	os.Exit(value & 0xff)
+}
+
+// Compile each of `inputs` as a separate translation unit and produce the
+// output of the selected mode. Executables are linked from all of them.
+func (d *driver) run(inputs []input) {
+	if d.mode != modeAsm && d.mode != modePreprocess && !d.dumpTokens && d.dumpAST == "" {
+		if config.EmitLLVM {
+			fatalStatus(token.ExitUsage, session.Tr("-emit-llvm requires -S"))
+		}
+		if codegen.PrintOnly(config.TargetTriple()) {
+			fatalStatus(token.ExitUsage, session.Tr("target \"%s\" requires -S", config.TargetTriple()))
+		}
+	}
+	if config.EmitQBE && config.EmitLLVM {
+		fatalStatus(token.ExitUsage, session.Tr("cannot use -emit-qbe with -emit-llvm"))
+	}
+	if _, ok := codegen.QBETargets[config.TargetTriple()]; config.EmitQBE && !ok {
+		fatalStatus(token.ExitUsage, session.Tr("target \"%s\" does not support -emit-qbe", config.TargetTriple()))
+	}
+	if d.listing && config.EmitQBE {
+		fatalStatus(token.ExitUsage, session.Tr("cannot use -flisting with -emit-qbe"))
+	}
+	if d.listing && config.EmitLLVM {
+		fatalStatus(token.ExitUsage, session.Tr("cannot use -flisting with -emit-llvm"))
+	}
+	if d.listing && codegen.PrintOnly(config.TargetTriple()) {
+		fatalStatus(token.ExitUsage, session.Tr("target \"%s\" does not support -flisting", config.TargetTriple()))
+	}
+	if d.mode != modeExec && d.output != "" && len(inputs) > 1 {
+		fatalStatus(token.ExitUsage, session.Tr("cannot specify -o with -S or -c and multiple files"))
+	}
+	if d.mode == modeRun && len(inputs) > 1 {
+		fatalStatus(token.ExitUsage, session.Tr("cannot specify -run with multiple files"))
+	}
+	if d.deps && (d.depOutput != "" || d.depTarget != "") && len(inputs) > 1 {
+		fatalStatus(token.ExitUsage, session.Tr("cannot specify -MF or -MT with multiple files"))
+	}
+	if d.mode != modeExec {
+		for _, input := range d.linkInputs {
+			warn(session.Tr("%s: linker input unused because linking not done", input))
+		}
+	}
+	// Every file is compiled before anything is assembled, so that an
+	// error in one of them leaves no partial objects behind.
+	var files []*token.File
+	var sources [][]byte
+	var preprocessed bytes.Buffer
+	status := 0 // Exit status of the files failed so far
+	for _, in := range inputs {
+		var file *token.File
+		if d.cpp != "" {
+			file = d.preprocess(in)
+		} else {
+			file = openInput(in)
+		}
+		files = append(files, file)
+		enterPhase("tokenizing", file)
+		tok, err := lexer.Tokenize(file)
+		progress.tokens = tok
+		if timeReport {
+			stats.tokens += len(tok) - 1 // All but EOF
+		}
+		var program *parser.Function
+		if d.mode == modePreprocess && d.cpp != "" {
+			preprocessed.WriteString(file.Contents)
+		} else if d.mode == modePreprocess {
+			lexer.WritePreprocessed(&preprocessed, file, tok)
+		} else if d.dumpTokens {
+			lexer.DumpTokens(os.Stdout, tok)
+		} else {
+			enterPhase("parsing", file)
+			program, err = parser.Parse(tok)
+			progress.program = program
+			if timeReport {
+				stats.nodes += countNodes(program.Body)
+			}
+		}
+		// All errors in a file are reported together, and the
+		// remaining files are still parsed to report theirs.
+		file.Report()
+		if err != nil {
+			if s := file.ExitStatus(); status == 0 || s < status {
+				status = s
+			}
+			continue
+		}
+		if d.mode == modePreprocess || d.dumpTokens || status != 0 {
+			continue
+		}
+		if d.dumpAST == "text" {
+			parser.DumpAST(os.Stdout, program)
+			continue
+		}
+		if d.dumpAST == "json" {
+			parser.DumpASTJSON(os.Stdout, program)
+			continue
+		}
+		if d.mode == modeRun {
+			enterPhase("interpreting", file)
+			interpret(program)
+		}
+		enterPhase("optimizing", file)
+		if session.VerifyAST {
+			if err := parser.Verify(program, "parsing"); err != nil {
+				exitWith(err)
+			}
+		}
+		if err := codegen.RunASTPasses(program, &config.Options); err != nil {
+			exitWith(err)
+		}
+		enterPhase("generating code", file)
+		if d.listing {
+			if err := d.writeListing(file, program); err != nil {
+				exitWith(err)
+			}
+		}
+		output := d.output
+		if output == "" {
+			output = defaultOutput(d.mode, file.Path)
+		}
+		if d.mode == modeAsm && (output == "" || output == "-") {
+			if err := d.backend(os.Stdout).Gen(program); err != nil {
+				exitWith(err)
+			}
+			continue
+		}
+		var src bytes.Buffer
+		if err := d.backend(&src).Gen(program); err != nil {
+			exitWith(err)
+		}
+		if d.mode == modeAsm {
+			if err := os.WriteFile(output, src.Bytes(), 0o644); err != nil {
+				fatal(err.Error())
+			}
+			continue
+		}
+		if config.EmitQBE {
+			asm, err := runQBE(src.Bytes(), codegen.QBETargets[config.TargetTriple()])
+			if err != nil {
+				fatal(err.Error())
+			}
+			sources = append(sources, asm)
+			continue
+		}
+		sources = append(sources, src.Bytes())
+	}
+	if d.mode == modePreprocess {
+		// Like with gcc, the program is printed even when it has errors.
+		if d.output == "" || d.output == "-" {
+			os.Stdout.Write(preprocessed.Bytes())
+		} else if err := os.WriteFile(d.output, preprocessed.Bytes(), 0o644); err != nil {
+			fatal(err.Error())
+		}
+	}
+	if status != 0 {
+		os.Exit(status)
+	}
+	if d.mode == modePreprocess || d.dumpTokens || d.dumpAST != "" {
+		return
+	}
+	enterPhase("assembling", nil)
+	if d.mode == modeObject {
+		for i, src := range sources {
+			output := d.output
+			if output == "" {
+				output = defaultOutput(d.mode, files[i].Path)
+			}
+			if err := assemble(src, config.TargetTriple(), d.integrated, output); err != nil {
+				fatal(err.Error())
+			}
+		}
+	}
+	if d.mode == modeExec {
+		output := d.output
+		if output == "" {
+			output = defaultOutput(d.mode, "")
+		}
+		var objects []string
+		var err error
+		for _, src := range sources {
+			var object string
+			if object, err = tempFile("gocc-*.o", nil); err != nil {
+				break
+			}
+			objects = append(objects, object)
+			if err = assemble(src, config.TargetTriple(), d.integrated, object); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			enterPhase("linking", nil)
+			err = link(append(objects, d.linkInputs...), output)
+		}
+		for _, object := range objects {
+			os.Remove(object)
+		}
+		if err != nil {
+			fatal(err.Error())
+		}
+	}
+	if d.deps {
+		for _, file := range files {
+			if err := d.writeDeps(file); err != nil {
+				fatal(err.Error())
+			}
+		}
+	}
+}
+
+// Write the make rule listing what the object of `file` depends on,
+// for -MMD or -MD: the file itself and, with --use-external-cpp, the
+// headers it includes, system headers only with -MD. The rule goes to
+// the -MF file, or next to the object with a .d suffix.
+func (d *driver) writeDeps(file *token.File) error {
+	if file.Path == "" {
+		// Programs from -e or the standard input have no file to depend on.
+		return nil
+	}
+	target := defaultOutput(modeObject, file.Path)
+	if d.mode == modeObject && d.output != "" {
+		target = d.output
+	}
+	output := d.depOutput
+	if output == "" {
+		output = strings.TrimSuffix(target, filepath.Ext(target)) + ".d"
+	}
+	if d.depTarget != "" {
+		target = d.depTarget
+	}
+	rule := makeEscape(target) + ": " + makeEscape(file.Path)
+	for _, header := range d.headers[file] {
+		rule += " \\\n " + makeEscape(header)
+	}
+	return os.WriteFile(output, []byte(rule+"\n"), 0o644)
+}
+
+// Escape `path` for a make rule the way gcc does.
+func makeEscape(path string) string {
+	path = strings.ReplaceAll(path, "$", "$$")
+	path = strings.ReplaceAll(path, "#", "\\#")
+	return strings.ReplaceAll(path, " ", "\\ ")
+}
+
+// Create a temporary file holding `data` and return its name.
+func tempFile(pattern string, data []byte) (string, error) {
+	f, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", err
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// Assemble `src` into the object file `output`.
+func assemble(src []byte, target string, integrated bool, output string) error {
+	if !integrated {
+		if _, err := exec.LookPath("as"); err != nil {
+			integrated = true
+		}
+	}
+	if !integrated {
+		return runAssembler(src, output)
+	}
+	if !integratedTargets[target] {
+		return errors.New(session.Tr("the integrated assembler does not support target \"%s\"", target))
+	}
+	obj, err := asm.Assemble(string(src))
+	if err != nil {
+		return errors.New(session.Tr("assembler: %v", err))
+	}
+	return os.WriteFile(output, obj, 0o644)
+}
+
+// Assemble `src` with the system assembler, which
+// reads it from a temporary file.
+func runAssembler(src []byte, output string) error {
+	tmp, err := tempFile("gocc-*.s", src)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	cmd := exec.Command("as", "-o", output, tmp)
+	cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+	if err := cmd.Run(); err != nil {
+		// Do not leave a partial object behind.
+		os.Remove(output)
+		return errors.New(session.Tr("assembler failed: %v", err))
+	}
+	return nil
+}
+
+// Compile the QBE IL `src` to assembly for the QBE target `target`.
+func runQBE(src []byte, target string) ([]byte, error) {
+	var out bytes.Buffer
+	cmd := exec.Command("qbe", "-t", target)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = bytes.NewReader(src), &out, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, errors.New(session.Tr("qbe failed: %v", err))
+	}
+	return out.Bytes(), nil
+}
+
+// Directories searched for the C runtime objects when linking with ld.
+var crtDirs = []string{"/usr/lib/x86_64-linux-gnu", "/usr/lib64", "/usr/lib"}
+
+// Link the object files `objects` with the C library into the
+// executable `output`. Linker options given with the objects, such
+// as -l, are kept in place. A failed link leaves no output behind.
+func link(objects []string, output string) error {
+	cmd := exec.Command("cc", append([]string{"-o", output}, objects...)...)
+	if _, err := exec.LookPath("cc"); err != nil {
+		if cmd, err = ldCommand(objects, output); err != nil {
+			return err
+		}
+	}
+	cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+	if err := cmd.Run(); err != nil {
+		os.Remove(output)
+		return errors.New(session.Tr("linker failed: %v", err))
+	}
+	return nil
+}
+
+// Return an ld invocation linking a non-PIE executable
+// the way the C compiler driver would.
+func ldCommand(objects []string, output string) (*exec.Cmd, error) {
+	for _, dir := range crtDirs {
+		if _, err := os.Stat(filepath.Join(dir, "crt1.o")); err != nil {
+			continue
+		}
+		args := []string{"-o", output,
+			"-dynamic-linker", "/lib64/ld-linux-x86-64.so.2",
+			filepath.Join(dir, "crt1.o"), filepath.Join(dir, "crti.o")}
+		for _, object := range objects {
+			if strings.HasPrefix(object, "-Wl,") {
+				args = append(args, strings.Split(object[len("-Wl,"):], ",")...)
+			} else {
+				args = append(args, object)
+			}
+		}
+		args = append(args, "-L"+dir, "-lc", filepath.Join(dir, "crtn.o"))
+		return exec.Command("ld", args...), nil
+	}
+	return nil, errors.New(session.Tr("cannot find the C runtime objects"))
+}
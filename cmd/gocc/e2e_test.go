@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/youngfr/gocc/codegen"
+	"github.com/youngfr/gocc/lexer"
+	"github.com/youngfr/gocc/parser"
+	"github.com/youngfr/gocc/token"
+)
+
+// End-to-end tests
+//
+// Each program is compiled in process, checked with the AST verifier,
+// assembled and linked the way the driver does it, and run. The tests
+// are skipped where x86-64 Linux executables cannot run, or when there
+// is neither a C compiler nor ld to link with. The cases are those of
+// test/test.sh, with their variables declared, and more.
+
+type e2eCase struct {
+	status int    // Exit status of the program
+	stdout string // Output of the program
+	src    string
+}
+
+var e2eCases = []e2eCase{
+	{0, "", "0==1;"},
+	{1, "", "42==42;"},
+	{1, "", "0!=1;"},
+	{0, "", "42!=42;"},
+	{0, "", "return 0;"},
+	{42, "", "return 42;"},
+	{21, "", "return 5+20-4;"},
+	{41, "", "return  12 + 34 - 5 ;"},
+	{47, "", "return 5+6*7;"},
+	{15, "", "return 5*(9-6);"},
+	{4, "", "return (3+5)/2;"},
+	{10, "", "return -10+20;"},
+	{10, "", "return - -10;"},
+	{10, "", "return - - +10;"},
+	{0, "", "return 0==1;"},
+	{1, "", "return 42==42;"},
+	{1, "", "return 0!=1;"},
+	{0, "", "return 42!=42;"},
+	{1, "", "return 0<1;"},
+	{0, "", "return 1<1;"},
+	{0, "", "return 2<1;"},
+	{1, "", "return 0<=1;"},
+	{1, "", "return 1<=1;"},
+	{0, "", "return 2<=1;"},
+	{1, "", "return 1>0;"},
+	{0, "", "return 1>1;"},
+	{0, "", "return 1>2;"},
+	{1, "", "return 1>=0;"},
+	{1, "", "return 1>=1;"},
+	{0, "", "return 1>=2;"},
+	{3, "", "int a=3; return a;"},
+	{8, "", "int a=3; int z=5; return a+z;"},
+	{6, "", "int a; int b; a=b=3; return a+b;"},
+	{3, "", "int foo=3; return foo;"},
+	{8, "", "int foo123=3; int bar=5; return foo123+bar;"},
+	{5, "", "int aa=1, bb=2; return 2*(aa*bb)+aa*(bb+ -1);"},
+	{1, "", "return 1; 2; 3;"},
+	{2, "", "1; return 2; 3;"},
+	{3, "", "1; 2; return 3;"},
+	{0, "", "{ return 0; }"},
+	{42, "", "{ return 42; }"},
+	{21, "", "{ return 5+20-4; }"},
+	{41, "", "{ return  12 + 34 - 5 ; }"},
+	{47, "", "{ return 5+6*7; }"},
+	{15, "", "{ return 5*(9-6); }"},
+	{4, "", "{ return (3+5)/2; }"},
+	{10, "", "{ return -10+20; }"},
+	{10, "", "{ return - -10; }"},
+	{10, "", "{ return - - +10; }"},
+	{0, "", "{ return 0==1; }"},
+	{1, "", "{ return 42==42; }"},
+	{1, "", "{ return 0!=1; }"},
+	{0, "", "{ return 42!=42; }"},
+	{1, "", "{ return 0<1; }"},
+	{0, "", "{ return 1<1; }"},
+	{0, "", "{ return 2<1; }"},
+	{1, "", "{ return 0<=1; }"},
+	{1, "", "{ return 1<=1; }"},
+	{0, "", "{ return 2<=1; }"},
+	{1, "", "{ return 1>0; }"},
+	{0, "", "{ return 1>1; }"},
+	{0, "", "{ return 1>2; }"},
+	{1, "", "{ return 1>=0; }"},
+	{1, "", "{ return 1>=1; }"},
+	{0, "", "{ return 1>=2; }"},
+	{3, "", "{ int a=3; return a; }"},
+	{8, "", "{ int a=3; int z=5; return a+z; }"},
+	{6, "", "{ int a; int b; a=b=3; return a+b; }"},
+	{3, "", "{ int foo=3; return foo; }"},
+	{8, "", "{ int foo123=3; int bar=5; return foo123+bar; }"},
+	{1, "", "{ return 1; 2; 3; }"},
+	{2, "", "{ 1; return 2; 3; }"},
+	{3, "", "{ 1; 2; return 3; }"},
+	{3, "", "{ {1; {2;} return 3;} }"},
+	{5, "", "{ ;;; return 5; }"},
+	{3, "", "{ if (0) return 2; return 3; }"},
+	{3, "", "{ if (1-1) return 2; return 3; }"},
+	{2, "", "{ if (1) return 2; return 3; }"},
+	{2, "", "{ if (2-1) return 2; return 3; }"},
+	{4, "", "{ if (0) { 1; 2; return 3; } else { return 4; } }"},
+	{3, "", "{ if (1) { 1; 2; return 3; } else { return 4; } }"},
+	{3, "", "if(0) return 0; if (0) {return 1;} if (0) {return 2;} else return 3;"},
+	{55, "", "{ int i=0; int j=0; for (i=0; i<=10; i=i+1) j=i+j; return j; }"},
+	{3, "", "{ for (;;) {return 3;} return 5; }"},
+	{5, "", "int i = 0; for (; i < 5; i = i+1) {;} return 5;"},
+	{6, "", "int a; for (a = (1+3)*3; ; a = a-1) if (a==3) return 2*a;"},
+	{6, "", "int a; for(a=5;;) {if (a==1) return 3*(a+1); else a = a-1;}"},
+	{5, "", "int i = 0; while (i < 10) {if (i==5) return i; i = i+1;}"},
+	{9, "", "{ int i=0; while (i<9) i=i+1; return i; }"},
+	{2, "", "int i; if (1) {i = 5; for (;;i = i-1) if (i==2) return i;}"},
+	{45, "", "int s = 0; /* sum */ for (int i = 0; i < 10; i = i+1) s = s+i; // done\nreturn s;"},
+	{3, "", "int x=3; return *&x;"},
+	{3, "", "int x=3; int *y=&x; int **z=&y; return **z;"},
+	{5, "", "int x=3; int *y=&x; *y=5; return x;"},
+	{5, "", "{ int x=3; int y=5; return *(&x+1); }"},
+	{3, "", "int x=3; int y=5; return *(&y-1);"},
+	{5, "", "{ int x=3; int y=5; return *(&x-(-1)); }"},
+	{7, "", "{ int x=3; int y=5; *(&x+1)=7; return y; }"},
+	{7, "", "int x=3; int y=5; *(&y-2+1)=7; return x;"},
+	{5, "", "int x=3; return ((&x+2) - &x)+3;"},
+	{0, "", "0==1; return 0;"},
+	{1, "", "int x=3; int y=x/3; return y*1+0;"},
+	{7, "", "int a=1; int b=2; if (a<b) return 7; return 9;"},
+	{9, "", "int a=1; int b=2; if (a>=b) return 7; return 9;"},
+	{1, "", "int a=1; int b=2; if (a!=b) if (a==1) return 1; return 0;"},
+	{6, "", "int i=0; int s=0; while (i!=4) {s=s+i; i=i+1;} return s;"},
+	{1, "", "int x=2147483647; x=x+1; return x<0;"},
+	{1, "", "int x=65536; x=x*x*2; return x==0;"},
+	{5, "", "int a=7; int b=5; return *(&a+1);"},
+	{1, "", "int a; int b; return &b-&a;"},
+	{3, "", "int x=-7; return 0-x/2;"},
+	{1, "", "int x=2147483647; return x+1<x;"},
+	{3, "", "int a=3; int b=5; int m; if (a<b) m=a; else m=b; return m;"},
+	{5, "", "int a=3; int b=5; int m; if (a>b) m=a; else m=b; return m;"},
+	{9, "", "int a=9; int m=1; if (a!=0) m=a; return m;"},
+	{1, "", "int a=0; int m=1; if (a) m=a; return m;"},
+	{7, "", "int a=7; int b=2; int m=0; if (a==7) m=a; else m=b; if (m<=2) m=0; return m;"},
+}
+
+// A way of building the programs.
+type e2eConfig struct {
+	name       string
+	optLevel   int
+	integrated bool // Whether to use the integrated assembler
+}
+
+var e2eConfigs = []e2eConfig{
+	{"O0", 0, false},
+	{"O2", 2, false},
+	{"integrated-as", 1, true},
+}
+
+// Skip the test unless the programs it builds can be linked and run.
+func requireToolchain(t testing.TB) {
+	t.Helper()
+	if runtime.GOOS != "linux" || runtime.GOARCH != "amd64" {
+		t.Skipf("cannot run x86_64-linux programs on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+	if _, err := exec.LookPath("cc"); err == nil {
+		return
+	}
+	if _, err := exec.LookPath("ld"); err != nil {
+		t.Skip("no C compiler or ld to link with")
+	}
+}
+
+// Compile `src` with `cfg`, link it, run it and return its exit status and output.
+func compileAndRun(t *testing.T, src string, cfg e2eConfig) (int, string) {
+	t.Helper()
+	session := token.NewSession()
+	session.VerifyAST = true
+	opts := &codegen.Options{OptLevel: cfg.optLevel}
+
+	file := session.AddFile("<test>", "", src)
+	tok, _ := lexer.Tokenize(file)
+	program, err := parser.Parse(tok)
+	if err != nil {
+		file.PrintDiagnostics(os.Stderr)
+		t.Fatalf("cannot compile %q", src)
+	}
+	if err := parser.Verify(program, "parsing"); err != nil {
+		t.Fatal(err)
+	}
+	if err := codegen.RunASTPasses(program, opts); err != nil {
+		t.Fatal(err)
+	}
+	var asm bytes.Buffer
+	if err := codegen.Targets["x86_64-linux"](&asm, opts).Gen(program); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	object := filepath.Join(dir, "prog.o")
+	executable := filepath.Join(dir, "prog")
+	if err := assemble(asm.Bytes(), "x86_64-linux", cfg.integrated, object); err != nil {
+		t.Fatalf("%q: %v", src, err)
+	}
+	if err := link([]string{object}, executable); err != nil {
+		t.Fatalf("%q: %v", src, err)
+	}
+	var stdout bytes.Buffer
+	cmd := exec.Command(executable)
+	cmd.Stdout = &stdout
+	err = cmd.Run()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		return exit.ExitCode(), stdout.String()
+	}
+	if err != nil {
+		t.Fatalf("%q: %v", src, err)
+	}
+	return 0, stdout.String()
+}
+
+func TestEndToEnd(t *testing.T) {
+	requireToolchain(t)
+	for _, cfg := range e2eConfigs {
+		for i, c := range e2eCases {
+			t.Run(fmt.Sprintf("%s/%d", cfg.name, i), func(t *testing.T) {
+				status, stdout := compileAndRun(t, c.src, cfg)
+				if status != c.status {
+					t.Errorf("%q: exit status %d, want %d", c.src, status, c.status)
+				}
+				if stdout != c.stdout {
+					t.Errorf("%q: output %q, want %q", c.src, stdout, c.stdout)
+				}
+			})
+		}
+	}
+}
+
+// Compile the cases to Go, build them in one go and run them.
+func TestGoBackend(t *testing.T) {
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("no go command to build with")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module e2e\n\ngo 1.20\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for i, c := range e2eCases {
+		file := token.NewSession().AddFile("<test>", "", c.src)
+		tok, _ := lexer.Tokenize(file)
+		program, err := parser.Parse(tok)
+		if err != nil {
+			t.Fatalf("cannot compile %q", c.src)
+		}
+		var src bytes.Buffer
+		if err := codegen.Targets["go"](&src, &codegen.Options{}).Gen(program); err != nil {
+			t.Fatal(err)
+		}
+		pkg := filepath.Join(dir, fmt.Sprint(i))
+		if err := os.Mkdir(pkg, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(pkg, "main.go"), src.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	build := exec.Command(goTool, "build", "-o", filepath.Join(dir, "bin")+string(filepath.Separator), "./...")
+	build.Dir = dir
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("cannot build the Go programs: %v\n%s", err, out)
+	}
+	for i, c := range e2eCases {
+		var stdout bytes.Buffer
+		cmd := exec.Command(filepath.Join(dir, "bin", fmt.Sprint(i)))
+		cmd.Stdout = &stdout
+		status := 0
+		var exit *exec.ExitError
+		if err := cmd.Run(); errors.As(err, &exit) {
+			status = exit.ExitCode()
+		} else if err != nil {
+			t.Fatalf("%q: %v", c.src, err)
+		}
+		if status != c.status || stdout.String() != c.stdout {
+			t.Errorf("%q: exit status %d and output %q, want %d and %q", c.src, status, stdout.String(), c.status, c.stdout)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import "testing"
+
+func TestOptimizationLevel(t *testing.T) {
+	for _, c := range []struct {
+		arg   string
+		level int
+		ok    bool
+	}{
+		{"-O", 1, true},
+		{"-O0", 0, true},
+		{"-O2", 2, true},
+		{"-Os", 1, true},
+		{"-Oz", 1, true},
+		{"-Og", 1, true},
+		{"-Ofast", 2, true},
+		{"-Ox", 0, false},
+		{"-O-1", 0, false},
+	} {
+		if level, ok := optimizationLevel(c.arg); ok != c.ok || ok && level != c.level {
+			t.Errorf("optimizationLevel(%q) = %d, %v, want %d, %v", c.arg, level, ok, c.level, c.ok)
+		}
+	}
+	if cfg, err := serveConfig(serveRequest{Options: []string{"-Ofast"}}); err != nil || cfg.OptLevel != 2 {
+		t.Errorf("-Ofast gives level %d, %v, want 2", cfg.OptLevel, err)
+	}
+	if _, err := serveConfig(serveRequest{Options: []string{"-Ox"}}); err == nil || err.Error() != "unsupported optimization level \"-Ox\"" {
+		t.Errorf("-Ox gives %v, want an unsupported optimization level", err)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+
+	"github.com/youngfr/gocc/lexer"
+	"github.com/youngfr/gocc/parser"
+	"github.com/youngfr/gocc/token"
+)
+
+// Formatting
+//
+// "gocc fmt" parses each file and prints it back as C to the standard
+// output, with its comments, see parser.FormatSource. Formatting the
+// output again leaves it as is. With -d, the differences between each
+// file and its formatted source are printed instead, and with -w, the
+// files are overwritten with their formatted source when it differs.
+// The standard input is read when no file is given, which cannot be
+// written back. A file with errors is reported like when compiling
+// instead of being formatted, and the remaining files are still
+// formatted.
+
+// Format the files of the command line `args`, and exit with the status
+// of the errors of the files that failed, if any.
+func format(args []string) {
+	diff, write := false, false
+	var inputs []input
+	for _, arg := range args {
+		switch {
+		case arg == "-d":
+			diff = true
+		case arg == "-w":
+			write = true
+		case strings.HasPrefix(arg, "-") && arg != "-":
+			usage()
+		default:
+			inputs = append(inputs, input{path: arg})
+		}
+	}
+	if len(inputs) == 0 {
+		inputs = append(inputs, input{path: "-"})
+	}
+	status := 0
+	for _, in := range inputs {
+		if write && in.path == "-" {
+			fatalStatus(token.ExitUsage, session.Tr("cannot use -w with the standard input"))
+		}
+		file := openInput(in)
+		formatted, err := formatFile(file)
+		file.Report()
+		if err != nil {
+			if s := file.ExitStatus(); status == 0 || s < status {
+				status = s
+			}
+			continue
+		}
+		if diff {
+			os.Stdout.WriteString(unifiedDiff(file.Name+".orig", file.Name, file.Contents, formatted))
+		}
+		if write && formatted != file.Contents {
+			if err := os.WriteFile(in.path, []byte(formatted), 0o644); err != nil {
+				fatal(err.Error())
+			}
+		}
+		if !diff && !write {
+			os.Stdout.WriteString(formatted)
+		}
+	}
+	if status != 0 {
+		os.Exit(status)
+	}
+}
+
+// Return the formatted source of `file`, or the errors of the file.
+func formatFile(file *token.File) (string, error) {
+	tok, _ := lexer.Tokenize(file)
+	program, err := parser.Parse(tok)
+	if err != nil {
+		return "", err
+	}
+	var comments []parser.Comment
+	for _, span := range lexer.Classify(file, tok) {
+		if span.Class == lexer.ClassComment {
+			comments = append(comments, parser.Comment{Begin: span.Begin, End: span.End})
+		}
+	}
+	var b bytes.Buffer
+	parser.FormatSource(&b, program, comments)
+	return b.String(), nil
+}
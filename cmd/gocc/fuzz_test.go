@@ -0,0 +1,53 @@
+package main
+
+import (
+	"io"
+	"testing"
+
+	"github.com/youngfr/gocc/lexer"
+	"github.com/youngfr/gocc/parser"
+	"github.com/youngfr/gocc/token"
+)
+
+// Fuzz tests of the front end. Whatever the input, the lexer and the
+// parser must report errors instead of panicking. Run them with
+//
+//	go test -fuzz FuzzTokenize
+//	go test -fuzz FuzzParse
+
+// Add the end-to-end programs and some malformed ones as seeds.
+func addSeeds(f *testing.F) {
+	for _, c := range e2eCases {
+		f.Add(c.src)
+	}
+	for _, src := range []string{
+		"", "+", "1+", "a++", "x+=1;", "p->x", "a--", "1<<2", "a>>=1", "a&&b", "&=",
+		"{", "}", "(", ")", "int", "int *", "int x = ;", "return", "if (", "for (;;",
+		"99999999999999999999", "int x; *x;", "int x; &(x+1);", "1 = 2;", "@",
+		"int x;\n\treturn &x\n", "{ int a; {{{ a = ; }}} return a; }",
+	} {
+		f.Add(src)
+	}
+}
+
+func FuzzTokenize(f *testing.F) {
+	addSeeds(f)
+	f.Fuzz(func(t *testing.T, src string) {
+		file := token.NewSession().AddFile("<fuzz>", "", src)
+		tok, _ := lexer.Tokenize(file)
+		lexer.DumpTokens(io.Discard, tok)
+		for _, d := range file.Diagnostics {
+			d.File.Position(d.Begin)
+		}
+	})
+}
+
+func FuzzParse(f *testing.F) {
+	addSeeds(f)
+	f.Fuzz(func(t *testing.T, src string) {
+		tok, _ := lexer.Tokenize(token.NewSession().AddFile("<fuzz>", "", src))
+		if program, err := parser.Parse(tok); err == nil {
+			parser.DumpAST(io.Discard, program)
+		}
+	})
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	goparser "go/parser"
+	gotoken "go/token"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/youngfr/gocc"
+	"github.com/youngfr/gocc/token"
+)
+
+// Generating Go
+//
+// "gocc gen" compiles the C programs of Go files into a Go file of the
+// same package, see gocc.Generate, for go:generate:
+//
+//	//go:generate gocc gen -O1 $GOFILE
+//
+//	//gocc:gen go Fib
+//	const fibSource = `
+//	int a = 0; int b = 1; int i;
+//	for (i = 0; i < 10; i = i + 1) { int t = a + b; a = b; b = t; }
+//	return a;`
+//
+// Each string constant annotated with a //gocc:gen comment holding the
+// kind of snippet, asm, object or go, and the name to declare is
+// compiled with the options of the command line, which are -target and
+// those "gocc serve" accepts, and its diagnostics are located in the Go
+// file. The output of x.go is x_gocc.go, unless -o names it, and is
+// only written if all the programs compile.
+
+// Annotation of the constants holding the programs.
+const genDirective = "//gocc:gen "
+
+// Compile the programs of the Go files of the command line `args`.
+func gen(args []string) {
+	output := ""
+	target := ""
+	var files []string
+	var options []string
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "-o" && i+1 < len(args):
+			output = args[i+1]
+			i++
+		case args[i] == "-target" && i+1 < len(args):
+			target = args[i+1]
+			i++
+		case strings.HasSuffix(args[i], ".go"):
+			files = append(files, args[i])
+		default:
+			options = append(options, args[i])
+		}
+	}
+	if len(files) == 0 || output != "" && len(files) > 1 {
+		usage()
+	}
+	cfg, err := serveConfig(serveRequest{Target: target, Options: options})
+	if err != nil {
+		fatalStatus(token.ExitUsage, err.Error())
+	}
+	for _, path := range files {
+		pkg, snippets, err := genSnippets(path)
+		if err != nil {
+			fatal(err.Error())
+		}
+		var out bytes.Buffer
+		diags, err := gocc.Generate(&out, pkg, "gocc gen from "+filepath.Base(path), snippets, cfg)
+		for _, d := range diags {
+			session.Reporter.Report(d)
+		}
+		var errs token.ErrorList
+		switch {
+		case errors.As(err, &errs):
+			os.Exit(errs.ExitStatus())
+		case err != nil:
+			fatal(err.Error())
+		}
+		name := output
+		if name == "" {
+			name = strings.TrimSuffix(path, ".go") + "_gocc.go"
+		}
+		if err := os.WriteFile(name, out.Bytes(), 0o644); err != nil {
+			fatal(err.Error())
+		}
+	}
+}
+
+// Return the package of the Go file `path` and its annotated programs.
+func genSnippets(path string) (string, []gocc.Snippet, error) {
+	fset := gotoken.NewFileSet()
+	file, err := goparser.ParseFile(fset, path, nil, goparser.ParseComments)
+	if err != nil {
+		return "", nil, err
+	}
+	var snippets []gocc.Snippet
+	for _, decl := range file.Decls {
+		decl, ok := decl.(*ast.GenDecl)
+		if !ok || decl.Tok != gotoken.CONST {
+			continue
+		}
+		for _, spec := range decl.Specs {
+			spec := spec.(*ast.ValueSpec)
+			doc := spec.Doc
+			if doc == nil && len(decl.Specs) == 1 {
+				doc = decl.Doc
+			}
+			if doc == nil {
+				continue
+			}
+			for _, c := range doc.List {
+				if !strings.HasPrefix(c.Text, genDirective) {
+					continue
+				}
+				position := fset.Position(c.Pos())
+				fields := strings.Fields(c.Text[len(genDirective):])
+				if len(fields) != 2 || len(spec.Values) != 1 {
+					return "", nil, fmt.Errorf("%s: %s", position, session.Tr("expected \"%s<kind> <name>\" before a constant", genDirective))
+				}
+				switch fields[0] {
+				case gocc.SnippetAsm, gocc.SnippetObject, gocc.SnippetGo:
+				default:
+					return "", nil, fmt.Errorf("%s: %s", position, session.Tr("unknown kind of program \"%s\"", fields[0]))
+				}
+				lit, ok := spec.Values[0].(*ast.BasicLit)
+				if !ok || lit.Kind != gotoken.STRING {
+					return "", nil, fmt.Errorf("%s: %s", position, session.Tr("%s is not a string literal", spec.Names[0].Name))
+				}
+				src, _ := strconv.Unquote(lit.Value)
+				snippets = append(snippets, gocc.Snippet{
+					Name:     fields[1],
+					Kind:     fields[0],
+					Source:   src,
+					Filename: path,
+					Line:     fset.Position(lit.Pos()).Line,
+				})
+			}
+		}
+	}
+	return file.Name.Name, snippets, nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGenSnippets(t *testing.T) {
+	dir := t.TempDir()
+	write := func(src string) string {
+		t.Helper()
+		path := filepath.Join(dir, "p.go")
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	path := write("package p\n\n//gocc:gen go F\nconst f = `\nreturn 1;`\n\nconst (\n\t// Not a program.\n\tx = 1\n\t//gocc:gen asm A\n\ta = \"return 2;\"\n)\n")
+	pkg, snippets, err := genSnippets(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := fmt.Sprint(pkg, " ", snippets)
+	want := fmt.Sprintf("p [{F go \nreturn 1; %s 4} {A asm return 2; %s 11}]", path, path)
+	if got != want {
+		t.Errorf("snippets are %q, want %q", got, want)
+	}
+	for _, src := range []string{
+		"package p\n//gocc:gen go\nconst f = \"return 1;\"\n",
+		"package p\n//gocc:gen binary F\nconst f = \"return 1;\"\n",
+		"package p\n//gocc:gen go F\nconst f = 1\n",
+		"package p\nconst f = \n",
+	} {
+		if _, _, err := genSnippets(write(src)); err == nil {
+			t.Errorf("no error for %q", src)
+		}
+	}
+}
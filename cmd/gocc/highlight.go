@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/youngfr/gocc/lexer"
+	"github.com/youngfr/gocc/token"
+)
+
+// Syntax highlighting
+//
+// "gocc highlight" prints each file as HTML to embed in a page, with
+// the spans of lexer.Classify wrapped in elements of their class:
+//
+//	<pre class="gocc"><span class="type">int</span> <span class="identifier">a</span>...</pre>
+//
+// Pages style the classes as they see fit. With -json, each file is
+// written instead as a JSON object on a line of its own, holding its
+// spans with the kind of their token, if any, and their position:
+//
+//	{"file":"a.c","spans":[{"class":"type","kind":"INT","text":"int",
+//	 "line":1,"column":1,"begin":0,"end":3},...]}
+//
+// The standard input is read when no file is given. Files are lexed in
+// a session configured by the -std=, -W and -w options, and by the
+// compilation database given with -p, see compdb.go, but errors are not
+// reported: the text the lexer rejected is of class "invalid".
+
+type highlightSpan struct {
+	Class  string `json:"class"`
+	Kind   string `json:"kind,omitempty"`
+	Text   string `json:"text"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	Begin  int    `json:"begin"`
+	End    int    `json:"end"`
+}
+
+// Highlight the files of the command line `args`.
+func highlight(args []string) {
+	asJSON := false
+	var db compileDB
+	var inputs []input
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "-json":
+			asJSON = true
+		case arg == "-p":
+			if i+1 == len(args) {
+				usage()
+			}
+			var err error
+			if db, err = loadCompileDB(args[i+1]); err != nil {
+				fatal(err.Error())
+			}
+			i++
+		case arg == "-":
+			inputs = append(inputs, input{path: arg})
+		case frontEndOption(arg):
+		case strings.HasPrefix(arg, "-"):
+			usage()
+		default:
+			inputs = append(inputs, input{path: arg})
+		}
+	}
+	if err := config.Apply(session); err != nil {
+		fatalStatus(token.ExitUsage, err.Error())
+	}
+	if len(inputs) == 0 {
+		inputs = append(inputs, input{path: "-"})
+	}
+	for _, in := range inputs {
+		file := db.openInput(in)
+		tok, _ := lexer.Tokenize(file)
+		spans := lexer.Classify(file, tok)
+		if asJSON {
+			highlightJSON(os.Stdout, file, spans)
+		} else {
+			highlightHTML(os.Stdout, file, spans)
+		}
+	}
+}
+
+// Write the contents of `file` as HTML with its `spans` wrapped in elements.
+func highlightHTML(out io.Writer, file *token.File, spans []lexer.Span) {
+	var b strings.Builder
+	b.WriteString(`<pre class="gocc">`)
+	p := 0
+	for _, s := range spans {
+		b.WriteString(html.EscapeString(file.Contents[p:s.Begin]))
+		fmt.Fprintf(&b, `<span class="%s">%s</span>`, s.Class, html.EscapeString(file.Contents[s.Begin:s.End]))
+		p = s.End
+	}
+	b.WriteString(html.EscapeString(file.Contents[p:]))
+	b.WriteString("</pre>\n")
+	io.WriteString(out, b.String())
+}
+
+// Write the `spans` of `file` as a JSON object on a line.
+func highlightJSON(out io.Writer, file *token.File, spans []lexer.Span) {
+	j := struct {
+		File  string          `json:"file"`
+		Spans []highlightSpan `json:"spans"`
+	}{file.Name, []highlightSpan{}}
+	for _, s := range spans {
+		pos := file.PositionFor(s.Begin)
+		span := highlightSpan{
+			Class:  s.Class,
+			Text:   file.Contents[s.Begin:s.End],
+			Line:   pos.Line,
+			Column: pos.Column,
+			Begin:  s.Begin,
+			End:    s.End,
+		}
+		if s.Token != nil {
+			span.Kind = s.Token.Kind.String()
+		}
+		j.Spans = append(j.Spans, span)
+	}
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+	enc.Encode(j)
+}
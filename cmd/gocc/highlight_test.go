@@ -0,0 +1,37 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/youngfr/gocc/lexer"
+	"github.com/youngfr/gocc/token"
+)
+
+func TestHighlight(t *testing.T) {
+	file := token.NewSession().AddFile("a.c", "", "int a; /* <b> */ a %= 2;\nreturn a&1; // end")
+	tok, _ := lexer.Tokenize(file)
+	spans := lexer.Classify(file, tok)
+	var html bytes.Buffer
+	highlightHTML(&html, file, spans)
+	want := `<pre class="gocc"><span class="type">int</span> <span class="identifier">a</span><span class="punctuation">;</span> ` +
+		`<span class="comment">/* &lt;b&gt; */</span> <span class="identifier">a</span> <span class="invalid">%</span><span class="operator">=</span> ` +
+		`<span class="number">2</span><span class="punctuation">;</span>` + "\n" +
+		`<span class="keyword">return</span> <span class="identifier">a</span><span class="operator">&amp;</span>` +
+		`<span class="number">1</span><span class="punctuation">;</span> <span class="comment">// end</span></pre>` + "\n"
+	if html.String() != want {
+		t.Errorf("HTML:\n%s\nwant:\n%s", html.String(), want)
+	}
+	var json bytes.Buffer
+	highlightJSON(&json, file, spans)
+	for _, want := range []string{
+		`{"file":"a.c","spans":[{"class":"type","kind":"INT","text":"int","line":1,"column":1,"begin":0,"end":3},`,
+		`{"class":"comment","text":"/* <b> */","line":1,"column":8,"begin":7,"end":16}`,
+		`{"class":"keyword","kind":"RETURN","text":"return","line":2,"column":1,"begin":25,"end":31}`,
+	} {
+		if !strings.Contains(json.String(), want) {
+			t.Errorf("JSON %s lacks %s", json.String(), want)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/youngfr/gocc/parser"
+	"github.com/youngfr/gocc/token"
+)
+
+// Listings
+//
+// With -flisting, the assembly of each file is also written to a
+// listing named after the file with a .lst suffix, or a.lst for the
+// standard input and -e, where each line of source comes before the
+// assembly generated for it, like with "gcc -Wa,-adhln":
+//
+//	   3: if (a) {
+//	        cmp $0, %rax
+//	        je .L.else.1
+//
+// The listing is made from the assembly generated once more with line
+// information, see codegen.Options.DebugInfo. A line of source is
+// printed where the code generated for it begins, after the lines
+// before it that were not printed yet, and once more each time the code
+// goes back to it, as for the condition of a loop. The line information
+// itself is only listed with -g. Only the x86-64 and AArch64 backends
+// emit it, so the other targets and -emit-llvm have no listings.
+
+// Return the name of the listing of the input file `path`.
+func listingName(path string) string {
+	if path == "" {
+		return "a.lst"
+	}
+	base := filepath.Base(path)
+	return strings.TrimSuffix(base, filepath.Ext(base)) + ".lst"
+}
+
+// Write the listing of `program`, read from `file`, once compiled.
+func (d *driver) writeListing(file *token.File, program *parser.Function) error {
+	cfg := config
+	cfg.DebugInfo = true
+	var src bytes.Buffer
+	backend, err := cfg.NewBackend(&src)
+	if err != nil {
+		return err
+	}
+	if err := backend.Gen(program); err != nil {
+		return err
+	}
+	var out bytes.Buffer
+	writeListing(&out, file, src.Bytes(), config.DebugInfo)
+	return os.WriteFile(listingName(file.Path), out.Bytes(), 0o644)
+}
+
+// Write to `out` the lines of `file` interleaved with the assembly `src`
+// generated for them with line information, listing the directives of
+// line information if `debugInfo` is set.
+func writeListing(out io.Writer, file *token.File, src []byte, debugInfo bool) {
+	lines := splitLines(file.Contents)
+	printed := 0 // Lines of source printed so far
+	current := 0 // Line of the code being listed
+	printLine := func(n int) {
+		fmt.Fprintf(out, "%4d: %s", n, strings.TrimRight(lines[n-1], "\n"))
+		fmt.Fprintln(out)
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(src))
+	for scanner.Scan() {
+		text := scanner.Text()
+		fields := strings.Fields(text)
+		if len(fields) >= 3 && fields[0] == ".loc" {
+			line, err := strconv.Atoi(fields[2])
+			if err == nil && line != current && line <= len(lines) {
+				current = line
+				if line <= printed {
+					printLine(line)
+				}
+				for ; printed < line; printed++ {
+					printLine(printed + 1)
+				}
+			}
+		}
+		if !debugInfo && len(fields) > 0 && (fields[0] == ".loc" || fields[0] == ".file") {
+			continue
+		}
+		fmt.Fprintf(out, "      %s\n", text)
+	}
+	for ; printed < len(lines); printed++ {
+		printLine(printed + 1)
+	}
+}
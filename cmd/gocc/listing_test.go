@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/youngfr/gocc/token"
+)
+
+func TestListing(t *testing.T) {
+	file := token.NewSession().AddFile("p.c", "p.c", "int i;\n// loop\nwhile (i < 3)\n  i = i + 1;\nreturn i;\n")
+	src := `  .file 1 "p.c"
+main:
+  .loc 1 1 5
+  mov $0, %rax
+  .loc 1 3 10
+  cmp $3, %rax
+  .loc 1 4 5
+  add $1, %rax
+  .loc 1 3 10
+  jmp main
+  .loc 1 3 10
+  ret
+`
+	want := `      main:
+   1: int i;
+        mov $0, %rax
+   2: // loop
+   3: while (i < 3)
+        cmp $3, %rax
+   4:   i = i + 1;
+        add $1, %rax
+   3: while (i < 3)
+        jmp main
+        ret
+   5: return i;
+`
+	var out bytes.Buffer
+	writeListing(&out, file, []byte(src), false)
+	if out.String() != want {
+		t.Errorf("listing is\n%s\nwant\n%s", out.String(), want)
+	}
+	out.Reset()
+	writeListing(&out, file, []byte(src), true)
+	if got := out.String(); !bytes.HasPrefix(out.Bytes(), []byte("        .file 1 \"p.c\"\n      main:\n   1: int i;\n        .loc 1 1 5\n")) {
+		t.Errorf("listing with -g is\n%s", got)
+	}
+	if listingName("dir/p.c") != "p.lst" || listingName("") != "a.lst" {
+		t.Errorf("listings are named %s and %s", listingName("dir/p.c"), listingName(""))
+	}
+}
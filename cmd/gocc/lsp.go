@@ -0,0 +1,393 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/youngfr/gocc/lexer"
+	"github.com/youngfr/gocc/parser"
+	"github.com/youngfr/gocc/token"
+)
+
+// Language server
+//
+// "gocc lsp" is a language server speaking the Language Server Protocol
+// over the standard input and output, for editors to check the programs
+// being edited. Each time a document is opened or changed, it is parsed
+// in a session of its own, configured by the -std=, -W and -w options
+// given to "gocc lsp" and by the compilation database of the document,
+// see compdb.go, and its diagnostics are published. Hovering an
+// expression shows its type and hovering a variable its declaration,
+// from the semantic information of the parser, see parser.Info. Going
+// to the definition of a variable goes to its declaration. The implicit
+// main is the only function, so there are no other definitions to go to.
+//
+// Documents are synchronized in full on each change. The protocol counts
+// columns in UTF-16 code units, which are converted to byte offsets.
+
+// Error codes of JSON-RPC.
+const (
+	lspParseError     = -32700
+	lspMethodNotFound = -32601
+)
+
+// Largest body of a message the server reads, far more than any
+// program gocc can compile in reasonable time.
+const lspMaxMessage = 64 << 20
+
+// A request, response or notification of JSON-RPC.
+type lspMessage struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspLocation struct {
+	URI   string   `json:"uri"`
+	Range lspRange `json:"range"`
+}
+
+type lspDiagnostic struct {
+	Range              lspRange                       `json:"range"`
+	Severity           int                            `json:"severity"`
+	Code               string                         `json:"code,omitempty"`
+	Source             string                         `json:"source"`
+	Message            string                         `json:"message"`
+	RelatedInformation []lspDiagnosticRelatedLocation `json:"relatedInformation,omitempty"`
+}
+
+type lspDiagnosticRelatedLocation struct {
+	Location lspLocation `json:"location"`
+	Message  string      `json:"message"`
+}
+
+// Parameters of the requests taking a position in a document.
+type lspPositionParams struct {
+	TextDocument struct {
+		URI string `json:"uri"`
+	} `json:"textDocument"`
+	Position lspPosition `json:"position"`
+}
+
+// Severities of diagnostics in the protocol.
+var lspSeverities = map[token.Severity]int{
+	token.SeverityError:   1,
+	token.SeverityWarning: 2,
+	token.SeverityNote:    3,
+}
+
+// A document opened in the editor, as last parsed.
+type lspDocument struct {
+	uri  string
+	file *token.File
+	info *parser.Info
+}
+
+type lspServer struct {
+	in       *textproto.Reader
+	out      io.Writer
+	docs     map[string]*lspDocument
+	shutdown bool // Whether the client asked to shut down
+
+	// Compilation database given with -p, if any, else those found
+	// for the directories of the documents, nil where there is none.
+	db  compileDB
+	dbs map[string]compileDB
+}
+
+// Run the language server with the command line `args`.
+func lsp(args []string) {
+	var db compileDB
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "-p":
+			if i+1 == len(args) {
+				usage()
+			}
+			var err error
+			if db, err = loadCompileDB(args[i+1]); err != nil {
+				fatal(err.Error())
+			}
+			i++
+		case !frontEndOption(args[i]):
+			usage()
+		}
+	}
+	if err := config.Apply(session); err != nil {
+		fatalStatus(token.ExitUsage, err.Error())
+	}
+	s := &lspServer{in: textproto.NewReader(bufio.NewReader(os.Stdin)), out: os.Stdout, docs: map[string]*lspDocument{}, db: db}
+	os.Exit(s.serve())
+}
+
+// Serve requests until the client exits, and return the exit status:
+// 0 if it asked to shut down first, as the protocol requires, else 1.
+func (s *lspServer) serve() int {
+	for {
+		body, err := s.read()
+		if err != nil {
+			return 1
+		}
+		msg := &lspMessage{}
+		if err := json.Unmarshal(body, msg); err != nil {
+			s.write(map[string]any{"jsonrpc": "2.0", "id": nil, "error": map[string]any{"code": lspParseError, "message": err.Error()}})
+			continue
+		}
+		if msg.Method == "exit" {
+			if s.shutdown {
+				return 0
+			}
+			return 1
+		}
+		result, rpcErr := s.handle(msg)
+		if msg.ID == nil {
+			continue
+		}
+		if rpcErr != nil {
+			s.write(map[string]any{"jsonrpc": "2.0", "id": msg.ID, "error": rpcErr})
+		} else {
+			s.write(map[string]any{"jsonrpc": "2.0", "id": msg.ID, "result": result})
+		}
+	}
+}
+
+// Read the body of the next message, after its headers.
+func (s *lspServer) read() ([]byte, error) {
+	header, err := s.in.ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	value := header.Get("Content-Length")
+	if value == "" {
+		return nil, errors.New("missing Content-Length header")
+	}
+	length, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, err
+	}
+	if length < 0 || length > lspMaxMessage {
+		return nil, fmt.Errorf("invalid Content-Length %d", length)
+	}
+	body := make([]byte, length)
+	_, err = io.ReadFull(s.in.R, body)
+	return body, err
+}
+
+// Write `msg` with its header.
+func (s *lspServer) write(msg any) {
+	body, _ := json.Marshal(msg)
+	fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+// Handle a request or a notification, and return its result or error.
+func (s *lspServer) handle(msg *lspMessage) (any, map[string]any) {
+	switch msg.Method {
+	case "initialize":
+		return map[string]any{
+			"capabilities": map[string]any{
+				"textDocumentSync":   1, // Full
+				"hoverProvider":      true,
+				"definitionProvider": true,
+			},
+			"serverInfo": map[string]any{"name": "gocc"},
+		}, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var params struct {
+			TextDocument struct {
+				URI  string `json:"uri"`
+				Text string `json:"text"`
+			} `json:"textDocument"`
+		}
+		json.Unmarshal(msg.Params, &params)
+		s.check(params.TextDocument.URI, params.TextDocument.Text)
+	case "textDocument/didChange":
+		var params struct {
+			TextDocument struct {
+				URI string `json:"uri"`
+			} `json:"textDocument"`
+			ContentChanges []struct {
+				Text string `json:"text"`
+			} `json:"contentChanges"`
+		}
+		json.Unmarshal(msg.Params, &params)
+		if n := len(params.ContentChanges); n > 0 {
+			s.check(params.TextDocument.URI, params.ContentChanges[n-1].Text)
+		}
+	case "textDocument/didClose":
+		var params lspPositionParams
+		json.Unmarshal(msg.Params, &params)
+		delete(s.docs, params.TextDocument.URI)
+		s.publish(params.TextDocument.URI, []lspDiagnostic{})
+	case "textDocument/hover":
+		doc, pos := s.position(msg.Params)
+		if doc == nil {
+			return nil, nil
+		}
+		return doc.hover(pos), nil
+	case "textDocument/definition":
+		doc, pos := s.position(msg.Params)
+		if doc == nil {
+			return nil, nil
+		}
+		if o, _ := doc.info.ObjectAt(pos); o != nil {
+			return doc.location(o.Decl().Begin, o.Decl().Length), nil
+		}
+		return nil, nil
+	default:
+		if msg.ID != nil {
+			return nil, map[string]any{"code": lspMethodNotFound, "message": "method not supported: " + msg.Method}
+		}
+	}
+	return nil, nil
+}
+
+// Parse the document `uri` holding `text`, and publish its diagnostics.
+func (s *lspServer) check(uri string, text string) {
+	name := uri
+	if u, err := url.Parse(uri); err == nil && u.Scheme == "file" {
+		name = u.Path
+	}
+	db := s.db
+	if db == nil && filepath.IsAbs(name) {
+		dir := filepath.Dir(name)
+		if _, ok := s.dbs[dir]; !ok {
+			if s.dbs == nil {
+				s.dbs = map[string]compileDB{}
+			}
+			s.dbs[dir] = findCompileDB(dir)
+		}
+		db = s.dbs[dir]
+	}
+	docSession := token.NewSession()
+	c := db.configFor(name)
+	c.Apply(docSession)
+	docSession.Language = session.Language
+	reporter := &token.MemoryReporter{}
+	docSession.Reporter = reporter
+	file := docSession.AddFile(name, name, text)
+	tok, _ := lexer.Tokenize(file)
+	// Statements with errors are left out of the program,
+	// so the rest of it can still be queried.
+	program, _ := parser.Parse(tok)
+	file.Report()
+	doc := &lspDocument{uri: uri, file: file, info: parser.NewInfo(program)}
+	s.docs[uri] = doc
+	diagnostics := []lspDiagnostic{}
+	for _, d := range reporter.Diagnostics {
+		diagnostic := lspDiagnostic{
+			Range:    doc.span(d.Begin, d.Length),
+			Severity: lspSeverities[d.Severity],
+			Code:     d.Group,
+			Source:   "gocc",
+			Message:  d.Message,
+		}
+		for _, n := range d.Notes {
+			diagnostic.RelatedInformation = append(diagnostic.RelatedInformation, lspDiagnosticRelatedLocation{
+				Location: doc.location(n.Begin, n.Length),
+				Message:  n.Message,
+			})
+		}
+		diagnostics = append(diagnostics, diagnostic)
+	}
+	s.publish(uri, diagnostics)
+}
+
+// Send the diagnostics of the document `uri` to the client.
+func (s *lspServer) publish(uri string, diagnostics []lspDiagnostic) {
+	s.write(map[string]any{
+		"jsonrpc": "2.0",
+		"method":  "textDocument/publishDiagnostics",
+		"params":  map[string]any{"uri": uri, "diagnostics": diagnostics},
+	})
+}
+
+// Return the document and the position in its file set designated by
+// the parameters `params` of a request, or nil if it is not open.
+func (s *lspServer) position(params json.RawMessage) (*lspDocument, token.Pos) {
+	var p lspPositionParams
+	json.Unmarshal(params, &p)
+	doc := s.docs[p.TextDocument.URI]
+	if doc == nil {
+		return nil, token.NoPos
+	}
+	return doc, doc.file.Pos(doc.offset(p.Position))
+}
+
+// Return the hover of the variable or the expression at `pos`, or nil.
+func (doc *lspDocument) hover(pos token.Pos) any {
+	var text string
+	var r lspRange
+	if o, tok := doc.info.ObjectAt(pos); o != nil {
+		text = o.Type.String() + " " + o.Name()
+		r = doc.span(tok.Begin, tok.Length)
+	} else if node := doc.info.ExprAt(pos); node != nil && node.Type != nil {
+		text = node.Type.String()
+		r = doc.span(doc.file.Offset(node.Pos()), int(node.End()-node.Pos()))
+	} else {
+		return nil
+	}
+	return map[string]any{
+		"contents": map[string]any{"kind": "markdown", "value": "```c\n" + text + "\n```"},
+		"range":    r,
+	}
+}
+
+// Return the location of the `length` bytes from `begin`.
+func (doc *lspDocument) location(begin int, length int) lspLocation {
+	return lspLocation{doc.uri, doc.span(begin, length)}
+}
+
+// Return the range of the `length` bytes from `begin`.
+func (doc *lspDocument) span(begin int, length int) lspRange {
+	return lspRange{doc.lspPosition(begin), doc.lspPosition(begin + length)}
+}
+
+// Return the position in the protocol of the byte at `offset`.
+func (doc *lspDocument) lspPosition(offset int) lspPosition {
+	pos := doc.file.PositionFor(offset)
+	prefix := doc.file.Contents[offset-(pos.Column-1) : offset]
+	return lspPosition{pos.Line - 1, len(utf16.Encode([]rune(prefix)))}
+}
+
+// Return the offset of the position `p` in the protocol, within the file.
+func (doc *lspDocument) offset(p lspPosition) int {
+	contents := doc.file.Contents
+	offset := 0
+	for line := 0; line < p.Line; line++ {
+		i := strings.IndexByte(contents[offset:], '\n')
+		if i < 0 {
+			return len(contents)
+		}
+		offset += i + 1
+	}
+	for units := 0; units < p.Character && offset < len(contents) && contents[offset] != '\n'; {
+		r, size := utf8.DecodeRuneInString(contents[offset:])
+		units += len(utf16.Encode([]rune{r}))
+		offset += size
+	}
+	return offset
+}
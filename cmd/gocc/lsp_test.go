@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+// Return `msgs` framed as the client sends them.
+func lspInput(msgs ...string) string {
+	var in strings.Builder
+	for _, msg := range msgs {
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(msg), msg)
+	}
+	return in.String()
+}
+
+func TestLanguageServer(t *testing.T) {
+	src := `int a = 1;\nint *p = &a;\nreturn *p + b;\n`
+	in := lspInput(
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"file:///a.c","text":"`+src+`"}}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"textDocument/hover","params":{"textDocument":{"uri":"file:///a.c"},"position":{"line":2,"character":8}}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"textDocument/hover","params":{"textDocument":{"uri":"file:///a.c"},"position":{"line":1,"character":10}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"textDocument/definition","params":{"textDocument":{"uri":"file:///a.c"},"position":{"line":1,"character":10}}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"textDocument/formatting","params":{}}`,
+		`{"jsonrpc":"2.0","id":7,"method":"textDocument/hover","params":{"textDocument":{"uri":"file:///a.c"},"position":{"line":1,"character":9}}}`,
+		`{"jsonrpc":"2.0","id":6,"method":"shutdown"}`,
+		`{"jsonrpc":"2.0","method":"exit"}`,
+	)
+	var out bytes.Buffer
+	s := &lspServer{in: textproto.NewReader(bufio.NewReader(strings.NewReader(in))), out: &out, docs: map[string]*lspDocument{}}
+	if status := s.serve(); status != 0 {
+		t.Errorf("exit status %d after shutdown, want 0", status)
+	}
+
+	var msgs []string
+	r := &lspServer{in: textproto.NewReader(bufio.NewReader(&out))}
+	for {
+		body, err := r.read()
+		if err != nil {
+			break
+		}
+		var compact bytes.Buffer
+		json.Compact(&compact, body)
+		msgs = append(msgs, compact.String())
+	}
+	want := []string{
+		`"id":1,"jsonrpc":"2.0","result":{"capabilities":{"definitionProvider":true,"hoverProvider":true,"textDocumentSync":1}`,
+		`"method":"textDocument/publishDiagnostics","params":{"diagnostics":[{"range":{"start":{"line":2,"character":12},"end":{"line":2,"character":13}},"severity":1,"source":"gocc","message":"undefined variable"}],"uri":"file:///a.c"}`,
+		// The statement with the error is left out.
+		`"id":2,"jsonrpc":"2.0","result":null`,
+		`"id":3,"jsonrpc":"2.0","result":{"contents":{"kind":"markdown","value":"` + "```c\\nint a\\n```" + `"},"range":{"start":{"line":1,"character":10},"end":{"line":1,"character":11}}}`,
+		`"id":4,"jsonrpc":"2.0","result":{"uri":"file:///a.c","range":{"start":{"line":0,"character":4},"end":{"line":0,"character":5}}}`,
+		`"error":{"code":-32601,"message":"method not supported: textDocument/formatting"},"id":5`,
+		`"id":7,"jsonrpc":"2.0","result":{"contents":{"kind":"markdown","value":"` + "```c\\nint*\\n```" + `"},"range":{"start":{"line":1,"character":9},"end":{"line":1,"character":11}}}`,
+		`"id":6,"jsonrpc":"2.0","result":null`,
+	}
+	if len(msgs) != len(want) {
+		t.Fatalf("%d messages from the server, want %d:\n%s", len(msgs), len(want), strings.Join(msgs, "\n"))
+	}
+	for i, msg := range msgs {
+		if !strings.Contains(msg, want[i]) {
+			t.Errorf("message %d is\n%s\nwant it to contain\n%s", i, msg, want[i])
+		}
+	}
+}
+
+func TestLanguageServerPositions(t *testing.T) {
+	s := &lspServer{out: &bytes.Buffer{}, docs: map[string]*lspDocument{}}
+	s.check("file:///b.c", "int é = 1;\nreturn é;\n")
+	doc := s.docs["file:///b.c"]
+	offset := strings.LastIndex(doc.file.Contents, "é;")
+	p := doc.lspPosition(offset + len("é"))
+	if p != (lspPosition{1, 8}) {
+		t.Errorf("position %+v, want line 1 and character 8", p)
+	}
+	if got := doc.offset(p); got != offset+len("é") {
+		t.Errorf("offset %d, want %d", got, offset+len("é"))
+	}
+}
+
+// A message whose length is missing, negative or too large is rejected
+// before its body is read.
+func TestLanguageServerLength(t *testing.T) {
+	for _, header := range []string{
+		"Content-Type: application/json\r\n",
+		"Content-Length: -1\r\n",
+		"Content-Length: x\r\n",
+		fmt.Sprintf("Content-Length: %d\r\n", lspMaxMessage+1),
+		"Content-Length: 99999999999999999999\r\n",
+	} {
+		s := &lspServer{in: textproto.NewReader(bufio.NewReader(strings.NewReader(header + "\r\n{}")))}
+		if body, err := s.read(); err == nil {
+			t.Errorf("read %q after %q, want an error", body, header)
+		}
+	}
+	s := &lspServer{in: textproto.NewReader(bufio.NewReader(strings.NewReader(lspInput("{}"))))}
+	if body, err := s.read(); err != nil || string(body) != "{}" {
+		t.Errorf("read %q, %v, want \"{}\"", body, err)
+	}
+}
@@ -0,0 +1,544 @@
+// Gocc compiles C programs. Build it with
+//
+//	go build -o gocc ./cmd/gocc
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/youngfr/gocc"
+	"github.com/youngfr/gocc/codegen"
+	"github.com/youngfr/gocc/parser"
+	"github.com/youngfr/gocc/token"
+)
+
+// Session of the files given on the command line, holding
+// the options of the lexer, the parser and the diagnostics.
+var session = token.NewSession()
+
+// Configuration of the compilation, given on the command line.
+// The session is configured with it once all options are read.
+var config gocc.Config
+
+// Whether -ftime-report was given, see stats.go.
+var timeReport bool
+
+// Options of gcc that build systems commonly pass and that are ignored,
+// either because gocc always behaves that way or because what they
+// configure does not exist in gocc.
+var ignoredOptions = map[string]bool{
+	"-pipe": true, "-pedantic": true, "-pedantic-errors": true,
+	"-fcommon": true, "-fno-common": true, "-fno-strict-aliasing": true,
+	"-ffunction-sections": true, "-fdata-sections": true,
+	"-MP": true, // No headers need phony targets
+}
+
+// Options taking a preprocessor argument, attached or as the next
+// argument. There is no preprocessor, so they are only kept in the
+// configuration, and passed on to the one given with --use-external-cpp.
+var preprocessorOptions = []string{"-I", "-D", "-U"}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, session.Colored(token.SeverityColors[token.SeverityError], "usage: gocc [-S | -c | -E | -run] [-o <file>] [-MMD [-MF <file>] [-MT <target>]] [-fintegrated-as] [-target <triple>] [-emit-llvm | -emit-qbe] [-O<level>] [-g] [-fPIC] [-fstack-protector[-all]] [-fomit-frame-pointer] [-mno-red-zone] [-fsanitize=undefined-lite] [-fverbose-asm] [-flisting] [-ftime-report] [-W<warning>] [-w] [-std=<standard>] [-f[un]signed-char] [-I<dir>] [-D<macro>[=<value>]] [-U<macro>] [--use-external-cpp[=<path>]] [--color=<when>] [-fdiagnostics-format=<format>] [--lang=<language>] [--verify] [--trace-parse] [--dump-tokens] [--dump-ast[=json]] [file.c... | - | -e program] [file.o...] [-l<library>] [@<file>]"))
+	fmt.Fprintln(os.Stderr, session.Colored(token.SeverityColors[token.SeverityError], "       gocc difftest [-cc <compiler> | -interp] [-v] [<gocc option>...] <file.c | directory>..."))
+	fmt.Fprintln(os.Stderr, session.Colored(token.SeverityColors[token.SeverityError], "       gocc run [<gocc option>...] <file.c | - | -e program> [args...]"))
+	fmt.Fprintln(os.Stderr, session.Colored(token.SeverityColors[token.SeverityError], "       gocc serve [-addr <host:port>] [-no-page]"))
+	fmt.Fprintln(os.Stderr, session.Colored(token.SeverityColors[token.SeverityError], "       gocc gen [-o <file.go>] [-target <triple>] [<gocc option>...] file.go..."))
+	fmt.Fprintln(os.Stderr, session.Colored(token.SeverityColors[token.SeverityError], "       gocc fmt [-d] [-w] [file.c... | -]"))
+	fmt.Fprintln(os.Stderr, session.Colored(token.SeverityColors[token.SeverityError], "       gocc astdiff [-std=<standard>] <old.c> <new.c>"))
+	fmt.Fprintln(os.Stderr, session.Colored(token.SeverityColors[token.SeverityError], "       gocc highlight [-json] [-p <build dir>] [-std=<standard>] [file.c... | -]"))
+	fmt.Fprintln(os.Stderr, session.Colored(token.SeverityColors[token.SeverityError], "       gocc tags [-e] [-o <file>] [-p <build dir>] [-std=<standard>] file.c... | -"))
+	fmt.Fprintln(os.Stderr, session.Colored(token.SeverityColors[token.SeverityError], "       gocc lsp [-p <build dir>] [-std=<standard>] [-W<warning>] [-w]"))
+	fmt.Fprintln(os.Stderr, session.Colored(token.SeverityColors[token.SeverityError], "       gocc repl [-std=<standard>] [-W<warning>] [-w]"))
+	os.Exit(token.ExitUsage)
+}
+
+// Optimization levels of the -O options other than -O<number>, mapped
+// to the closest level gocc has, like with gcc.
+var optLevels = map[string]int{"": 1, "s": 1, "z": 1, "g": 1, "fast": 2}
+
+// Return the optimization level of the -O option `arg`, and whether it
+// names one.
+func optimizationLevel(arg string) (int, bool) {
+	if level, ok := optLevels[arg[len("-O"):]]; ok {
+		return level, true
+	}
+	level, err := strconv.Atoi(arg[len("-O"):])
+	return level, err == nil && level >= 0
+}
+
+// Apply `arg` to the configuration if it is an option of the front end
+// that the subcommands checking programs accept: -std=, -W and -w.
+// Return whether it is one.
+func frontEndOption(arg string) bool {
+	switch {
+	case strings.HasPrefix(arg, "-std="):
+		config.Standard = strings.TrimPrefix(arg, "-std=")
+	case arg == "-w":
+		config.SuppressWarnings = true
+	case strings.HasPrefix(arg, "-W"):
+		config.Warnings = append(config.Warnings, strings.TrimPrefix(arg, "-W"))
+	default:
+		return false
+	}
+	return true
+}
+
+// Report an error with no location and exit.
+func fatal(message string) {
+	fatalStatus(token.ExitFailure, message)
+}
+
+// Report an error with no location and exit with `status`.
+func fatalStatus(status int, message string) {
+	fmt.Fprintf(os.Stderr, "gocc: %s %s\n", session.Colored(token.SeverityColors[token.SeverityError], session.Translate("error")+":"), message)
+	os.Exit(status)
+}
+
+// Report a warning with no location.
+func warn(message string) {
+	fmt.Fprintf(os.Stderr, "gocc: %s %s\n", session.Colored(token.SeverityColors[token.SeverityWarning], session.Translate("warning")+":"), message)
+}
+
+// Report `err`, returned by a phase of the compilation, and exit with
+// the status of its class. Broken invariants of the AST are internal
+// errors, and diagnostics are reported to the reporter of the session.
+func exitWith(err error) {
+	var verr *parser.VerifyError
+	var errs token.ErrorList
+	switch {
+	case errors.As(err, &verr):
+		fmt.Fprintf(os.Stderr, "gocc: %s %v\n", session.Colored(token.SeverityColors[token.SeverityError], "internal error:"), err)
+		os.Exit(token.ExitInternal)
+	case errors.As(err, &errs):
+		for _, d := range errs {
+			d.File.Report()
+		}
+		os.Exit(errs.ExitStatus())
+	}
+	fatal(err.Error())
+}
+
+func main() {
+	defer handleCrash()
+	os.Args = append(os.Args[:1], expandResponseFiles(os.Args[1:], nil)...)
+	if len(os.Args) > 1 && os.Args[1] == "difftest" {
+		difftest(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "run" {
+		run(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		serve(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "gen" {
+		gen(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "astdiff" {
+		astdiff(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "fmt" {
+		format(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "highlight" {
+		highlight(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "tags" {
+		tags(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "lsp" {
+		lsp(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "repl" {
+		repl(os.Args[2:])
+		return
+	}
+	mode := modeExec
+	integrated := false
+	output := ""
+	dumpTokens := false
+	dumpAST := ""
+	deps := false
+	systemDeps := false
+	depOutput := ""
+	depTarget := ""
+	cpp := ""
+	listing := false
+	var inputs []input
+	var linkInputs []string
+	for i := 1; i < len(os.Args); i++ {
+		if os.Args[i] == "-S" {
+			mode = modeAsm
+			continue
+		}
+		if os.Args[i] == "-c" {
+			mode = modeObject
+			continue
+		}
+		if os.Args[i] == "-E" {
+			mode = modePreprocess
+			continue
+		}
+		if os.Args[i] == "-run" {
+			mode = modeRun
+			continue
+		}
+		if os.Args[i] == "-e" || os.Args[i] == "--expr" {
+			if i+1 == len(os.Args) {
+				usage()
+			}
+			inputs = append(inputs, input{program: os.Args[i+1]})
+			i++
+			continue
+		}
+		if os.Args[i] == "-ftime-report" {
+			timeReport = true
+			continue
+		}
+		if os.Args[i] == "--trace-parse" {
+			config.Trace = os.Stderr
+			continue
+		}
+		if os.Args[i] == "--verify" {
+			session.VerifyAST = true
+			continue
+		}
+		if os.Args[i] == "--use-external-cpp" {
+			cpp = "cpp"
+			continue
+		}
+		if strings.HasPrefix(os.Args[i], "--use-external-cpp=") {
+			cpp = strings.TrimPrefix(os.Args[i], "--use-external-cpp=")
+			continue
+		}
+		if os.Args[i] == "--dump-tokens" {
+			dumpTokens = true
+			continue
+		}
+		if os.Args[i] == "--dump-ast" || os.Args[i] == "--dump-ast=text" {
+			dumpAST = "text"
+			continue
+		}
+		if os.Args[i] == "--dump-ast=json" {
+			dumpAST = "json"
+			continue
+		}
+		if os.Args[i] == "-MMD" || os.Args[i] == "-MD" {
+			deps = true
+			systemDeps = os.Args[i] == "-MD"
+			continue
+		}
+		if os.Args[i] == "-MF" || os.Args[i] == "-MT" {
+			if i+1 == len(os.Args) {
+				usage()
+			}
+			if os.Args[i] == "-MF" {
+				depOutput = os.Args[i+1]
+			} else {
+				depTarget = os.Args[i+1]
+			}
+			i++
+			continue
+		}
+		if os.Args[i] == "-o" {
+			if i+1 == len(os.Args) {
+				usage()
+			}
+			output = os.Args[i+1]
+			i++
+			continue
+		}
+		if os.Args[i] == "-fintegrated-as" {
+			integrated = true
+			continue
+		}
+		if os.Args[i] == "-fno-integrated-as" {
+			integrated = false
+			continue
+		}
+		if os.Args[i] == "-g" {
+			config.DebugInfo = true
+			continue
+		}
+		if os.Args[i] == "-fPIC" || os.Args[i] == "-fpic" || os.Args[i] == "-fPIE" || os.Args[i] == "-fpie" {
+			config.PIC = true
+			continue
+		}
+		if os.Args[i] == "-fno-PIC" || os.Args[i] == "-fno-pic" || os.Args[i] == "-fno-PIE" || os.Args[i] == "-fno-pie" {
+			config.PIC = false
+			continue
+		}
+		if os.Args[i] == "-fstack-protector" {
+			config.StackProtector = codegen.ProtectArrays
+			continue
+		}
+		if os.Args[i] == "-fstack-protector-all" {
+			config.StackProtector = codegen.ProtectAll
+			continue
+		}
+		if os.Args[i] == "-fno-stack-protector" {
+			config.StackProtector = codegen.ProtectNone
+			continue
+		}
+		if os.Args[i] == "-fomit-frame-pointer" {
+			config.OmitFramePointer = true
+			continue
+		}
+		if os.Args[i] == "-fno-omit-frame-pointer" {
+			config.OmitFramePointer = false
+			continue
+		}
+		if os.Args[i] == "-mred-zone" {
+			config.NoRedZone = false
+			continue
+		}
+		if os.Args[i] == "-mno-red-zone" {
+			config.NoRedZone = true
+			continue
+		}
+		if os.Args[i] == "-fsanitize=undefined-lite" {
+			config.SanitizeUndefined = true
+			continue
+		}
+		if os.Args[i] == "-fverbose-asm" {
+			config.VerboseAsm = true
+			continue
+		}
+		if os.Args[i] == "-flisting" {
+			listing = true
+			continue
+		}
+		if strings.HasPrefix(os.Args[i], "--lang=") {
+			lang := strings.TrimPrefix(os.Args[i], "--lang=")
+			if lang != "en" && token.LanguageFor(lang) != lang {
+				fatalStatus(token.ExitUsage, session.Tr("unknown language \"%s\"", lang))
+			}
+			session.Language = token.LanguageFor(lang)
+			continue
+		}
+		if strings.HasPrefix(os.Args[i], "--color=") {
+			when := strings.TrimPrefix(os.Args[i], "--color=")
+			if when != "auto" && when != "always" && when != "never" {
+				usage()
+			}
+			session.Color = token.ColorFor(when)
+			continue
+		}
+		if strings.HasPrefix(os.Args[i], "-fdiagnostics-format=") {
+			switch strings.TrimPrefix(os.Args[i], "-fdiagnostics-format=") {
+			case "text":
+				session.Reporter = &token.ConsoleReporter{Out: os.Stderr}
+			case "json":
+				session.Reporter = &token.JSONReporter{Out: os.Stderr}
+			default:
+				usage()
+			}
+			continue
+		}
+		if strings.HasPrefix(os.Args[i], "-Wl,") || strings.HasPrefix(os.Args[i], "-l") || strings.HasPrefix(os.Args[i], "-L") {
+			linkInputs = append(linkInputs, os.Args[i])
+			continue
+		}
+		if os.Args[i] == "-w" {
+			config.SuppressWarnings = true
+			continue
+		}
+		if strings.HasPrefix(os.Args[i], "-W") {
+			// Build systems pass warning options meant for other compilers,
+			// so unknown ones are only warned about, and -Wno- ones ignored.
+			switch {
+			case token.IsWarningOption(os.Args[i][2:]):
+				config.Warnings = append(config.Warnings, os.Args[i][2:])
+			case !strings.HasPrefix(os.Args[i], "-Wno-"):
+				warn(session.Tr("unknown warning option \"%s\"", os.Args[i]))
+			}
+			continue
+		}
+		if strings.HasPrefix(os.Args[i], "-std=") {
+			config.Standard = os.Args[i][len("-std="):]
+			if _, ok := token.Standards[config.Standard]; !ok {
+				fatalStatus(token.ExitUsage, session.Tr("unrecognized command-line option \"%s\"", os.Args[i]))
+			}
+			continue
+		}
+		if os.Args[i] == "-ansi" {
+			config.Standard = "c90"
+			continue
+		}
+		if os.Args[i] == "-fsigned-char" || os.Args[i] == "-fno-unsigned-char" {
+			config.UnsignedChar = false
+			continue
+		}
+		if os.Args[i] == "-funsigned-char" || os.Args[i] == "-fno-signed-char" {
+			config.UnsignedChar = true
+			continue
+		}
+		if ignoredOptions[os.Args[i]] {
+			continue
+		}
+		if option, ok := preprocessorOption(os.Args[i]); ok {
+			value := os.Args[i][len(option):]
+			if option == os.Args[i] {
+				if i+1 == len(os.Args) {
+					usage()
+				}
+				value = os.Args[i+1]
+				i++
+			}
+			if option == "-I" {
+				config.IncludePaths = append(config.IncludePaths, value)
+			} else {
+				config.Macros = append(config.Macros, option+value)
+			}
+			continue
+		}
+		if os.Args[i] == "-emit-llvm" {
+			config.EmitLLVM = true
+			continue
+		}
+		if os.Args[i] == "-emit-qbe" {
+			config.EmitQBE = true
+			continue
+		}
+		if strings.HasPrefix(os.Args[i], "-O") {
+			level, ok := optimizationLevel(os.Args[i])
+			if !ok {
+				fatalStatus(token.ExitUsage, session.Tr("unsupported optimization level \"%s\"", os.Args[i]))
+			}
+			config.OptLevel = level
+			continue
+		}
+		if os.Args[i] == "-target" {
+			if i+1 == len(os.Args) {
+				usage()
+			}
+			config.Target = os.Args[i+1]
+			i++
+			continue
+		}
+		if strings.HasPrefix(os.Args[i], "-") && os.Args[i] != "-" {
+			fatalStatus(token.ExitUsage, session.Tr("unrecognized command-line option \"%s\"", os.Args[i]))
+		}
+		switch filepath.Ext(os.Args[i]) {
+		case ".o", ".a", ".so":
+			linkInputs = append(linkInputs, os.Args[i])
+			continue
+		}
+		inputs = append(inputs, input{path: os.Args[i]})
+	}
+	if len(inputs) == 0 && len(linkInputs) == 0 {
+		// Read the program from the standard input, like with "-".
+		inputs = append(inputs, input{path: "-"})
+	}
+	if _, ok := codegen.Targets[config.TargetTriple()]; !ok {
+		fatalStatus(token.ExitUsage, session.Tr("unknown target \"%s\"", config.Target))
+	}
+	if err := config.Apply(session); err != nil {
+		fatalStatus(token.ExitUsage, err.Error())
+	}
+	d := &driver{mode: mode, integrated: integrated, output: output, dumpTokens: dumpTokens, dumpAST: dumpAST, deps: deps, systemDeps: systemDeps, depOutput: depOutput, depTarget: depTarget, cpp: cpp, listing: listing, linkInputs: linkInputs}
+	d.run(inputs)
+	if timeReport {
+		printTimeReport(os.Stderr)
+	}
+}
+
+// Return the preprocessor option `arg` starts with, if any.
+func preprocessorOption(arg string) (string, bool) {
+	for _, option := range preprocessorOptions {
+		if strings.HasPrefix(arg, option) {
+			return option, true
+		}
+	}
+	return "", false
+}
+
+// Replace each @file argument of `args` with the arguments in the file,
+// like gcc does, as build systems pass long command lines this way. The
+// arguments are separated by whitespace, and may be quoted with single
+// or double quotes or escaped with a backslash. Response files may name
+// other ones, but not those in `open`, which are being expanded. Like
+// with gcc, @file is kept as is when the file cannot be read.
+func expandResponseFiles(args []string, open []string) []string {
+	var expanded []string
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "@") {
+			expanded = append(expanded, arg)
+			continue
+		}
+		path := arg[1:]
+		for _, name := range open {
+			if name == path {
+				fatalStatus(token.ExitUsage, session.Tr("response file \"%s\" includes itself", path))
+			}
+		}
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			expanded = append(expanded, arg)
+			continue
+		}
+		words, err := splitResponseFile(string(contents))
+		if err != nil {
+			fatalStatus(token.ExitUsage, fmt.Sprintf("%s: %v", path, err))
+		}
+		expanded = append(expanded, expandResponseFiles(words, append(open, path))...)
+	}
+	return expanded
+}
+
+// Split the contents of a response file into arguments.
+func splitResponseFile(contents string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	quote := byte(0) // Quote the current word is in, if any
+	for i := 0; i < len(contents); i++ {
+		c := contents[i]
+		switch {
+		case quote == '\'' && c != '\'':
+			word.WriteByte(c)
+		case c == '\\' && quote != '\'':
+			if i+1 == len(contents) {
+				return nil, errors.New(session.Translate("backslash at the end of the file"))
+			}
+			i++
+			word.WriteByte(contents[i])
+			inWord = true
+		case c == quote:
+			quote = 0
+		case quote == 0 && (c == '\'' || c == '"'):
+			quote = c
+			inWord = true
+		case quote == 0 && (c == ' ' || c == '\t' || c == '\n' || c == '\r'):
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, errors.New(session.Tr("missing terminating %c", quote))
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/youngfr/gocc"
+	"github.com/youngfr/gocc/token"
+)
+
+// Read-eval-print loop
+//
+// "gocc repl" reads entries from the standard input and evaluates them
+// one after the other, like if each was appended to a program:
+//
+//   - An expression is compiled as the value returned by the program,
+//     after the statements entered so far. The program is assembled,
+//     linked and run, and its exit status is printed. Only the low 8
+//     bits of the value survive as an exit status, so the value printed
+//     is modulo 256. "return expr;" is evaluated the same way.
+//   - Anything else is compiled as statements, kept for the entries
+//     that follow if it has no error. Declarations are kept this way.
+//
+// An expression is kept too, as an expression statement, so that
+// assignments stay in effect. Since each program runs the statements
+// kept from the start, so do their side effects, which C programs
+// without library calls cannot show anyway.
+//
+// An entry continues on the next line as long as it has unclosed
+// braces. ":list" prints the statements kept so far, ":reset" forgets
+// them and ":quit" exits, like the end of the input.
+
+type replSession struct {
+	out    io.Writer // Values of the expressions
+	errOut io.Writer // Diagnostics and failures
+	dir    string    // Directory of the executables
+	stmts  []string  // Statements kept so far
+}
+
+// Run the loop with the command line `args`.
+func repl(args []string) {
+	for _, arg := range args {
+		if !frontEndOption(arg) {
+			usage()
+		}
+	}
+	if err := config.Apply(session); err != nil {
+		fatalStatus(token.ExitUsage, err.Error())
+	}
+	dir, err := os.MkdirTemp("", "gocc-repl-*")
+	if err != nil {
+		fatal(err.Error())
+	}
+	defer os.RemoveAll(dir)
+	r := &replSession{out: os.Stdout, errOut: os.Stderr, dir: dir}
+	prompt := "> "
+	if info, err := os.Stdin.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		prompt = ""
+	}
+	in := bufio.NewScanner(os.Stdin)
+	var entry strings.Builder
+	fmt.Print(prompt)
+	for in.Scan() {
+		entry.WriteString(in.Text() + "\n")
+		if s := entry.String(); strings.Count(s, "{") > strings.Count(s, "}") {
+			if prompt != "" {
+				fmt.Print(". ")
+			}
+			continue
+		}
+		if strings.TrimSpace(entry.String()) == ":quit" {
+			return
+		}
+		r.eval(entry.String())
+		entry.Reset()
+		fmt.Print(prompt)
+	}
+}
+
+// Evaluate the entry `line`.
+func (r *replSession) eval(line string) {
+	line = strings.TrimSpace(line)
+	switch line {
+	case "":
+		return
+	case ":list":
+		for _, stmt := range r.stmts {
+			fmt.Fprintln(r.out, stmt)
+		}
+		return
+	case ":reset":
+		r.stmts = nil
+		return
+	}
+	prefix := ""
+	for _, stmt := range r.stmts {
+		prefix += stmt + "\n"
+	}
+	expr := line
+	if strings.HasPrefix(expr, "return") && strings.HasSuffix(expr, ";") {
+		expr = strings.TrimSpace(expr[len("return") : len(expr)-1])
+	}
+	asm, exprDiags, err := gocc.Compile(prefix+"return "+expr+";\n", config)
+	if err == nil {
+		r.report(exprDiags, len(prefix))
+		if r.run(asm) {
+			r.stmts = append(r.stmts, expr+";")
+		}
+		return
+	}
+	_, stmtDiags, err := gocc.Compile(prefix+line+"\n", config)
+	if err == nil {
+		r.report(stmtDiags, len(prefix))
+		r.stmts = append(r.stmts, line)
+		return
+	}
+	// Report the errors of what the entry looks like.
+	if strings.HasSuffix(line, ";") || strings.HasSuffix(line, "}") {
+		r.report(stmtDiags, len(prefix))
+	} else {
+		r.report(exprDiags, len(prefix))
+	}
+}
+
+// Print the diagnostics of `diags` within the entry, which
+// starts at `begin`. Those of the statements kept were
+// reported when they were entered.
+func (r *replSession) report(diags []*token.Diagnostic, begin int) {
+	for _, d := range diags {
+		if d.Begin >= begin {
+			d.Print(r.errOut)
+		}
+	}
+}
+
+// Assemble, link and run the program `asm`, and print its exit
+// status. Return whether it ran to completion.
+func (r *replSession) run(asm []byte) bool {
+	object := filepath.Join(r.dir, "repl.o")
+	executable := filepath.Join(r.dir, "repl")
+	if err := assemble(asm, config.TargetTriple(), false, object); err != nil {
+		fmt.Fprintf(r.errOut, "gocc: %s\n", err)
+		return false
+	}
+	if err := link([]string{object}, executable); err != nil {
+		fmt.Fprintf(r.errOut, "gocc: %s\n", err)
+		return false
+	}
+	result, err := difftestExec(executable)
+	switch {
+	case err != nil:
+		fmt.Fprintf(r.errOut, "gocc: %s\n", err)
+	case result.hung:
+		fmt.Fprintf(r.errOut, "gocc: %s\n", session.Tr("the program timed out"))
+	case result.status < 0:
+		fmt.Fprintf(r.errOut, "gocc: %s\n", session.Tr("the program was killed by a signal"))
+	default:
+		fmt.Fprint(r.out, result.stdout)
+		fmt.Fprintln(r.out, result.status)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestREPL(t *testing.T) {
+	requireToolchain(t)
+	var out, errOut bytes.Buffer
+	r := &replSession{out: &out, errOut: &errOut, dir: t.TempDir()}
+	for _, c := range []struct {
+		entry  string
+		output string
+		err    string // Part of the diagnostics
+	}{
+		{"6 * 7", "42\n", ""},
+		{"int a = 3;", "", ""},
+		{"a * 2", "6\n", ""},
+		{"a = 50", "50\n", ""},
+		{"for (int i = 0; i < 5; i = i + 1) {\n\ta = a + i;\n}", "", ""},
+		{"return a + 1;", "61\n", ""},
+		{"b + 1", "", "undefined variable"},
+		{"int c = ;", "", "expected an expression"},
+		{"-1", "255\n", ""},
+		{":list", "6 * 7;\nint a = 3;\na * 2;\na = 50;\nfor (int i = 0; i < 5; i = i + 1) {\n\ta = a + i;\n}\na + 1;\n-1;\n", ""},
+		{":reset", "", ""},
+		{"a", "", "undefined variable"},
+	} {
+		out.Reset()
+		errOut.Reset()
+		r.eval(c.entry)
+		if out.String() != c.output {
+			t.Errorf("%q printed %q, want %q", c.entry, out.String(), c.output)
+		}
+		if c.err == "" && errOut.Len() != 0 || !strings.Contains(errOut.String(), c.err) {
+			t.Errorf("%q reported %q, want %q", c.entry, errOut.String(), c.err)
+		}
+	}
+}
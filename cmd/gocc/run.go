@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+)
+
+// Run
+//
+// "gocc run" compiles a program to a temporary executable, runs it and
+// exits with its exit status, like "go run", for quick experiments:
+//
+//	gocc run [<gocc option>...] prog.c [args...]
+//	gocc run [<gocc option>...] -e program [args...]
+//
+// The options before the program are passed to gocc to compile it, and
+// the arguments after it to the executable, although programs cannot
+// read them yet. The executable is removed once it exits. If a signal
+// killed it, the status is the one a shell reports, 128 plus the signal.
+
+// Options of gocc whose value is the next argument.
+var runValueOptions = map[string]bool{
+	"-target": true, "-I": true, "-D": true, "-U": true,
+	"-MF": true, "-MT": true,
+}
+
+// Split the command line `args` of "gocc run" into the options to compile
+// with, the program to compile, as the arguments of gocc naming it, and
+// the arguments of the executable. The program is nil if there is none.
+func runArgs(args []string) (options []string, program []string, programArgs []string) {
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "-e" && i+1 < len(args):
+			return options, args[i : i+2], args[i+2:]
+		case args[i] == "-e" || args[i] == "-o":
+			return options, nil, nil
+		case runValueOptions[args[i]] && i+1 < len(args):
+			options = append(options, args[i], args[i+1])
+			i++
+		case args[i] == "-":
+			return options, args[i : i+1], args[i+1:]
+		case len(args[i]) > 1 && args[i][0] == '-':
+			options = append(options, args[i])
+		default:
+			return options, args[i : i+1], args[i+1:]
+		}
+	}
+	return options, nil, nil
+}
+
+// Compile and run the program of the command line `args`, and exit with
+// its exit status.
+func run(args []string) {
+	options, program, programArgs := runArgs(args)
+	if program == nil {
+		usage()
+	}
+	self, err := os.Executable()
+	if err != nil {
+		fatal(err.Error())
+	}
+	dir, err := os.MkdirTemp("", "gocc-run-*")
+	if err != nil {
+		fatal(err.Error())
+	}
+	executable := filepath.Join(dir, "a.out")
+	build := exec.Command(self, append(append(options, "-o", executable), program...)...)
+	build.Stdin, build.Stdout, build.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := build.Run(); err != nil {
+		os.RemoveAll(dir)
+		var exit *exec.ExitError
+		if errors.As(err, &exit) {
+			os.Exit(exit.ExitCode())
+		}
+		fatal(err.Error())
+	}
+	// The executable gets the interrupts of the terminal too, and exits
+	// on its own, after which it is removed.
+	signal.Notify(make(chan os.Signal, 1), os.Interrupt, syscall.SIGTERM)
+	cmd := exec.Command(executable, programArgs...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	err = cmd.Run()
+	os.RemoveAll(dir)
+	var exit *exec.ExitError
+	if err != nil && !errors.As(err, &exit) {
+		fatal(err.Error())
+	}
+	if status, ok := cmd.ProcessState.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		os.Exit(128 + int(status.Signal()))
+	}
+	os.Exit(cmd.ProcessState.ExitCode())
+}
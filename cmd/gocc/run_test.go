@@ -0,0 +1,26 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRunArgs(t *testing.T) {
+	for _, c := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"prog.c"}, "[] [prog.c] []"},
+		{[]string{"-O1", "-target", "x86_64-linux", "-DX=1", "prog.c", "-v", "a.c"}, "[-O1 -target x86_64-linux -DX=1] [prog.c] [-v a.c]"},
+		{[]string{"-I", "inc", "-", "x"}, "[-I inc] [-] [x]"},
+		{[]string{"-g", "-e", "return 3;", "-e"}, "[-g] [-e return 3;] [-e]"},
+		{[]string{"-g"}, "[-g] [] []"},
+		{[]string{"-e"}, "[] [] []"},
+		{[]string{"-o", "a", "prog.c"}, "[] [] []"},
+	} {
+		options, program, programArgs := runArgs(c.args)
+		if got := fmt.Sprint(options, program, programArgs); got != c.want {
+			t.Errorf("runArgs(%q) = %s, want %s", c.args, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/youngfr/gocc"
+	"github.com/youngfr/gocc/codegen"
+	"github.com/youngfr/gocc/lexer"
+	"github.com/youngfr/gocc/parser"
+	"github.com/youngfr/gocc/token"
+)
+
+// Playground
+//
+// "gocc serve" is an HTTP server compiling the programs it is sent, for
+// demonstrations in a browser without installing anything. It listens
+// on localhost:8080, or the address given with -addr, and serves a page
+// to edit and compile programs at /, unless -no-page is given, on top
+// of a JSON API at /compile. The API takes a POST of
+//
+//	{"source": "return 1+2;", "target": "x86_64-linux", "options": ["-O1", "-g"]}
+//
+// where the target and the options are optional, and answers with the
+// AST as --dump-ast prints it, the assembly, or the LLVM IR with
+// -emit-llvm and the QBE IL with -emit-qbe, and the diagnostics, both
+// as gcc prints them and as -fdiagnostics-format=json writes them:
+//
+//	{"ast": "...", "asm": "...", "text": "...", "diagnostics": [...]}
+//
+// A program with errors has no assembly, and no AST if it cannot be
+// parsed. A request that is not valid JSON, or has an unknown target or
+// option, gets the status 400 and {"error": "..."}. Each program is
+// compiled in a session of its own, with the language of the messages
+// of the command line. The options are those of the code generator and
+// of the front end, -std=, -W and -w.
+
+// Largest program accepted, in bytes.
+const serveMaxSource = 1 << 20
+
+type serveRequest struct {
+	Source  string   `json:"source"`
+	Target  string   `json:"target"`
+	Options []string `json:"options"`
+}
+
+type serveResponse struct {
+	AST         string            `json:"ast"`
+	Asm         string            `json:"asm"`
+	Text        string            `json:"text"`
+	Diagnostics []json.RawMessage `json:"diagnostics"`
+}
+
+// Run the playground server with the command line `args`.
+func serve(args []string) {
+	addr := "localhost:8080"
+	page := true
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "-addr" && i+1 < len(args):
+			addr = args[i+1]
+			i++
+		case args[i] == "-no-page":
+			page = false
+		default:
+			usage()
+		}
+	}
+	fmt.Fprintf(os.Stderr, "gocc: %s\n", session.Tr("serving on http://%s", addr))
+	if err := http.ListenAndServe(addr, newPlayground(page)); err != nil {
+		fatal(err.Error())
+	}
+}
+
+// Return the handler of the playground, serving its page at / with `page`.
+func newPlayground(page bool) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/compile", serveCompile)
+	if page {
+		mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/" {
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, playgroundPage)
+		})
+	}
+	return mux
+}
+
+// Compile the program of the request, and answer with what became of it.
+func serveCompile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		serveError(w, http.StatusMethodNotAllowed, session.Tr("method %s not allowed", r.Method))
+		return
+	}
+	var req serveRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, serveMaxSource)).Decode(&req); err != nil {
+		serveError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	cfg, err := serveConfig(req)
+	if err != nil {
+		serveError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	resp, err := playgroundCompile(req.Source, cfg)
+	if err != nil {
+		serveError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// Answer with the `status` and the error `message`.
+func serveError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+// Return the configuration selected by the target and the options of `req`.
+func serveConfig(req serveRequest) (gocc.Config, error) {
+	cfg := gocc.Config{Target: req.Target}
+	if _, ok := codegen.Targets[cfg.TargetTriple()]; !ok {
+		return cfg, errors.New(session.Tr("unknown target \"%s\"", req.Target))
+	}
+	for _, option := range req.Options {
+		switch {
+		case option == "-g":
+			cfg.DebugInfo = true
+		case option == "-fPIC" || option == "-fpic":
+			cfg.PIC = true
+		case option == "-fstack-protector":
+			cfg.StackProtector = codegen.ProtectArrays
+		case option == "-fstack-protector-all":
+			cfg.StackProtector = codegen.ProtectAll
+		case option == "-fomit-frame-pointer":
+			cfg.OmitFramePointer = true
+		case option == "-mno-red-zone":
+			cfg.NoRedZone = true
+		case option == "-fsanitize=undefined-lite":
+			cfg.SanitizeUndefined = true
+		case option == "-fverbose-asm":
+			cfg.VerboseAsm = true
+		case option == "-emit-llvm":
+			cfg.EmitLLVM = true
+		case option == "-emit-qbe":
+			cfg.EmitQBE = true
+		case strings.HasPrefix(option, "-O"):
+			level, ok := optimizationLevel(option)
+			if !ok {
+				return cfg, errors.New(session.Tr("unsupported optimization level \"%s\"", option))
+			}
+			cfg.OptLevel = level
+		case strings.HasPrefix(option, "-std="):
+			cfg.Standard = option[len("-std="):]
+		case option == "-w":
+			cfg.SuppressWarnings = true
+		case strings.HasPrefix(option, "-W"):
+			cfg.Warnings = append(cfg.Warnings, option[2:])
+		default:
+			return cfg, errors.New(session.Tr("unrecognized command-line option \"%s\"", option))
+		}
+	}
+	return cfg, nil
+}
+
+// Compile `src` with `cfg` in a session of its own, like gocc.Compile
+// does, keeping the AST and the diagnostics in both formats. The error
+// reports an invalid configuration.
+func playgroundCompile(src string, cfg gocc.Config) (*serveResponse, error) {
+	s, err := cfg.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	defer s.Free()
+	s.Language = session.Language
+	s.Color = false
+	var asm, text, diags bytes.Buffer
+	backend, err := cfg.NewBackend(&asm)
+	if err != nil {
+		return nil, err
+	}
+	reporter := &token.MemoryReporter{}
+	s.Reporter = reporter
+	file := s.AddFile(gocc.CompileFilename, "", src)
+	tok, _ := lexer.Tokenize(file)
+	program, err := parser.Parse(tok)
+	resp := &serveResponse{Diagnostics: []json.RawMessage{}}
+	if err == nil {
+		var ast bytes.Buffer
+		parser.DumpAST(&ast, program)
+		resp.AST = ast.String()
+		err = codegen.RunASTPasses(program, &cfg.Options)
+	}
+	if err == nil {
+		err = backend.Gen(program)
+	}
+	file.Report()
+	if err == nil {
+		resp.Asm = asm.String()
+	}
+	for _, d := range reporter.Diagnostics {
+		d.Print(&text)
+		(&token.JSONReporter{Out: &diags}).Report(d)
+	}
+	resp.Text = text.String()
+	for _, line := range bytes.Split(bytes.TrimSpace(diags.Bytes()), []byte("\n")) {
+		if len(line) > 0 {
+			resp.Diagnostics = append(resp.Diagnostics, line)
+		}
+	}
+	return resp, nil
+}
+
+// The page of the playground: an editor, the options and the outputs.
+const playgroundPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>gocc playground</title>
+<style>
+body { font-family: sans-serif; margin: 1em; }
+textarea, pre { font-family: monospace; width: 100%; box-sizing: border-box; }
+pre { background: #f4f4f4; padding: 0.5em; min-height: 2em; white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<h1>gocc playground</h1>
+<textarea id="source" rows="12">int a = 3;
+int b = 4;
+return a * b;</textarea>
+<p>
+<label>Target <input id="target" value="x86_64-linux"></label>
+<label>Options <input id="options" value="-O1" size="40"></label>
+<button id="compile">Compile</button>
+</p>
+<h2>Diagnostics</h2>
+<pre id="text"></pre>
+<h2>Assembly</h2>
+<pre id="asm"></pre>
+<h2>AST</h2>
+<pre id="ast"></pre>
+<script>
+document.getElementById("compile").onclick = async function () {
+  const options = document.getElementById("options").value.split(/\s+/).filter(o => o);
+  const resp = await fetch("/compile", {
+    method: "POST",
+    body: JSON.stringify({
+      source: document.getElementById("source").value,
+      target: document.getElementById("target").value,
+      options: options,
+    }),
+  });
+  const result = await resp.json();
+  document.getElementById("text").textContent = result.error || result.text;
+  document.getElementById("asm").textContent = result.asm || "";
+  document.getElementById("ast").textContent = result.ast || "";
+};
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPlayground(t *testing.T) {
+	server := httptest.NewServer(newPlayground(true))
+	defer server.Close()
+	post := func(body string) (int, map[string]any) {
+		t.Helper()
+		resp, err := http.Post(server.URL+"/compile", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var result map[string]any
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, result
+	}
+
+	status, result := post(`{"source": "int a = 1; return a + 2;", "options": ["-O1", "-Wall"]}`)
+	if status != http.StatusOK || !strings.Contains(result["asm"].(string), "main:") ||
+		!strings.HasPrefix(result["ast"].(string), "Function main") || len(result["diagnostics"].([]any)) != 0 {
+		t.Errorf("compiling a program answers %d %v", status, result)
+	}
+	status, result = post(`{"source": "return 1;", "target": "aarch64-linux", "options": ["-emit-llvm"]}`)
+	if status != http.StatusOK || !strings.Contains(result["asm"].(string), "define i32 @main") {
+		t.Errorf("compiling to LLVM IR answers %d %v", status, result)
+	}
+	status, result = post(`{"source": "return x;"}`)
+	diagnostics := result["diagnostics"].([]any)
+	if status != http.StatusOK || result["asm"] != "" || len(diagnostics) != 1 ||
+		!strings.Contains(result["text"].(string), "<input>:1:8: error: undefined variable") ||
+		diagnostics[0].(map[string]any)["kind"] != "error" {
+		t.Errorf("compiling a program with errors answers %d %v", status, result)
+	}
+	for _, body := range []string{
+		`{"source": "return 1;", "target": "pdp11"}`,
+		`{"source": "return 1;", "options": ["-Wno-such"]}`,
+		`{"source": "return 1;", "options": ["-o", "x"]}`,
+		`{"source": `,
+	} {
+		if status, result := post(body); status != http.StatusBadRequest || result["error"] == nil {
+			t.Errorf("%s answers %d %v", body, status, result)
+		}
+	}
+
+	resp, err := http.Get(server.URL + "/compile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET /compile answers %d", resp.StatusCode)
+	}
+	resp, err = http.Get(server.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		t.Errorf("GET / answers %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/youngfr/gocc/codegen"
+	"github.com/youngfr/gocc/lexer"
+	"github.com/youngfr/gocc/parser"
+	"github.com/youngfr/gocc/token"
+)
+
+// Compile `src` to assembly for `target` in a session of its own.
+func compileToAsm(src string, target string, opts codegen.Options) string {
+	tok, _ := lexer.Tokenize(token.NewSession().AddFile("<test>", "", src))
+	program, err := parser.Parse(tok)
+	if err != nil {
+		return "error"
+	}
+	codegen.RunASTPasses(program, &opts)
+	var asm bytes.Buffer
+	if err := codegen.Targets[target](&asm, &opts).Gen(program); err != nil {
+		return "error"
+	}
+	return asm.String()
+}
+
+// Compilations in different sessions, with different options, must
+// produce the same output whether they run one after the other or
+// at the same time. Run with -race to catch shared state.
+func TestConcurrentSessions(t *testing.T) {
+	type job struct {
+		src    string
+		target string
+		opts   codegen.Options
+	}
+	var jobs []job
+	for _, c := range e2eCases {
+		jobs = append(jobs,
+			job{c.src, "x86_64-linux", codegen.Options{}},
+			job{c.src, "x86_64-linux", codegen.Options{OptLevel: 2, SanitizeUndefined: true, StackProtector: codegen.ProtectAll}},
+			job{c.src, "arm64-linux", codegen.Options{OptLevel: 1, PIC: true, DebugInfo: true}},
+			job{c.src, "wasm32", codegen.Options{VerboseAsm: true}})
+	}
+	want := make([]string, len(jobs))
+	for i, j := range jobs {
+		want[i] = compileToAsm(j.src, j.target, j.opts)
+	}
+	got := make([]string, len(jobs))
+	var wg sync.WaitGroup
+	for i, j := range jobs {
+		wg.Add(1)
+		go func(i int, j job) {
+			defer wg.Done()
+			got[i] = compileToAsm(j.src, j.target, j.opts)
+		}(i, j)
+	}
+	wg.Wait()
+	for i, j := range jobs {
+		if got[i] != want[i] {
+			t.Errorf("%q for %s with %+v: concurrent output differs:\n%s\nwant:\n%s", j.src, j.target, j.opts, got[i], want[i])
+		}
+	}
+}
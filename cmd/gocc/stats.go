@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"time"
+
+	"github.com/youngfr/gocc/parser"
+)
+
+// Compilation statistics
+//
+// With -ftime-report, the time spent and the memory allocated in each
+// phase are summed over all files and printed to standard error, with
+// the number of tokens and AST nodes, like gcc does. The phases are
+// those marked with enterPhase(). Types are checked as statements are
+// parsed, so type checking is part of parsing.
+
+type phaseStats struct {
+	name  string
+	time  time.Duration
+	alloc uint64 // Bytes allocated
+}
+
+var stats struct {
+	phases  []*phaseStats // In the order they were first entered
+	current *phaseStats   // Phase being measured, if any
+	start   time.Time     // When the current phase was entered
+	alloc   uint64        // Bytes allocated when it was entered
+	tokens  int
+	nodes   int
+}
+
+// Stop measuring the current phase and start measuring `name`,
+// unless it is empty.
+func measurePhase(name string) {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	now := time.Now()
+	if p := stats.current; p != nil {
+		p.time += now.Sub(stats.start)
+		p.alloc += m.TotalAlloc - stats.alloc
+	}
+	stats.current = nil
+	if name == "" {
+		return
+	}
+	for _, p := range stats.phases {
+		if p.name == name {
+			stats.current = p
+		}
+	}
+	if stats.current == nil {
+		stats.current = &phaseStats{name: name}
+		stats.phases = append(stats.phases, stats.current)
+	}
+	stats.start, stats.alloc = now, m.TotalAlloc
+}
+
+// Count the nodes of the tree rooted at `node` and of the nodes following it.
+func countNodes(node *parser.Node) int {
+	n := 0
+	parser.InspectList(node, func(*parser.Node) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+func printTimeReport(out io.Writer) {
+	measurePhase("")
+	var total phaseStats
+	for _, p := range stats.phases {
+		total.time += p.time
+		total.alloc += p.alloc
+	}
+	fmt.Fprintln(out, "Time and memory per phase:")
+	for _, p := range stats.phases {
+		share := 0.0
+		if total.time > 0 {
+			share = float64(p.time) * 100 / float64(total.time)
+		}
+		fmt.Fprintf(out, " %-16s: %8.3f ms (%3.0f%%) %8d kB\n", p.name, p.time.Seconds()*1000, share, p.alloc/1024)
+	}
+	fmt.Fprintf(out, " %-16s: %8.3f ms        %8d kB\n", "TOTAL", total.time.Seconds()*1000, total.alloc/1024)
+	fmt.Fprintf(out, "%d tokens, %d AST nodes\n", stats.tokens, stats.nodes)
+}
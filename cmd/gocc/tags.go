@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/youngfr/gocc/lexer"
+	"github.com/youngfr/gocc/parser"
+	"github.com/youngfr/gocc/token"
+)
+
+// Tags
+//
+// "gocc tags" parses each file and writes a tags file indexing the
+// declarations, for editors to jump to them: a ctags file for vim named
+// "tags" by default, or an etags file for emacs named "TAGS" with -e.
+// -o names the file, "-" for the standard output, and -p the compilation
+// database to read the files with, see compdb.go. The tags of ctags are
+// sorted by name and located by a search pattern matching the line:
+//
+//	a	prog.c	/^int a = 1;$/;"	l	line:1
+//
+// The program is the body of an implicit main, so the declarations are
+// those of its variables, of kind "l" for local variables. There are no
+// other functions, globals, typedefs nor structs to index yet. Errors
+// are reported like when compiling, and the declarations the parser
+// read in spite of them are indexed anyway.
+
+// A declaration to index.
+type tag struct {
+	name string
+	file *token.File
+	tok  *token.Token // Name in the declaration
+}
+
+// Write the tags of the files of the command line `args`, and exit with
+// the status of the errors of the files that failed, if any.
+func tags(args []string) {
+	etags := false
+	output := ""
+	dbPath := ""
+	var inputs []input
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "-e":
+			etags = true
+		case args[i] == "-o":
+			if i+1 == len(args) {
+				usage()
+			}
+			output = args[i+1]
+			i++
+		case args[i] == "-p":
+			if i+1 == len(args) {
+				usage()
+			}
+			dbPath = args[i+1]
+			i++
+		case frontEndOption(args[i]):
+		case strings.HasPrefix(args[i], "-") && args[i] != "-":
+			usage()
+		default:
+			inputs = append(inputs, input{path: args[i]})
+		}
+	}
+	if len(inputs) == 0 {
+		usage()
+	}
+	if err := config.Apply(session); err != nil {
+		fatalStatus(token.ExitUsage, err.Error())
+	}
+	var db compileDB
+	if dbPath != "" {
+		var err error
+		if db, err = loadCompileDB(dbPath); err != nil {
+			fatal(err.Error())
+		}
+	}
+	if output == "" {
+		output = "tags"
+		if etags {
+			output = "TAGS"
+		}
+	}
+	status := 0
+	var files []*token.File
+	var all []tag
+	for _, in := range inputs {
+		file := db.openInput(in)
+		tok, _ := lexer.Tokenize(file)
+		program, err := parser.Parse(tok)
+		file.Report()
+		if err != nil {
+			if s := file.ExitStatus(); status == 0 || s < status {
+				status = s
+			}
+		}
+		files = append(files, file)
+		// Locals are kept newest first.
+		var declared []tag
+		for v := program.Locals; v != nil; v = v.Next {
+			declared = append([]tag{{v.Name(), file, v.Decl()}}, declared...)
+		}
+		all = append(all, declared...)
+	}
+	var b bytes.Buffer
+	if etags {
+		writeEtags(&b, files, all)
+	} else {
+		writeCtags(&b, all)
+	}
+	if output == "-" {
+		os.Stdout.Write(b.Bytes())
+	} else if err := os.WriteFile(output, b.Bytes(), 0o644); err != nil {
+		fatal(err.Error())
+	}
+	if status != 0 {
+		os.Exit(status)
+	}
+}
+
+// Return the line holding the name of `t`, and the offset it starts at.
+func (t tag) line() (string, int) {
+	contents := t.file.Contents
+	begin := strings.LastIndexByte(contents[:t.tok.Begin], '\n') + 1
+	end := strings.IndexByte(contents[t.tok.Begin:], '\n')
+	if end < 0 {
+		return contents[begin:], begin
+	}
+	return contents[begin : t.tok.Begin+end], begin
+}
+
+// Write `tags` in the extended format of ctags, sorted by name.
+func writeCtags(b *bytes.Buffer, tags []tag) {
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].name < tags[j].name
+	})
+	fmt.Fprintln(b, "!_TAG_FILE_FORMAT\t2\t/extended format; --format=1 will not append ;\" to lines/")
+	fmt.Fprintln(b, "!_TAG_FILE_SORTED\t1\t/0=unsorted, 1=sorted, 2=foldcase/")
+	fmt.Fprintln(b, "!_TAG_PROGRAM_NAME\tgocc\t//")
+	escaper := strings.NewReplacer(`\`, `\\`, `/`, `\/`)
+	for _, t := range tags {
+		text, _ := t.line()
+		line, _ := t.tok.Position()
+		fmt.Fprintf(b, "%s\t%s\t/^%s$/;\"\tl\tline:%d\n", t.name, t.file.Name, escaper.Replace(strings.TrimSuffix(text, "\r")), line)
+	}
+}
+
+// Write `tags` in the format of etags, with a section for each of `files`.
+func writeEtags(b *bytes.Buffer, files []*token.File, tags []tag) {
+	for _, file := range files {
+		var section bytes.Buffer
+		for _, t := range tags {
+			if t.file != file {
+				continue
+			}
+			text, begin := t.line()
+			line, _ := t.tok.Position()
+			// The text of the line up to the end of the name.
+			fmt.Fprintf(&section, "%s\x7f%s\x01%d,%d\n", text[:t.tok.Begin+t.tok.Length-begin], t.name, line, begin)
+		}
+		fmt.Fprintf(b, "\x0c\n%s,%d\n", file.Name, section.Len())
+		b.Write(section.Bytes())
+	}
+}
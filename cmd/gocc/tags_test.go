@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/youngfr/gocc/lexer"
+	"github.com/youngfr/gocc/parser"
+	"github.com/youngfr/gocc/token"
+)
+
+func TestTags(t *testing.T) {
+	file := token.NewSession().AddFile("a.c", "a.c", "int b = 1;\nint *a = &b; /* a/b */\n{ int b; b = 2; }\nreturn b;\n")
+	tok, _ := lexer.Tokenize(file)
+	program, err := parser.Parse(tok)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var all []tag
+	for v := program.Locals; v != nil; v = v.Next {
+		all = append([]tag{{v.Name(), file, v.Decl()}}, all...)
+	}
+	var etags bytes.Buffer
+	writeEtags(&etags, []*token.File{file}, all)
+	want := "\x0c\na.c,41\nint b\x7fb\x011,0\nint *a\x7fa\x012,11\n{ int b\x7fb\x013,34\n"
+	if etags.String() != want {
+		t.Errorf("etags %q, want %q", etags.String(), want)
+	}
+	var ctags bytes.Buffer
+	writeCtags(&ctags, all)
+	lines := strings.Split(ctags.String(), "\n")
+	want = strings.Join([]string{
+		"a\ta.c\t/^int *a = &b; \\/* a\\/b *\\/$/;\"\tl\tline:2",
+		"b\ta.c\t/^int b = 1;$/;\"\tl\tline:1",
+		"b\ta.c\t/^{ int b; b = 2; }$/;\"\tl\tline:3",
+		"",
+	}, "\n")
+	if got := strings.Join(lines[3:], "\n"); got != want {
+		t.Errorf("ctags:\n%s\nwant:\n%s", got, want)
+	}
+}
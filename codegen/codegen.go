@@ -0,0 +1,628 @@
+// Package codegen generates assembly, LLVM IR, QBE IL or WebAssembly
+// from an AST, for each supported target.
+package codegen
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/youngfr/gocc/parser"
+	"github.com/youngfr/gocc/token"
+)
+
+// Code generator
+//
+// Backends keep all their state, including label numbers, in the value
+// created for one compilation, so identical input always produces
+// identical output.
+//
+// The assembly sticks to the syntax GNU as and the integrated assembler
+// of clang have in common, so that either can assemble it; what differs
+// between object formats, such as ELF-only directives, depends on the
+// target. TestAssemblers checks it with the assemblers it finds.
+
+// A backend emits assembly for one target architecture
+// to the writer it was created with. Gen returns the errors
+// recorded for the file of the program, in which case the
+// output is incomplete, or the error writing the output.
+type Backend interface {
+	Gen(program *parser.Function) error
+}
+
+// Make the writes of a backend to `*out` go to a buffer during Gen, so
+// that the output is written in a few large writes rather than one per
+// line, and return the function writing the rest of the buffer out at
+// the end of Gen, which sets `*err` to the error of writing, unless it
+// is already set:
+//
+//	defer buffer(&x.out, &err)()
+func buffer(out *io.Writer, err *error) func() {
+	w, saved := bufio.NewWriter(*out), *out
+	*out = w
+	return func() {
+		*out = saved
+		if flushErr := w.Flush(); *err == nil {
+			*err = flushErr
+		}
+	}
+}
+
+// Supported targets, keyed by the triple passed to -target.
+// Each entry creates a fresh backend for one compilation.
+var Targets = map[string]func(out io.Writer, opts *Options) Backend{
+	"x86_64-linux":  newX86,
+	"amd64-linux":   newX86,
+	"x86_64-darwin": newX86Darwin,
+	"arm64-linux":   newArm64,
+	"aarch64-linux": newArm64,
+	"wasm32":        newWasm,
+	"go":            newGo,
+}
+
+// Return a fresh backend for `target` writing to `out` with `opts`,
+// or one emitting LLVM IR for it with `emitLLVM`.
+func NewBackend(target string, emitLLVM bool, out io.Writer, opts *Options) Backend {
+	if emitLLVM {
+		return &llvm{out: out, opts: opts, triple: llvmTriples[target]}
+	}
+	return Targets[target](out, opts)
+}
+
+// Return whether the output for `target` can only be printed,
+// since there is no assembler for it.
+func PrintOnly(target string) bool {
+	switch Targets[target](nil, nil).(type) {
+	case *wasm, *golang:
+		return true
+	}
+	return false
+}
+
+func newX86(out io.Writer, opts *Options) Backend {
+	return &x86{out: out, opts: opts}
+}
+
+func newX86Darwin(out io.Writer, opts *Options) Backend {
+	return &x86{out: out, opts: opts, darwin: true}
+}
+
+func newArm64(out io.Writer, opts *Options) Backend {
+	return &arm64{out: out, opts: opts}
+}
+
+func newWasm(out io.Writer, opts *Options) Backend {
+	return &wasm{out: out, opts: opts}
+}
+
+// Assign offsets to local variables.
+func assignLvarOffsets(program *parser.Function, opts *Options) {
+	offset := 0
+	// The stack canary sits right below the saved frame pointer.
+	if protects(program, opts) {
+		offset = 8
+	}
+	for v := program.Locals; v != nil; v = v.Next {
+		offset = alignTo(offset+v.Type.Size, v.Type.Size)
+		v.Offset = -offset
+	}
+	program.StackSize = alignTo(offset, 16)
+}
+
+// Stack protector modes selected with -fstack-protector[-all].
+const (
+	ProtectNone = iota
+	ProtectArrays
+	ProtectAll
+)
+
+// Whether a canary guards the frame of `program`. In the default mode
+// only functions with character arrays are protected; gocc has no
+// arrays yet, so only -fstack-protector-all has an effect for now.
+func protects(program *parser.Function, opts *Options) bool {
+	return opts.StackProtector == ProtectAll
+}
+
+// Round up `n` to the nearest multiple of `align`.
+// For instance, alignTo(5, 8) == 8 && alignTo(11, 8) == 16
+func alignTo(n, align int) int {
+	return (n + align - 1) / align * align
+}
+
+// Whether comparison `in` is only read by the next instruction, as the
+// condition of a branch or select. That instruction can then test the
+// flags set by the comparison directly instead of materializing a 0 or
+// 1 first.
+func fusesWithFlags(in *IRInstr, rest []*IRInstr, uses []int) bool {
+	if uses[in.dst] != 1 {
+		return false
+	}
+	for _, next := range rest {
+		if next.kind == IRImm || next.kind == IRLocal {
+			continue
+		}
+		return next.kind == IRBr && next.lhs == in.dst || next.kind == IRSelect && next.cond == in.dst
+	}
+	return false
+}
+
+// x86 is the x86-64 backend using the System V ABI.
+//
+// It selects instructions for the IR one at a time, using %rax and %rdi
+// as scratch registers. Virtual registers live in stack slots below the
+// local variables, with two exceptions: immediates and local addresses
+// are rematerialized at each use, and a value read only by the very next
+// instruction stays in %rax instead of making a round trip to memory.
+//
+// Instructions are buffered rather than printed right away, so
+// that assembly passes such as the peephole optimizer can rewrite them.
+//
+// With `darwin` set, the output targets macOS: symbols get the leading
+// underscore of the Mach-O ABI and the canary is read through the GOT.
+type x86 struct {
+	out    io.Writer
+	opts   *Options
+	darwin bool
+
+	code  []instr
+	fn    *IRFunction
+	uses  []int        // Number of reads of each virtual register
+	remat []*IRInstr   // Defining IRImm or IRLocal, if any
+	inRax int          // Register whose value is only held in %rax
+	slots []int        // Frame offset of each spilled register
+	frame int          // Bytes used by locals and spill slots
+	size  int          // Bytes subtracted from %rsp by the prologue
+	token *token.Token // Token of the IR instruction being selected
+	flags *IRInstr     // Comparison whose result is only held in the flags
+
+	comment string // Comment for the next instruction emitted
+
+	checks []ubCheck // Failed undefined behavior checks to report
+}
+
+func (x *x86) emit(op string, args ...string) {
+	in := instr{op: op, args: args, comment: x.comment}
+	x.comment = ""
+	if x.token != nil {
+		in.line, in.column = x.token.Position()
+	}
+	x.code = append(x.code, in)
+}
+
+func (x *x86) label(name string) {
+	x.code = append(x.code, instr{op: name, label: true})
+}
+
+// Assembly name of the C symbol `name`.
+func (x *x86) symbol(name string) string {
+	if x.darwin {
+		return "_" + name
+	}
+	return name
+}
+
+// Assembly name of the function-local label `name`. Mach-O only
+// keeps labels out of the symbol table if they start with "L".
+func (x *x86) labelName(name string) string {
+	if x.darwin {
+		return "L." + name
+	}
+	return ".L." + name
+}
+
+// Return `s` quoted for .file and .ascii, the same way for GNU as, the
+// integrated assembler of clang and the asm package. Bytes other than
+// printable ASCII are written as octal escapes: neither assembler knows
+// the \u escapes of Go, and \x escapes swallow the hex digits following
+// them in GNU as.
+func asmString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c == '\n':
+			b.WriteString(`\n`)
+		case c == '\t':
+			b.WriteString(`\t`)
+		case c < ' ' || c > '~':
+			fmt.Fprintf(&b, "\\%03o", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// Call a function defined outside of the output, through the PLT if
+// position-independent code is requested. On macOS the linker always
+// routes such calls through stubs, so no relocation suffix is needed.
+//
+// The ABI requires %rsp to be 16-byte aligned at a call. Nothing is
+// pushed once the prologue has run, since temporaries live in stack
+// slots, so the frame size chosen by frameSize is all that keeps it
+// aligned.
+func (x *x86) call(name string) {
+	name = x.symbol(name)
+	if x.opts.PIC && !x.darwin {
+		name += "@PLT"
+	}
+	x.emit("call", name)
+}
+
+// Return an operand reading the stack canary. glibc keeps it at
+// %fs:40, while macOS exports it as ___stack_chk_guard, whose
+// address is loaded into %rcx.
+func (x *x86) canary() string {
+	if x.darwin {
+		x.emit("mov", x.symbol("__stack_chk_guard")+"@GOTPCREL(%rip)", "%rcx")
+		return "(%rcx)"
+	}
+	return "%fs:40"
+}
+
+// Address of the frame location at `offset`. Offsets are relative to
+// the top of the frame, which is %rbp unless the frame pointer is
+// omitted, in which case it is found `size` bytes above %rsp.
+func (x *x86) local(offset int) string {
+	if x.opts.OmitFramePointer {
+		return fmt.Sprintf("%d(%%rsp)", offset+x.size)
+	}
+	return fmt.Sprintf("%d(%%rbp)", offset)
+}
+
+// Stack slot of a virtual register, allocated on first use.
+func (x *x86) slot(reg int) string {
+	if x.slots[reg] == 0 {
+		x.frame += 8
+		x.slots[reg] = -x.frame
+	}
+	return x.local(x.slots[reg])
+}
+
+// Copy virtual register `reg` to the machine register `dst`.
+func (x *x86) load(reg int, dst string) {
+	if in := x.remat[reg]; in != nil {
+		if in.kind == IRImm {
+			x.emit("mov", fmt.Sprintf("$%d", in.value), dst)
+		} else {
+			x.emit("lea", x.local(in.value), dst)
+		}
+		return
+	}
+	if reg == x.inRax {
+		if dst != "%rax" {
+			x.emit("mov", "%rax", dst)
+		}
+		return
+	}
+	x.emit("mov", x.slot(reg), dst)
+}
+
+// Record that the value of virtual register `reg` is in %rax.
+// It is spilled unless the next instruction emitting code,
+// found in `rest`, is its only reader.
+func (x *x86) result(reg int, rest []*IRInstr) {
+	x.inRax = 0
+	if x.uses[reg] == 0 {
+		return
+	}
+	for _, next := range rest {
+		if next.kind == IRImm || next.kind == IRLocal {
+			continue
+		}
+		if x.uses[reg] == 1 && (next.lhs == reg || next.rhs == reg) {
+			x.inRax = reg
+			return
+		}
+		break
+	}
+	x.emit("mov", "%rax", x.slot(reg))
+}
+
+func (x *x86) Gen(program *parser.Function) (err error) {
+	defer buffer(&x.out, &err)()
+	fn, err := lower(program, x.opts)
+	if err != nil {
+		return err
+	}
+	x.fn = fn
+	x.uses = x.fn.uses()
+	x.remat = make([]*IRInstr, x.fn.nregs+1)
+	x.slots = make([]int, x.fn.nregs+1)
+	x.frame = x.fn.stackSize
+	if x.opts.DebugInfo {
+		x.emit(".file", "1 "+asmString(program.File.Name))
+	}
+	x.emit(".text")
+	x.emit(".globl", x.symbol(x.fn.name))
+	if !x.darwin {
+		x.emit(".type", x.fn.name, "@function")
+	}
+	x.label(x.symbol(x.fn.name))
+	// Call frame information lets debuggers and profilers unwind the
+	// stack. The CFA is the value of %rsp before the function was called.
+	x.emit(".cfi_startproc")
+	canary := protects(program, x.opts)
+	if x.opts.OmitFramePointer {
+		// Locals are addressed from %rsp, so the frame size must be
+		// known before selecting any instruction. Selecting the body
+		// once allocates every spill slot; the same slots are reused
+		// when selecting it again below.
+		start := len(x.code)
+		x.genBlocks()
+		x.size = x.frameSize(canary)
+		x.code = x.code[:start]
+		x.remat = make([]*IRInstr, x.fn.nregs+1)
+		x.inRax = 0
+		x.checks = nil
+	} else {
+		x.emit("push", "%rbp")
+		x.emit(".cfi_def_cfa_offset", "16")
+		x.emit(".cfi_offset", "%rbp", "-16")
+		x.emit("mov", "%rsp", "%rbp")
+		x.emit(".cfi_def_cfa_register", "%rbp")
+	}
+	// The frame size is only known once spill slots are allocated.
+	prologue := len(x.code)
+	x.emit("sub")
+	if x.opts.OmitFramePointer && x.size != 0 {
+		x.emit(".cfi_def_cfa_offset", fmt.Sprint(x.size+8))
+	}
+	if canary {
+		x.emit("mov", x.canary(), "%rax")
+		x.emit("mov", "%rax", x.local(-8))
+	}
+	x.genBlocks()
+	if !x.opts.OmitFramePointer {
+		x.size = x.frameSize(canary)
+	}
+	if x.size == 0 {
+		x.code = append(x.code[:prologue], x.code[prologue+1:]...)
+	} else {
+		x.code[prologue].args = []string{fmt.Sprintf("$%d", x.size), "%rsp"}
+	}
+	x.token = nil
+	x.label(x.labelName("return." + x.fn.name))
+	if canary {
+		// %rax holds the return value, so compare in %rdx.
+		x.emit("mov", x.local(-8), "%rdx")
+		x.emit("sub", x.canary(), "%rdx")
+		x.emit("jne", x.labelName("stack_chk_fail."+x.fn.name))
+	}
+	// Code placed after the epilogue still runs within the frame.
+	x.emit(".cfi_remember_state")
+	if x.opts.OmitFramePointer {
+		if x.size != 0 {
+			x.emit("add", fmt.Sprintf("$%d", x.size), "%rsp")
+			x.emit(".cfi_def_cfa_offset", "8")
+		}
+	} else {
+		if x.size != 0 {
+			x.emit("mov", "%rbp", "%rsp")
+		}
+		x.emit("pop", "%rbp")
+		x.emit(".cfi_def_cfa", "%rsp", "8")
+	}
+	x.emit("ret")
+	x.emit(".cfi_restore_state")
+	if canary {
+		x.label(x.labelName("stack_chk_fail." + x.fn.name))
+		x.call("__stack_chk_fail")
+	}
+	x.genChecks()
+	x.emit(".cfi_endproc")
+	if !x.darwin {
+		x.emit(".size", x.fn.name, ".-"+x.fn.name)
+	}
+	x.genCheckMessages()
+	if !x.darwin {
+		// Without this note, GNU ld assumes the stack must be executable.
+		x.emit(".section", ".note.GNU-stack", `""`, "@progbits")
+	}
+	x.code = runAsmPasses(x.code, x.opts)
+	line, column := 0, 0
+	for _, in := range x.code {
+		if in.comment != "" {
+			fmt.Fprintf(x.out, "  # %s\n", in.comment)
+		}
+		if x.opts.DebugInfo && in.line != 0 && (in.line != line || in.column != column) {
+			line, column = in.line, in.column
+			fmt.Fprintf(x.out, "  .loc 1 %d %d\n", line, column)
+		}
+		fmt.Fprintln(x.out, in)
+	}
+	return nil
+}
+
+// Return the number of bytes the prologue subtracts from %rsp, once
+// the frame and the checks of the function are known. A leaf function
+// whose frame fits in the 128 bytes below %rsp, which the SysV ABI
+// keeps from being clobbered by signal handlers, needs none.
+func (x *x86) frameSize(canary bool) int {
+	// Failed stack protector and undefined behavior checks make calls.
+	leaf := !canary && len(x.checks) == 0
+	if leaf && !x.opts.NoRedZone && x.frame <= 128 {
+		return 0
+	}
+	if x.opts.OmitFramePointer {
+		// The return address takes the place of the saved %rbp
+		// in keeping %rsp aligned to 16 bytes.
+		return alignTo(x.frame+8, 16) - 8
+	}
+	return alignTo(x.frame, 16)
+}
+
+// Conditional jumps taken when a comparison is false.
+var x86InverseJumps = map[IRKind]string{
+	IREql: "jne",
+	IRNeq: "je",
+	IRLss: "jge",
+	IRLeq: "jg",
+}
+
+// Select instructions for every block, in order.
+func (x *x86) genBlocks() {
+	for i, bb := range x.fn.blocks {
+		if i > 0 {
+			x.label(x.labelName(bb.label))
+		}
+		var next *BasicBlock
+		if i+1 < len(x.fn.blocks) {
+			next = x.fn.blocks[i+1]
+		}
+		for j, in := range bb.instrs {
+			x.genInstr(in, bb.instrs[j+1:], next)
+		}
+	}
+}
+
+// Select instructions for `in`. `rest` holds the instructions following
+// it in the same block and `next` is the block laid out after this one.
+func (x *x86) genInstr(in *IRInstr, rest []*IRInstr, next *BasicBlock) {
+	x.token = in.token
+	if in.comment != "" {
+		x.comment = in.comment
+	}
+	switch in.kind {
+	case IRImm, IRLocal:
+		x.remat[in.dst] = in
+		return
+	case IRLoad:
+		op := "mov"
+		if in.size == 4 {
+			op = "movslq"
+		}
+		if addr := x.remat[in.lhs]; addr != nil && addr.kind == IRLocal {
+			x.emit(op, x.local(addr.value), "%rax")
+		} else {
+			x.load(in.lhs, "%rax")
+			x.emit(op, "(%rax)", "%rax")
+		}
+		x.result(in.dst, rest)
+		return
+	case IRStore:
+		ax := "%rax"
+		if in.size == 4 {
+			ax = "%eax"
+		}
+		if addr := x.remat[in.lhs]; addr != nil && addr.kind == IRLocal {
+			x.load(in.rhs, "%rax")
+			x.emit("mov", ax, x.local(addr.value))
+		} else {
+			x.load(in.lhs, "%rdi")
+			x.load(in.rhs, "%rax")
+			x.emit("mov", ax, "(%rdi)")
+		}
+		x.inRax = 0
+		return
+	case IRNeg:
+		x.load(in.lhs, "%rax")
+		if in.size == 4 {
+			x.emit("neg", "%eax")
+			if x.opts.SanitizeUndefined {
+				x.check("jo", "negation overflow")
+			}
+			x.emit("movslq", "%eax", "%rax")
+		} else {
+			x.emit("neg", "%rax")
+		}
+		x.result(in.dst, rest)
+		return
+	case IRJmp:
+		if in.then != next {
+			x.emit("jmp", x.labelName(in.then.label))
+		}
+		x.inRax = 0
+		return
+	case IRBr:
+		if x.flags != nil && x.flags.dst == in.lhs {
+			x.emit(x86InverseJumps[x.flags.kind], x.labelName(in.els.label))
+		} else {
+			x.load(in.lhs, "%rax")
+			x.emit("cmp", "$0", "%rax")
+			x.emit("je", x.labelName(in.els.label))
+		}
+		if in.then != next {
+			x.emit("jmp", x.labelName(in.then.label))
+		}
+		x.inRax = 0
+		return
+	case IRRet:
+		x.load(in.lhs, "%rax")
+		x.emit("jmp", x.labelName("return."+x.fn.name))
+		x.inRax = 0
+		return
+	case IRSelect:
+		// Loading the operands leaves the flags alone.
+		x.load(in.rhs, "%rdi")
+		x.load(in.lhs, "%rax")
+		if x.flags != nil && x.flags.dst == in.cond {
+			x.emit("cmov"+x86InverseJumps[x.flags.kind][1:], "%rdi", "%rax")
+		} else {
+			x.load(in.cond, "%rcx")
+			x.emit("cmp", "$0", "%rcx")
+			x.emit("cmove", "%rdi", "%rax")
+		}
+		x.result(in.dst, rest)
+		return
+	}
+	// The right operand is loaded first, since %rax
+	// may be holding it when it was just computed.
+	x.load(in.rhs, "%rdi")
+	x.load(in.lhs, "%rax")
+	ax, di := "%rax", "%rdi"
+	if in.size == 4 {
+		ax, di = "%eax", "%edi"
+	}
+	switch in.kind {
+	case IRAdd:
+		x.emit("add", di, ax)
+	case IRSub:
+		x.emit("sub", di, ax)
+	case IRMul:
+		x.emit("imul", di, ax)
+	case IRDiv:
+		if x.opts.SanitizeUndefined {
+			x.emit("test", di, di)
+			x.check("je", "division by zero")
+		}
+		if in.size == 4 {
+			x.emit("cdq")
+		} else {
+			x.emit("cqo")
+		}
+		x.emit("idiv", di)
+	case IREql, IRNeq, IRLss, IRLeq:
+		x.emit("cmp", di, ax)
+		x.inRax = 0
+		if fusesWithFlags(in, rest, x.uses) {
+			x.flags = in
+			return
+		}
+		switch in.kind {
+		case IREql:
+			x.emit("sete", "%al")
+		case IRNeq:
+			x.emit("setne", "%al")
+		case IRLss:
+			x.emit("setl", "%al")
+		case IRLeq:
+			x.emit("setle", "%al")
+		}
+		x.emit("movzb", "%al", "%rax")
+		x.result(in.dst, rest)
+		return
+	}
+	if in.size == 4 {
+		if x.opts.SanitizeUndefined && in.kind != IRDiv {
+			x.check("jo", "signed integer overflow")
+		}
+		x.emit("movslq", "%eax", "%rax")
+	}
+	x.result(in.dst, rest)
+}
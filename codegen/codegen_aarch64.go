@@ -0,0 +1,314 @@
+package codegen
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/youngfr/gocc/parser"
+)
+
+// arm64 is the AArch64 backend using the AAPCS64 calling convention.
+//
+// Like the x86-64 backend it selects instructions for the IR one at a
+// time, using x0 and x1 as scratch registers and stack slots below the
+// local variables for virtual registers.
+type arm64 struct {
+	out   io.Writer
+	opts  *Options
+	fn    *IRFunction
+	uses  []int      // Number of reads of each virtual register
+	remat []*IRInstr // Defining IRImm or IRLocal, if any
+	inX0  int        // Register whose value is only held in x0
+	line  int        // Line of the last .loc directive
+	col   int        // Column of the last .loc directive
+	flags *IRInstr   // Comparison whose result is only held in the flags
+}
+
+// Load an arbitrary 64-bit immediate into `reg`.
+// A single mov only accepts 16-bit chunks, so larger values
+// are built with a movz/movk sequence.
+func (a *arm64) mov(reg string, value int) {
+	if value >= -65535 && value <= 65535 {
+		fmt.Fprintf(a.out, "  mov %s, #%d\n", reg, value)
+		return
+	}
+	u := uint64(value)
+	fmt.Fprintf(a.out, "  movz %s, #%d\n", reg, u&0xffff)
+	for shift := 16; shift < 64; shift += 16 {
+		if chunk := (u >> shift) & 0xffff; chunk != 0 {
+			fmt.Fprintf(a.out, "  movk %s, #%d, lsl #%d\n", reg, chunk, shift)
+		}
+	}
+}
+
+// Stack slot of a virtual register, relative to x29.
+func (a *arm64) slot(reg int) int {
+	return -a.fn.stackSize - reg*8
+}
+
+// Return an operand addressing `offset` bytes from x29. Immediate
+// offsets are limited to 9 bits, so larger ones are computed in x9.
+func (a *arm64) frameAddr(offset int) string {
+	if offset >= -256 && offset <= 255 {
+		return fmt.Sprintf("[x29, #%d]", offset)
+	}
+	a.mov("x9", offset)
+	fmt.Fprintln(a.out, "  add x9, x29, x9")
+	return "[x9]"
+}
+
+// Copy virtual register `reg` to the machine register `dst`.
+func (a *arm64) load(reg int, dst string) {
+	if in := a.remat[reg]; in != nil {
+		if in.kind == IRImm {
+			a.mov(dst, in.value)
+		} else {
+			a.mov(dst, in.value)
+			fmt.Fprintf(a.out, "  add %s, x29, %s\n", dst, dst)
+		}
+		return
+	}
+	if reg == a.inX0 {
+		if dst != "x0" {
+			fmt.Fprintf(a.out, "  mov %s, x0\n", dst)
+		}
+		return
+	}
+	fmt.Fprintf(a.out, "  ldr %s, %s\n", dst, a.frameAddr(a.slot(reg)))
+}
+
+// Record that the value of virtual register `reg` is in x0. It is
+// spilled unless the next instruction emitting code is its only reader.
+func (a *arm64) result(reg int, rest []*IRInstr) {
+	a.inX0 = 0
+	if a.uses[reg] == 0 {
+		return
+	}
+	for _, next := range rest {
+		if next.kind == IRImm || next.kind == IRLocal {
+			continue
+		}
+		if a.uses[reg] == 1 && (next.lhs == reg || next.rhs == reg) {
+			a.inX0 = reg
+			return
+		}
+		break
+	}
+	fmt.Fprintf(a.out, "  str x0, %s\n", a.frameAddr(a.slot(reg)))
+}
+
+func (a *arm64) Gen(program *parser.Function) (err error) {
+	defer buffer(&a.out, &err)()
+	fn, err := lower(program, a.opts)
+	if err != nil {
+		return err
+	}
+	a.fn = fn
+	a.uses = a.fn.uses()
+	a.remat = make([]*IRInstr, a.fn.nregs+1)
+	if a.opts.DebugInfo {
+		fmt.Fprintf(a.out, "  .file 1 %s\n", asmString(program.File.Name))
+	}
+	fmt.Fprintln(a.out, "  .text")
+	fmt.Fprintf(a.out, "  .globl %s\n", a.fn.name)
+	fmt.Fprintf(a.out, "  .type %s, %%function\n", a.fn.name)
+	fmt.Fprintf(a.out, "%s:\n", a.fn.name)
+	fmt.Fprintln(a.out, "  .cfi_startproc")
+	fmt.Fprintln(a.out, "  stp x29, x30, [sp, #-16]!")
+	fmt.Fprintln(a.out, "  .cfi_def_cfa_offset 16")
+	fmt.Fprintln(a.out, "  .cfi_offset w30, -8")
+	fmt.Fprintln(a.out, "  .cfi_offset w29, -16")
+	fmt.Fprintln(a.out, "  mov x29, sp")
+	fmt.Fprintln(a.out, "  .cfi_def_cfa w29, 16")
+	a.mov("x9", alignTo(a.fn.stackSize+a.fn.nregs*8, 16))
+	fmt.Fprintln(a.out, "  sub sp, sp, x9")
+	canary := protects(program, a.opts)
+	if canary {
+		a.loadCanary("x9")
+		fmt.Fprintln(a.out, "  str x9, [x29, #-8]")
+	}
+	for i, bb := range a.fn.blocks {
+		if i > 0 {
+			fmt.Fprintf(a.out, ".L.%s:\n", bb.label)
+		}
+		var next *BasicBlock
+		if i+1 < len(a.fn.blocks) {
+			next = a.fn.blocks[i+1]
+		}
+		for j, in := range bb.instrs {
+			a.genInstr(in, bb.instrs[j+1:], next)
+		}
+	}
+	fmt.Fprintf(a.out, ".L.return.%s:\n", a.fn.name)
+	if canary {
+		// x0 holds the return value, so compare in x9 and x10.
+		fmt.Fprintln(a.out, "  ldr x9, [x29, #-8]")
+		a.loadCanary("x10")
+		fmt.Fprintln(a.out, "  cmp x9, x10")
+		fmt.Fprintf(a.out, "  b.ne .L.stack_chk_fail.%s\n", a.fn.name)
+	}
+	// Code placed after the epilogue still runs within the frame.
+	fmt.Fprintln(a.out, "  .cfi_remember_state")
+	fmt.Fprintln(a.out, "  mov sp, x29")
+	fmt.Fprintln(a.out, "  .cfi_def_cfa sp, 16")
+	fmt.Fprintln(a.out, "  ldp x29, x30, [sp], #16")
+	fmt.Fprintln(a.out, "  .cfi_def_cfa_offset 0")
+	fmt.Fprintln(a.out, "  .cfi_restore w30")
+	fmt.Fprintln(a.out, "  .cfi_restore w29")
+	fmt.Fprintln(a.out, "  ret")
+	fmt.Fprintln(a.out, "  .cfi_restore_state")
+	if canary {
+		fmt.Fprintf(a.out, ".L.stack_chk_fail.%s:\n", a.fn.name)
+		fmt.Fprintln(a.out, "  bl __stack_chk_fail")
+	}
+	fmt.Fprintln(a.out, "  .cfi_endproc")
+	fmt.Fprintf(a.out, "  .size %s, .-%s\n", a.fn.name, a.fn.name)
+	fmt.Fprintf(a.out, "  .section .note.GNU-stack,\"\",%%progbits\n")
+	return nil
+}
+
+// Load the stack canary, which glibc keeps in __stack_chk_guard
+// on AArch64, into `reg`.
+func (a *arm64) loadCanary(reg string) {
+	if a.opts.PIC {
+		fmt.Fprintf(a.out, "  adrp %s, :got:__stack_chk_guard\n", reg)
+		fmt.Fprintf(a.out, "  ldr %s, [%s, #:got_lo12:__stack_chk_guard]\n", reg, reg)
+	} else {
+		fmt.Fprintf(a.out, "  adrp %s, __stack_chk_guard\n", reg)
+		fmt.Fprintf(a.out, "  add %s, %s, #:lo12:__stack_chk_guard\n", reg, reg)
+	}
+	fmt.Fprintf(a.out, "  ldr %s, [%s]\n", reg, reg)
+}
+
+// Condition codes under which a comparison is false.
+var arm64InverseConds = map[IRKind]string{
+	IREql: "ne",
+	IRNeq: "eq",
+	IRLss: "ge",
+	IRLeq: "gt",
+}
+
+// Select instructions for `in`. `rest` holds the instructions following
+// it in the same block and `next` is the block laid out after this one.
+func (a *arm64) genInstr(in *IRInstr, rest []*IRInstr, next *BasicBlock) {
+	if in.comment != "" {
+		fmt.Fprintf(a.out, "  // %s\n", in.comment)
+	}
+	if a.opts.DebugInfo && in.token != nil && in.kind != IRImm && in.kind != IRLocal {
+		if line, col := in.token.Position(); line != a.line || col != a.col {
+			a.line, a.col = line, col
+			fmt.Fprintf(a.out, "  .loc 1 %d %d\n", line, col)
+		}
+	}
+	switch in.kind {
+	case IRImm, IRLocal:
+		a.remat[in.dst] = in
+		return
+	case IRLoad:
+		a.load(in.lhs, "x0")
+		if in.size == 4 {
+			fmt.Fprintln(a.out, "  ldrsw x0, [x0]")
+		} else {
+			fmt.Fprintln(a.out, "  ldr x0, [x0]")
+		}
+		a.result(in.dst, rest)
+		return
+	case IRStore:
+		a.load(in.lhs, "x1")
+		a.load(in.rhs, "x0")
+		if in.size == 4 {
+			fmt.Fprintln(a.out, "  str w0, [x1]")
+		} else {
+			fmt.Fprintln(a.out, "  str x0, [x1]")
+		}
+		a.inX0 = 0
+		return
+	case IRNeg:
+		a.load(in.lhs, "x0")
+		if in.size == 4 {
+			fmt.Fprintln(a.out, "  neg w0, w0")
+			fmt.Fprintln(a.out, "  sxtw x0, w0")
+		} else {
+			fmt.Fprintln(a.out, "  neg x0, x0")
+		}
+		a.result(in.dst, rest)
+		return
+	case IRJmp:
+		if in.then != next {
+			fmt.Fprintf(a.out, "  b .L.%s\n", in.then.label)
+		}
+		a.inX0 = 0
+		return
+	case IRBr:
+		if a.flags != nil && a.flags.dst == in.lhs {
+			fmt.Fprintf(a.out, "  b.%s .L.%s\n", arm64InverseConds[a.flags.kind], in.els.label)
+		} else {
+			a.load(in.lhs, "x0")
+			fmt.Fprintln(a.out, "  cmp x0, #0")
+			fmt.Fprintf(a.out, "  b.eq .L.%s\n", in.els.label)
+		}
+		if in.then != next {
+			fmt.Fprintf(a.out, "  b .L.%s\n", in.then.label)
+		}
+		a.inX0 = 0
+		return
+	case IRRet:
+		a.load(in.lhs, "x0")
+		fmt.Fprintf(a.out, "  b .L.return.%s\n", a.fn.name)
+		a.inX0 = 0
+		return
+	case IRSelect:
+		a.load(in.rhs, "x1")
+		a.load(in.lhs, "x0")
+		if a.flags != nil && a.flags.dst == in.cond {
+			fmt.Fprintf(a.out, "  csel x0, x1, x0, %s\n", arm64InverseConds[a.flags.kind])
+		} else {
+			a.load(in.cond, "x2")
+			fmt.Fprintln(a.out, "  cmp x2, #0")
+			fmt.Fprintln(a.out, "  csel x0, x1, x0, eq")
+		}
+		a.result(in.dst, rest)
+		return
+	}
+	// The right operand is loaded first, since x0
+	// may be holding it when it was just computed.
+	a.load(in.rhs, "x1")
+	a.load(in.lhs, "x0")
+	r := "x"
+	if in.size == 4 {
+		r = "w"
+	}
+	switch in.kind {
+	case IRAdd:
+		fmt.Fprintf(a.out, "  add %s0, %s0, %s1\n", r, r, r)
+	case IRSub:
+		fmt.Fprintf(a.out, "  sub %s0, %s0, %s1\n", r, r, r)
+	case IRMul:
+		fmt.Fprintf(a.out, "  mul %s0, %s0, %s1\n", r, r, r)
+	case IRDiv:
+		fmt.Fprintf(a.out, "  sdiv %s0, %s0, %s1\n", r, r, r)
+	case IREql, IRNeq, IRLss, IRLeq:
+		fmt.Fprintf(a.out, "  cmp %s0, %s1\n", r, r)
+		a.inX0 = 0
+		if fusesWithFlags(in, rest, a.uses) {
+			a.flags = in
+			return
+		}
+		switch in.kind {
+		case IREql:
+			fmt.Fprintln(a.out, "  cset x0, eq")
+		case IRNeq:
+			fmt.Fprintln(a.out, "  cset x0, ne")
+		case IRLss:
+			fmt.Fprintln(a.out, "  cset x0, lt")
+		case IRLeq:
+			fmt.Fprintln(a.out, "  cset x0, le")
+		}
+		a.result(in.dst, rest)
+		return
+	}
+	if in.size == 4 {
+		fmt.Fprintln(a.out, "  sxtw x0, w0")
+	}
+	a.result(in.dst, rest)
+}
@@ -0,0 +1,419 @@
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	gotoken "go/token"
+	"io"
+	"strconv"
+
+	"github.com/youngfr/gocc/parser"
+	"github.com/youngfr/gocc/types"
+)
+
+// golang is an experimental backend emitting Go source, to port small
+// programs to Go and to read what a program does without reading
+// assembly.
+//
+// Like the wasm backend, it walks the AST. int becomes int32, whose
+// arithmetic wraps around like in the code of the other backends, and
+// pointers become unsafe.Pointer, moved with unsafe.Add by the byte
+// offsets the parser already scaled. Locals live in an array standing
+// for the stack frame, at the offsets the other backends give them, so
+// that programs walking from one to the next behave the same. Each is
+// reached through a Go pointer variable declared at the top of the
+// function, named after it unless the name clashes with Go or with
+// another local of an enclosing or sibling scope. Assignments
+// used as values and comparisons used as ints go through small helper
+// functions. Unlike in the native code, a division by zero panics, and
+// the checks of -fsanitize=undefined-lite are not inserted.
+//
+// The program becomes a function `program` returning its exit status,
+// which main passes to os.Exit. The source is formatted with gofmt.
+type golang struct {
+	out   io.Writer
+	opts  *Options
+	body  bytes.Buffer              // Statements of the program
+	names map[*parser.Object]string // Go name of the pointer to each variable
+	used  map[*parser.Object]bool   // Whether the pointer is ever used
+	last  bool                      // Whether expression statements keep their value in `last`
+	zero  bool                      // Whether the variable `zero` is used
+}
+
+// Precedences of Go operators, for parenthesizing operands.
+const (
+	goPrecCompare = 3
+	goPrecAdd     = 4
+	goPrecMul     = 5
+	goPrecUnary   = 6
+)
+
+// Identifiers of the generated code that variables must not shadow.
+var goReserved = map[string]bool{
+	"_": true, "int32": true, "uint64": true, "uintptr": true, "nil": true, "unsafe": true, "frame": true,
+	"b2i": true, "setInt": true, "setPtr": true, "last": true, "zero": true,
+}
+
+// Helper functions of the generated code.
+const goHelpers = `
+// Return 1 if b is true, else 0.
+func b2i(b bool) int32 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// Store v at p and return it, like a C assignment.
+func setInt(p *int32, v int32) int32 {
+	*p = v
+	return v
+}
+
+// Store v at p and return it, like a C assignment.
+func setPtr(p *unsafe.Pointer, v unsafe.Pointer) unsafe.Pointer {
+	*p = v
+	return v
+}
+`
+
+func newGo(out io.Writer, opts *Options) Backend {
+	return &golang{out: out, opts: opts}
+}
+
+func (g *golang) Gen(program *parser.Function) error {
+	assignLvarOffsets(program, g.opts)
+	g.names = map[*parser.Object]string{}
+	g.used = map[*parser.Object]bool{}
+	// Locals are kept newest first.
+	var locals []*parser.Object
+	for v := program.Locals; v != nil; v = v.Next {
+		locals = append([]*parser.Object{v}, locals...)
+	}
+	used := map[string]bool{}
+	for _, v := range locals {
+		name := v.Name()
+		for goReserved[name] || gotoken.IsKeyword(name) || used[name] {
+			name += "_"
+		}
+		used[name] = true
+		g.names[v] = name
+	}
+	// A program falling off the end returns the value of the last
+	// expression statement, so they must keep it unless it cannot.
+	g.last = !goTerminates(&parser.Node{Kind: parser.NodeBlock, Body: program.Body})
+	for n := program.Body; n != nil; n = n.Next {
+		g.stmt(n)
+	}
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "// Code generated by gocc from %s. DO NOT EDIT.\n\n", program.File.Name)
+	fmt.Fprintln(&src, "package main")
+	fmt.Fprintln(&src, "import (\n\"os\"\n\"unsafe\"\n)")
+	fmt.Fprintln(&src, "func main() {\nos.Exit(int(program()) & 0xff)\n}")
+	fmt.Fprintln(&src, "// The C program, returning its exit status.")
+	fmt.Fprintln(&src, "func program() int32 {")
+	if len(locals) > 0 {
+		fmt.Fprintf(&src, "var frame [%d]uint64\n", program.StackSize/8)
+	}
+	for _, v := range locals {
+		fmt.Fprintf(&src, "%s := (*%s)(unsafe.Add(unsafe.Pointer(&frame), %d))\n", g.names[v], goType(v.Type), program.StackSize+v.Offset)
+	}
+	if g.last {
+		fmt.Fprintln(&src, "var last int32")
+	}
+	for _, v := range locals {
+		if !g.used[v] {
+			fmt.Fprintf(&src, "_ = %s\n", g.names[v])
+		}
+	}
+	src.Write(g.body.Bytes())
+	if g.last {
+		fmt.Fprintln(&src, "return last")
+	}
+	fmt.Fprintln(&src, "}")
+	src.WriteString(goHelpers)
+	if g.zero {
+		fmt.Fprintln(&src, "\n// Divisor of divisions by zero, which Go rejects for constants.")
+		fmt.Fprintln(&src, "var zero int32")
+	}
+	if err := program.File.Err(); err != nil {
+		return err
+	}
+	formatted, err := format.Source(src.Bytes())
+	if err != nil {
+		panic(fmt.Sprintf("codegen: invalid Go source: %v\n%s", err, src.Bytes()))
+	}
+	_, err = g.out.Write(formatted)
+	return err
+}
+
+// Return whether the statement `node` never falls through, like the
+// terminating statements of the Go specification.
+func goTerminates(node *parser.Node) bool {
+	switch node.Kind {
+	case parser.NodeReturn:
+		return true
+	case parser.NodeBlock:
+		var tail *parser.Node
+		for n := node.Body; n != nil; n = n.Next {
+			tail = n
+		}
+		return tail != nil && goTerminates(tail)
+	case parser.NodeIf:
+		return node.ElseBranch != nil && goTerminates(node.ThenBranch) && goTerminates(node.ElseBranch)
+	case parser.NodeFor:
+		// There is no break statement.
+		return node.Condition == nil
+	}
+	return false
+}
+
+// Return the Go type of values of `t`.
+func goType(t *types.Type) string {
+	if t.Base != nil {
+		return "unsafe.Pointer"
+	}
+	return "int32"
+}
+
+// Print `text` as a comment if -fverbose-asm was given.
+func (g *golang) annotate(text string) {
+	if g.opts.VerboseAsm {
+		fmt.Fprintf(&g.body, "// %s\n", text)
+	}
+}
+
+func (g *golang) stmt(node *parser.Node) {
+	switch node.Kind {
+	case parser.NodeExprStmt:
+		if s := g.simpleStmt(node); s != "" {
+			g.annotate(sourceText(node.Token) + ";")
+			fmt.Fprintln(&g.body, s)
+		}
+	case parser.NodeBlock:
+		for n := node.Body; n != nil; n = n.Next {
+			g.stmt(n)
+		}
+	case parser.NodeReturn:
+		g.annotate(sourceText(node.Token) + ";")
+		fmt.Fprintf(&g.body, "return %s\n", g.convert(node.Lhs, types.Int))
+	case parser.NodeIf:
+		g.annotate(headerText(node))
+		g.ifStmt(node)
+	case parser.NodeFor:
+		g.annotate(headerText(node))
+		init := ""
+		if node.Initializer != nil && node.Initializer.Kind == parser.NodeExprStmt {
+			init = g.simpleStmt(node.Initializer)
+		} else if node.Initializer != nil {
+			g.stmt(node.Initializer)
+		}
+		cond := ""
+		if node.Condition != nil {
+			cond = g.cond(node.Condition)
+		}
+		post := ""
+		if node.Increment != nil {
+			post = g.exprStmt(node.Increment)
+		}
+		if init == "" && post == "" {
+			fmt.Fprintf(&g.body, "for %s {\n", cond)
+		} else {
+			fmt.Fprintf(&g.body, "for %s; %s; %s {\n", init, cond, post)
+		}
+		g.stmt(node.ThenBranch)
+		fmt.Fprintln(&g.body, "}")
+	default:
+		panic(fmt.Sprintf("codegen: unexpected statement %s", node.Kind))
+	}
+}
+
+func (g *golang) ifStmt(node *parser.Node) {
+	fmt.Fprintf(&g.body, "if %s {\n", g.cond(node.Condition))
+	g.stmt(node.ThenBranch)
+	switch {
+	case node.ElseBranch == nil:
+		fmt.Fprintln(&g.body, "}")
+	case node.ElseBranch.Kind == parser.NodeIf:
+		fmt.Fprint(&g.body, "} else ")
+		g.ifStmt(node.ElseBranch)
+	default:
+		fmt.Fprintln(&g.body, "} else {")
+		g.stmt(node.ElseBranch)
+		fmt.Fprintln(&g.body, "}")
+	}
+}
+
+// Return the simple statement of the expression statement `node`, or ""
+// for a lone variable, which is what a declaration without initializer
+// leaves.
+func (g *golang) simpleStmt(node *parser.Node) string {
+	switch {
+	case g.last:
+		return "last = " + g.convert(node.Lhs, types.Int)
+	case node.Lhs.Kind == parser.NodeVar:
+		return ""
+	}
+	return g.exprStmt(node.Lhs)
+}
+
+// Return the simple statement evaluating `node` for its side effects.
+func (g *golang) exprStmt(node *parser.Node) string {
+	if node.Kind == parser.NodeAsg {
+		lhs := node.Lhs
+		return g.value(lhs) + " = " + g.convert(node.Rhs, lhs.Type)
+	}
+	return "_ = " + g.value(node)
+}
+
+// Return the Go expression of `node`.
+func (g *golang) value(node *parser.Node) string {
+	s, _ := g.expr(node)
+	return s
+}
+
+// Return the Go expression of `node`, parenthesized if its
+// precedence is below `prec`.
+func (g *golang) operand(node *parser.Node, prec int) string {
+	s, p := g.expr(node)
+	if p < prec {
+		return "(" + s + ")"
+	}
+	return s
+}
+
+// Return the Go expression of `node` converted to a value of type `t`.
+func (g *golang) convert(node *parser.Node, t *types.Type) string {
+	switch {
+	case (node.Type.Base != nil) == (t.Base != nil):
+		return g.value(node)
+	case t.Base != nil && node.Kind == parser.NodeNum && node.Value == 0:
+		return "nil"
+	case t.Base != nil:
+		return "unsafe.Pointer(uintptr(" + g.value(node) + "))"
+	}
+	return "int32(uintptr(" + g.value(node) + "))"
+}
+
+// Return the Go expression of the address of the object designated by
+// `node`.
+func (g *golang) addr(node *parser.Node) string {
+	switch node.Kind {
+	case parser.NodeVar:
+		g.used[node.Variable] = true
+		return "unsafe.Pointer(" + g.names[node.Variable] + ")"
+	case parser.NodeDeref:
+		return g.value(node.Lhs)
+	}
+	panic(fmt.Sprintf("codegen: %s is not addressable", node.Kind))
+}
+
+// Return the Go expression of the condition `node` as a bool.
+func (g *golang) cond(node *parser.Node) string {
+	op := map[parser.NodeKind]string{
+		parser.NodeEql: " == ",
+		parser.NodeNeq: " != ",
+		parser.NodeLss: " < ",
+		parser.NodeLeq: " <= ",
+	}[node.Kind]
+	switch {
+	case op == "" && node.Type.Base != nil:
+		return g.operand(node, goPrecCompare+1) + " != nil"
+	case op == "":
+		return g.operand(node, goPrecCompare+1) + " != 0"
+	case node.Lhs.Type.Base == nil && node.Rhs.Type.Base == nil:
+		return g.operand(node.Lhs, goPrecCompare+1) + op + g.operand(node.Rhs, goPrecCompare+1)
+	}
+	// Comparisons involving pointers compare them as pointers,
+	// and their addresses for order.
+	ptr := types.PointerTo(types.Int)
+	lhs, rhs := g.convert(node.Lhs, ptr), g.convert(node.Rhs, ptr)
+	if node.Kind == parser.NodeLss || node.Kind == parser.NodeLeq {
+		lhs, rhs = "uintptr("+lhs+")", "uintptr("+rhs+")"
+	}
+	return lhs + op + rhs
+}
+
+// Return the Go expression of `node` and its precedence.
+func (g *golang) expr(node *parser.Node) (string, int) {
+	// Go evaluates constant expressions at compile time, and rejects
+	// those overflowing int32, so they are folded with C semantics.
+	if node.Kind != parser.NodeNum && node.Type.Base == nil {
+		if value, ok := parser.EvalConst(node, nil); ok {
+			return goNumber(value)
+		}
+	}
+	switch node.Kind {
+	case parser.NodeNum:
+		return goNumber(int(int32(node.Value)))
+	case parser.NodeVar:
+		g.used[node.Variable] = true
+		return "*" + g.names[node.Variable], goPrecUnary
+	case parser.NodeAddr:
+		return g.addr(node.Lhs), goPrecUnary + 1
+	case parser.NodeDeref:
+		return fmt.Sprintf("*(*%s)(%s)", goType(node.Type), g.value(node.Lhs)), goPrecUnary
+	case parser.NodeAsg:
+		lhs := node.Lhs
+		setter := "setInt"
+		if lhs.Type.Base != nil {
+			setter = "setPtr"
+		}
+		var target string
+		if lhs.Kind == parser.NodeVar {
+			g.used[lhs.Variable] = true
+			target = g.names[lhs.Variable]
+		} else {
+			target = fmt.Sprintf("(*%s)(%s)", goType(lhs.Type), g.value(lhs.Lhs))
+		}
+		return fmt.Sprintf("%s(%s, %s)", setter, target, g.convert(node.Rhs, lhs.Type)), goPrecUnary + 1
+	case parser.NodeNeg:
+		return negate(g.operand(node.Lhs, goPrecUnary)), goPrecUnary
+	case parser.NodeEql, parser.NodeNeq, parser.NodeLss, parser.NodeLeq:
+		return "b2i(" + g.cond(node) + ")", goPrecUnary + 1
+	}
+	// Pointer arithmetic, whose offsets are already scaled.
+	switch {
+	case node.Kind == parser.NodeAdd && node.Type.Base != nil:
+		return fmt.Sprintf("unsafe.Add(%s, %s)", g.value(node.Lhs), g.value(node.Rhs)), goPrecUnary + 1
+	case node.Kind == parser.NodeSub && node.Type.Base != nil:
+		return fmt.Sprintf("unsafe.Add(%s, %s)", g.value(node.Lhs), negate(g.operand(node.Rhs, goPrecUnary))), goPrecUnary + 1
+	case node.Kind == parser.NodeSub && node.Lhs.Type.Base != nil:
+		return fmt.Sprintf("int32(uintptr(%s) - uintptr(%s))", g.value(node.Lhs), g.value(node.Rhs)), goPrecUnary + 1
+	}
+	op, prec := map[parser.NodeKind]string{
+		parser.NodeAdd: " + ",
+		parser.NodeSub: " - ",
+		parser.NodeMul: " * ",
+		parser.NodeDiv: " / ",
+	}[node.Kind], goPrecAdd
+	if node.Kind == parser.NodeMul || node.Kind == parser.NodeDiv {
+		prec = goPrecMul
+	}
+	if op == "" {
+		panic(fmt.Sprintf("codegen: unexpected expression %s", node.Kind))
+	}
+	rhs := g.operand(node.Rhs, prec+1)
+	if value, ok := parser.EvalConst(node.Rhs, nil); ok && value == 0 && node.Kind == parser.NodeDiv {
+		g.zero = true
+		rhs = "zero"
+	}
+	return g.operand(node.Lhs, prec) + op + rhs, prec
+}
+
+// Return the Go literal of `value` and its precedence.
+func goNumber(value int) (string, int) {
+	if value < 0 {
+		return strconv.Itoa(value), goPrecUnary
+	}
+	return strconv.Itoa(value), goPrecUnary + 1
+}
+
+// Return the negation of the Go operand `s`, without making "--".
+func negate(s string) string {
+	if s[0] == '-' {
+		return "-(" + s + ")"
+	}
+	return "-" + s
+}
@@ -0,0 +1,243 @@
+package codegen
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/youngfr/gocc/parser"
+)
+
+// llvm is a backend printing textual LLVM IR instead of assembly.
+//
+// Virtual registers of the IR become SSA values. Locals keep the same
+// frame layout as the native backends: a single byte array is allocated
+// per function and variables are addressed at their offsets from its top,
+// so pointer arithmetic between locals behaves exactly like the generated
+// assembly. Every value is an i64; pointers are converted with inttoptr
+// right before memory accesses, and int values are sign-extended from
+// i32 after each load or arithmetic operation.
+type llvm struct {
+	out     io.Writer
+	opts    *Options
+	triple  string // Target triple, empty if unknown
+	temps   int    // Number of temporaries created so far
+	fn      *IRFunction
+	imms    []*IRInstr // Defining IRImm, inlined as a constant
+	spilled []bool     // Whether a virtual register lives in an alloca
+}
+
+// LLVM triples for the targets accepted by -target.
+var llvmTriples = map[string]string{
+	"x86_64-linux":  "x86_64-pc-linux-gnu",
+	"amd64-linux":   "x86_64-pc-linux-gnu",
+	"x86_64-darwin": "x86_64-apple-macosx",
+	"arm64-linux":   "aarch64-unknown-linux-gnu",
+	"aarch64-linux": "aarch64-unknown-linux-gnu",
+	"wasm32":        "wasm32-unknown-unknown",
+}
+
+// Return a fresh temporary name.
+func (l *llvm) temp() string {
+	l.temps++
+	return fmt.Sprintf("%%t%d", l.temps)
+}
+
+// Return an operand holding the value of virtual register `reg`.
+func (l *llvm) value(reg int) string {
+	if in := l.imms[reg]; in != nil {
+		return fmt.Sprint(in.value)
+	}
+	if !l.spilled[reg] {
+		return fmt.Sprintf("%%r%d", reg)
+	}
+	value := l.temp()
+	fmt.Fprintf(l.out, "  %s = load i64, i64* %%r%d.slot\n", value, reg)
+	return value
+}
+
+// Return the name to define virtual register `reg` with.
+// Spilled registers are defined as a temporary, see store().
+func (l *llvm) def(reg int) string {
+	if l.spilled[reg] {
+		return fmt.Sprintf("%%r%d.def", reg)
+	}
+	return fmt.Sprintf("%%r%d", reg)
+}
+
+// Store a just defined virtual register to its slot if it has one.
+func (l *llvm) store(reg int) {
+	if l.spilled[reg] {
+		fmt.Fprintf(l.out, "  store i64 %%r%d.def, i64* %%r%d.slot\n", reg, reg)
+	}
+}
+
+// Find virtual registers read outside of the block defining them.
+// The IR is not in SSA form, so their definition may not dominate
+// every use. They live in allocas, which mem2reg turns back into
+// SSA values.
+func (l *llvm) findSpilled() {
+	l.spilled = make([]bool, l.fn.nregs+1)
+	owner := make([]*BasicBlock, l.fn.nregs+1)
+	for _, bb := range l.fn.blocks {
+		for _, in := range bb.instrs {
+			if in.dst != 0 {
+				owner[in.dst] = bb
+			}
+		}
+	}
+	for _, bb := range l.fn.blocks {
+		for _, in := range bb.instrs {
+			for _, reg := range []int{in.lhs, in.rhs, in.cond} {
+				if reg != 0 && owner[reg] != bb {
+					l.spilled[reg] = true
+				}
+			}
+		}
+	}
+}
+
+func (l *llvm) Gen(program *parser.Function) (err error) {
+	defer buffer(&l.out, &err)()
+	fn, err := lower(program, l.opts)
+	if err != nil {
+		return err
+	}
+	l.fn = fn
+	l.imms = make([]*IRInstr, l.fn.nregs+1)
+	l.findSpilled()
+	if l.triple != "" {
+		fmt.Fprintf(l.out, "target triple = \"%s\"\n\n", l.triple)
+	}
+	fmt.Fprintf(l.out, "define i32 @%s() {\n", l.fn.name)
+	// Never allocate an empty frame so %fp always points into it.
+	size := l.fn.stackSize
+	if size == 0 {
+		size = 16
+	}
+	for i, bb := range l.fn.blocks {
+		fmt.Fprintf(l.out, "%s:\n", bb.label)
+		if i == 0 {
+			fmt.Fprintf(l.out, "  %%frame = alloca [%d x i8], align 16\n", size)
+			fmt.Fprintf(l.out, "  %%base = ptrtoint [%d x i8]* %%frame to i64\n", size)
+			fmt.Fprintf(l.out, "  %%fp = add i64 %%base, %d\n", size)
+			for reg, spilled := range l.spilled {
+				if spilled {
+					fmt.Fprintf(l.out, "  %%r%d.slot = alloca i64\n", reg)
+				}
+			}
+		}
+		for _, in := range bb.instrs {
+			l.genInstr(in)
+		}
+	}
+	fmt.Fprintln(l.out, "}")
+	return nil
+}
+
+// Return the name to define the result of arithmetic `in` with. A 4-byte
+// operation computes it in a temporary first, which extend() wraps to 32
+// bits. For sign-extended operands, this matches doing the operation on
+// i32 values.
+func (l *llvm) narrow(in *IRInstr) string {
+	if in.size == 4 {
+		return fmt.Sprintf("%%r%d.wide", in.dst)
+	}
+	return l.def(in.dst)
+}
+
+func (l *llvm) extend(in *IRInstr) {
+	if in.size == 4 {
+		value := l.temp()
+		fmt.Fprintf(l.out, "  %s = trunc i64 %%r%d.wide to i32\n", value, in.dst)
+		fmt.Fprintf(l.out, "  %s = sext i32 %s to i64\n", l.def(in.dst), value)
+	}
+}
+
+func (l *llvm) genInstr(in *IRInstr) {
+	if in.comment != "" {
+		fmt.Fprintf(l.out, "  ; %s\n", in.comment)
+	}
+	switch in.kind {
+	case IRImm:
+		if !l.spilled[in.dst] {
+			l.imms[in.dst] = in
+			return
+		}
+		fmt.Fprintf(l.out, "  %s = add i64 0, %d\n", l.def(in.dst), in.value)
+	case IRLocal:
+		fmt.Fprintf(l.out, "  %s = add i64 %%fp, %d\n", l.def(in.dst), in.value)
+	case IRLoad:
+		ptr := l.temp()
+		if in.size == 4 {
+			value := l.temp()
+			fmt.Fprintf(l.out, "  %s = inttoptr i64 %s to i32*\n", ptr, l.value(in.lhs))
+			fmt.Fprintf(l.out, "  %s = load i32, i32* %s\n", value, ptr)
+			fmt.Fprintf(l.out, "  %s = sext i32 %s to i64\n", l.def(in.dst), value)
+			break
+		}
+		fmt.Fprintf(l.out, "  %s = inttoptr i64 %s to i64*\n", ptr, l.value(in.lhs))
+		fmt.Fprintf(l.out, "  %s = load i64, i64* %s\n", l.def(in.dst), ptr)
+	case IRStore:
+		ptr := l.temp()
+		if in.size == 4 {
+			value := l.temp()
+			fmt.Fprintf(l.out, "  %s = inttoptr i64 %s to i32*\n", ptr, l.value(in.lhs))
+			fmt.Fprintf(l.out, "  %s = trunc i64 %s to i32\n", value, l.value(in.rhs))
+			fmt.Fprintf(l.out, "  store i32 %s, i32* %s\n", value, ptr)
+			return
+		}
+		fmt.Fprintf(l.out, "  %s = inttoptr i64 %s to i64*\n", ptr, l.value(in.lhs))
+		fmt.Fprintf(l.out, "  store i64 %s, i64* %s\n", l.value(in.rhs), ptr)
+		return
+	case IRNeg:
+		fmt.Fprintf(l.out, "  %s = sub i64 0, %s\n", l.narrow(in), l.value(in.lhs))
+		l.extend(in)
+	case IRJmp:
+		fmt.Fprintf(l.out, "  br label %%%s\n", in.then.label)
+		return
+	case IRBr:
+		cond := l.temp()
+		fmt.Fprintf(l.out, "  %s = icmp ne i64 %s, 0\n", cond, l.value(in.lhs))
+		fmt.Fprintf(l.out, "  br i1 %s, label %%%s, label %%%s\n", cond, in.then.label, in.els.label)
+		return
+	case IRSelect:
+		cond := l.temp()
+		fmt.Fprintf(l.out, "  %s = icmp ne i64 %s, 0\n", cond, l.value(in.cond))
+		fmt.Fprintf(l.out, "  %s = select i1 %s, i64 %s, i64 %s\n", l.def(in.dst), cond, l.value(in.lhs), l.value(in.rhs))
+	case IRRet:
+		value := l.temp()
+		fmt.Fprintf(l.out, "  %s = trunc i64 %s to i32\n", value, l.value(in.lhs))
+		fmt.Fprintf(l.out, "  ret i32 %s\n", value)
+		return
+	case IREql, IRNeq, IRLss, IRLeq:
+		var cond string
+		switch in.kind {
+		case IREql:
+			cond = "eq"
+		case IRNeq:
+			cond = "ne"
+		case IRLss:
+			cond = "slt"
+		case IRLeq:
+			cond = "sle"
+		}
+		flag := l.temp()
+		fmt.Fprintf(l.out, "  %s = icmp %s i64 %s, %s\n", flag, cond, l.value(in.lhs), l.value(in.rhs))
+		fmt.Fprintf(l.out, "  %s = zext i1 %s to i64\n", l.def(in.dst), flag)
+	default:
+		var op string
+		switch in.kind {
+		case IRAdd:
+			op = "add"
+		case IRSub:
+			op = "sub"
+		case IRMul:
+			op = "mul"
+		case IRDiv:
+			op = "sdiv"
+		}
+		fmt.Fprintf(l.out, "  %s = %s i64 %s, %s\n", l.narrow(in), op, l.value(in.lhs), l.value(in.rhs))
+		l.extend(in)
+	}
+	l.store(in.dst)
+}
@@ -0,0 +1,159 @@
+package codegen
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/youngfr/gocc/parser"
+)
+
+// qbe is a backend printing the intermediate language of QBE, a small
+// optimizing compiler backend, instead of assembly. qbe then compiles
+// it to assembly for the target:
+//
+//	gocc -emit-qbe -S -o prog.ssa prog.c && qbe -t amd64_sysv prog.ssa
+//
+// Virtual registers of the IR become temporaries of the same number,
+// which QBE does not require to be in SSA form, and immediates are
+// inlined as constants. Locals keep the frame layout of the native
+// backends in a single block allocated on entry, like with the llvm
+// backend. Values are longs; 4-byte loads sign-extend with loadsw, and
+// 4-byte arithmetic computes a word that extsw extends back.
+type qbe struct {
+	out  io.Writer
+	opts *Options
+	fn   *IRFunction
+	imms []*IRInstr // Defining IRImm, inlined as a constant
+	temp int        // Number of temporaries created so far
+}
+
+// Targets of qbe -t for the targets accepted by -target.
+var QBETargets = map[string]string{
+	"x86_64-linux":  "amd64_sysv",
+	"amd64-linux":   "amd64_sysv",
+	"x86_64-darwin": "amd64_apple",
+	"arm64-linux":   "arm64",
+	"aarch64-linux": "arm64",
+}
+
+// Return a fresh backend emitting QBE IL to `out` with `opts`.
+func NewQBE(out io.Writer, opts *Options) Backend {
+	return &qbe{out: out, opts: opts}
+}
+
+// Return an operand holding the value of virtual register `reg`.
+func (q *qbe) value(reg int) string {
+	if in := q.imms[reg]; in != nil {
+		return fmt.Sprint(in.value)
+	}
+	return fmt.Sprintf("%%r%d", reg)
+}
+
+// Return a fresh temporary name.
+func (q *qbe) tmp() string {
+	q.temp++
+	return fmt.Sprintf("%%t%d", q.temp)
+}
+
+func (q *qbe) Gen(program *parser.Function) (err error) {
+	defer buffer(&q.out, &err)()
+	fn, err := lower(program, q.opts)
+	if err != nil {
+		return err
+	}
+	q.fn = fn
+	q.imms = make([]*IRInstr, q.fn.nregs+1)
+	fmt.Fprintf(q.out, "export function w $%s() {\n", q.fn.name)
+	// Never allocate an empty frame so %fp always points into it.
+	size := q.fn.stackSize
+	if size == 0 {
+		size = 16
+	}
+	for i, bb := range q.fn.blocks {
+		fmt.Fprintf(q.out, "@%s\n", bb.label)
+		if i == 0 {
+			fmt.Fprintf(q.out, "  %%frame =l alloc16 %d\n", size)
+			fmt.Fprintf(q.out, "  %%fp =l add %%frame, %d\n", size)
+		}
+		for _, in := range bb.instrs {
+			q.genInstr(in)
+		}
+	}
+	fmt.Fprintln(q.out, "}")
+	return nil
+}
+
+// QBE comparisons by IR kind.
+var qbeComparisons = map[IRKind]string{
+	IREql: "ceql",
+	IRNeq: "cnel",
+	IRLss: "csltl",
+	IRLeq: "cslel",
+}
+
+// QBE arithmetic by IR kind.
+var qbeOperations = map[IRKind]string{
+	IRAdd: "add",
+	IRSub: "sub",
+	IRMul: "mul",
+	IRDiv: "div",
+}
+
+func (q *qbe) genInstr(in *IRInstr) {
+	if in.comment != "" {
+		fmt.Fprintf(q.out, "  # %s\n", in.comment)
+	}
+	dst := fmt.Sprintf("%%r%d", in.dst)
+	switch in.kind {
+	case IRImm:
+		q.imms[in.dst] = in
+	case IRLocal:
+		fmt.Fprintf(q.out, "  %s =l add %%fp, %d\n", dst, in.value)
+	case IRLoad:
+		if in.size == 4 {
+			fmt.Fprintf(q.out, "  %s =l loadsw %s\n", dst, q.value(in.lhs))
+		} else {
+			fmt.Fprintf(q.out, "  %s =l loadl %s\n", dst, q.value(in.lhs))
+		}
+	case IRStore:
+		if in.size == 4 {
+			fmt.Fprintf(q.out, "  storew %s, %s\n", q.value(in.rhs), q.value(in.lhs))
+		} else {
+			fmt.Fprintf(q.out, "  storel %s, %s\n", q.value(in.rhs), q.value(in.lhs))
+		}
+	case IRNeg:
+		q.arith(dst, "sub", "0", q.value(in.lhs), in.size)
+	case IRJmp:
+		fmt.Fprintf(q.out, "  jmp @%s\n", in.then.label)
+	case IRBr:
+		// jnz tests a word, which would drop the high half of a pointer.
+		cond := q.tmp()
+		fmt.Fprintf(q.out, "  %s =w cnel %s, 0\n", cond, q.value(in.lhs))
+		fmt.Fprintf(q.out, "  jnz %s, @%s, @%s\n", cond, in.then.label, in.els.label)
+	case IRSelect:
+		// There is no select: dst = rhs + (lhs - rhs) * (cond != 0).
+		cond, diff, scaled := q.tmp(), q.tmp(), q.tmp()
+		fmt.Fprintf(q.out, "  %s =l cnel %s, 0\n", cond, q.value(in.cond))
+		fmt.Fprintf(q.out, "  %s =l sub %s, %s\n", diff, q.value(in.lhs), q.value(in.rhs))
+		fmt.Fprintf(q.out, "  %s =l mul %s, %s\n", scaled, diff, cond)
+		fmt.Fprintf(q.out, "  %s =l add %s, %s\n", dst, q.value(in.rhs), scaled)
+	case IRRet:
+		fmt.Fprintf(q.out, "  ret %s\n", q.value(in.lhs))
+	case IREql, IRNeq, IRLss, IRLeq:
+		fmt.Fprintf(q.out, "  %s =l %s %s, %s\n", dst, qbeComparisons[in.kind], q.value(in.lhs), q.value(in.rhs))
+	default:
+		q.arith(dst, qbeOperations[in.kind], q.value(in.lhs), q.value(in.rhs), in.size)
+	}
+}
+
+// Define `dst` as `op` of `lhs` and `rhs`, on words extended back to
+// longs for a 4-byte `size`.
+func (q *qbe) arith(dst string, op string, lhs string, rhs string, size int) {
+	if size != 4 {
+		fmt.Fprintf(q.out, "  %s =l %s %s, %s\n", dst, op, lhs, rhs)
+		return
+	}
+	word := q.tmp()
+	fmt.Fprintf(q.out, "  %s =w %s %s, %s\n", word, op, lhs, rhs)
+	fmt.Fprintf(q.out, "  %s =l extsw %s\n", dst, word)
+}
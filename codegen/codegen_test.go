@@ -0,0 +1,50 @@
+package codegen
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/youngfr/gocc/lexer"
+	"github.com/youngfr/gocc/parser"
+	"github.com/youngfr/gocc/token"
+)
+
+// Writer counting its writes, failing them with err if set.
+type countingWriter struct {
+	writes int
+	err    error
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	if w.err != nil {
+		return 0, w.err
+	}
+	return len(p), nil
+}
+
+// Check that backends write their output at once, and report the
+// errors of writing it.
+func TestBufferedOutput(t *testing.T) {
+	src := "int a = 1; int i; for (i = 0; i < 10; i = i + 1) a = a * 2; return a;"
+	for target := range Targets {
+		for _, emitLLVM := range []bool{false, true} {
+			gen := func(w *countingWriter) error {
+				tok, _ := lexer.Tokenize(token.NewSession().AddFile("p.c", "", src))
+				program, err := parser.Parse(tok)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return NewBackend(target, emitLLVM, w, &Options{}).Gen(program)
+			}
+			w := &countingWriter{}
+			if err := gen(w); err != nil || w.writes != 1 {
+				t.Errorf("%s, LLVM IR %v: %d writes, error %v", target, emitLLVM, w.writes, err)
+			}
+			failure := errors.New("disk full")
+			if err := gen(&countingWriter{err: failure}); err != failure {
+				t.Errorf("%s, LLVM IR %v: writing fails with %v", target, emitLLVM, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package codegen
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/youngfr/gocc/parser"
+	"github.com/youngfr/gocc/types"
+)
+
+// wasm is a backend emitting the WebAssembly text format.
+//
+// WebAssembly only has structured control flow, so unlike the other
+// backends this one walks the AST rather than the basic blocks of the
+// IR. It also has its own operand stack, so no temporaries are
+// spilled. Locals live in linear memory below a frame pointer, which
+// keeps `&x` and pointer arithmetic working unchanged.
+// All values are i64; addresses are wrapped to i32 right before a load
+// or store, and int results are wrapped to 32 bits and sign-extended
+// back. The program is exported as `main`, returning an i64. Each
+// expression statement keeps its value in $ret, so that a program
+// falling off the end returns that of the last one, like with the other
+// backends.
+type wasm struct {
+	out   io.Writer
+	opts  *Options
+	loops int // Number of loops emitted so far, used to name their blocks
+}
+
+func (w *wasm) Gen(program *parser.Function) (err error) {
+	defer buffer(&w.out, &err)()
+	assignLvarOffsets(program, w.opts)
+	fmt.Fprintln(w.out, "(module")
+	fmt.Fprintln(w.out, "  (memory (export \"memory\") 1)")
+	fmt.Fprintln(w.out, "  (global $sp (mut i32) (i32.const 65536))")
+	fmt.Fprintln(w.out, "  (func $main (export \"main\") (result i64)")
+	fmt.Fprintln(w.out, "    (local $fp i32) (local $ret i64) (local $tmp i64)")
+	fmt.Fprintln(w.out, "    global.get $sp")
+	fmt.Fprintln(w.out, "    local.tee $fp")
+	fmt.Fprintf(w.out, "    i32.const %d\n", program.StackSize)
+	fmt.Fprintln(w.out, "    i32.sub")
+	fmt.Fprintln(w.out, "    global.set $sp")
+	fmt.Fprintln(w.out, "    block $L.return")
+	for n := program.Body; n != nil; n = n.Next {
+		w.genStmt(n)
+	}
+	fmt.Fprintln(w.out, "    end")
+	fmt.Fprintln(w.out, "    local.get $fp")
+	fmt.Fprintln(w.out, "    global.set $sp")
+	fmt.Fprintln(w.out, "    local.get $ret")
+	fmt.Fprintln(w.out, "  )")
+	fmt.Fprintln(w.out, ")")
+	return program.File.Err()
+}
+
+// Print `text` as a comment if -fverbose-asm was given.
+func (w *wasm) annotate(text string) {
+	if w.opts.VerboseAsm {
+		fmt.Fprintf(w.out, "    ;; %s\n", text)
+	}
+}
+
+func (w *wasm) genStmt(node *parser.Node) {
+	switch node.Kind {
+	case parser.NodeExprStmt:
+		w.annotate(sourceText(node.Token) + ";")
+		w.genExpr(node.Lhs)
+		fmt.Fprintln(w.out, "    local.set $ret")
+		return
+	case parser.NodeBlock:
+		for n := node.Body; n != nil; n = n.Next {
+			w.genStmt(n)
+		}
+		return
+	case parser.NodeReturn:
+		w.annotate(sourceText(node.Token) + ";")
+		w.genExpr(node.Lhs)
+		fmt.Fprintln(w.out, "    local.set $ret")
+		fmt.Fprintln(w.out, "    br $L.return")
+		return
+	case parser.NodeIf:
+		w.annotate(headerText(node))
+		w.genExpr(node.Condition)
+		fmt.Fprintln(w.out, "    i64.const 0")
+		fmt.Fprintln(w.out, "    i64.ne")
+		fmt.Fprintln(w.out, "    if")
+		w.genStmt(node.ThenBranch)
+		if node.ElseBranch != nil {
+			fmt.Fprintln(w.out, "    else")
+			w.genStmt(node.ElseBranch)
+		}
+		fmt.Fprintln(w.out, "    end")
+		return
+	case parser.NodeFor:
+		w.loops++
+		c := w.loops
+		if node.Initializer != nil {
+			w.genStmt(node.Initializer)
+		}
+		fmt.Fprintf(w.out, "    block $L.end.%d\n", c)
+		fmt.Fprintf(w.out, "    loop $L.begin.%d\n", c)
+		if node.Condition != nil {
+			w.annotate(headerText(node))
+			w.genExpr(node.Condition)
+			fmt.Fprintln(w.out, "    i64.eqz")
+			fmt.Fprintf(w.out, "    br_if $L.end.%d\n", c)
+		}
+		w.genStmt(node.ThenBranch)
+		if node.Increment != nil {
+			w.annotate(incrementText(node))
+			w.genExpr(node.Increment)
+			fmt.Fprintln(w.out, "    drop")
+		}
+		fmt.Fprintf(w.out, "    br $L.begin.%d\n", c)
+		fmt.Fprintln(w.out, "    end")
+		fmt.Fprintln(w.out, "    end")
+		return
+	}
+}
+
+// Compute the absolute address of a given node as an i64.
+func (w *wasm) genAddr(node *parser.Node) {
+	switch node.Kind {
+	case parser.NodeVar:
+		fmt.Fprintln(w.out, "    local.get $fp")
+		fmt.Fprintln(w.out, "    i64.extend_i32_u")
+		fmt.Fprintf(w.out, "    i64.const %d\n", node.Variable.Offset)
+		fmt.Fprintln(w.out, "    i64.add")
+		return
+	case parser.NodeDeref:
+		w.genExpr(node.Lhs)
+		return
+	}
+	node.Token.SemanticErrorf("not addressable")
+	fmt.Fprintln(w.out, "    i64.const 0")
+}
+
+// Load a value of type `tp` from the address on the stack.
+func (w *wasm) load(tp *types.Type) {
+	fmt.Fprintln(w.out, "    i32.wrap_i64")
+	if tp.Size == 4 {
+		fmt.Fprintln(w.out, "    i64.load32_s")
+	} else {
+		fmt.Fprintln(w.out, "    i64.load")
+	}
+}
+
+// Wrap the int result of an arithmetic node to 32 bits.
+func (w *wasm) wrap(node *parser.Node) {
+	if node.Type.Size == 4 {
+		fmt.Fprintln(w.out, "    i32.wrap_i64")
+		fmt.Fprintln(w.out, "    i64.extend_i32_s")
+	}
+}
+
+func (w *wasm) genExpr(node *parser.Node) {
+	switch node.Kind {
+	case parser.NodeNum:
+		fmt.Fprintf(w.out, "    i64.const %d\n", node.Value)
+		return
+	case parser.NodeNeg:
+		fmt.Fprintln(w.out, "    i64.const 0")
+		w.genExpr(node.Lhs)
+		fmt.Fprintln(w.out, "    i64.sub")
+		w.wrap(node)
+		return
+	case parser.NodeDeref:
+		w.genExpr(node.Lhs)
+		w.load(node.Type)
+		return
+	case parser.NodeAddr:
+		w.genAddr(node.Lhs)
+		return
+	case parser.NodeVar:
+		w.genAddr(node)
+		w.load(node.Type)
+		return
+	case parser.NodeAsg:
+		w.genAddr(node.Lhs)
+		fmt.Fprintln(w.out, "    i32.wrap_i64")
+		w.genExpr(node.Rhs)
+		fmt.Fprintln(w.out, "    local.tee $tmp")
+		if node.Type.Size == 4 {
+			fmt.Fprintln(w.out, "    i64.store32")
+		} else {
+			fmt.Fprintln(w.out, "    i64.store")
+		}
+		fmt.Fprintln(w.out, "    local.get $tmp")
+		return
+	}
+	w.genExpr(node.Lhs)
+	w.genExpr(node.Rhs)
+	switch node.Kind {
+	case parser.NodeAdd:
+		fmt.Fprintln(w.out, "    i64.add")
+		w.wrap(node)
+		return
+	case parser.NodeSub:
+		fmt.Fprintln(w.out, "    i64.sub")
+		w.wrap(node)
+		return
+	case parser.NodeMul:
+		fmt.Fprintln(w.out, "    i64.mul")
+		w.wrap(node)
+		return
+	case parser.NodeDiv:
+		fmt.Fprintln(w.out, "    i64.div_s")
+		w.wrap(node)
+		return
+	case parser.NodeEql, parser.NodeNeq, parser.NodeLss, parser.NodeLeq:
+		switch node.Kind {
+		case parser.NodeEql:
+			fmt.Fprintln(w.out, "    i64.eq")
+		case parser.NodeNeq:
+			fmt.Fprintln(w.out, "    i64.ne")
+		case parser.NodeLss:
+			fmt.Fprintln(w.out, "    i64.lt_s")
+		case parser.NodeLeq:
+			fmt.Fprintln(w.out, "    i64.le_s")
+		}
+		fmt.Fprintln(w.out, "    i64.extend_i32_u")
+		return
+	}
+}
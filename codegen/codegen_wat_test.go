@@ -0,0 +1,228 @@
+package codegen
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/youngfr/gocc/interp"
+	"github.com/youngfr/gocc/lexer"
+	"github.com/youngfr/gocc/parser"
+	"github.com/youngfr/gocc/token"
+)
+
+// Run the main function of the module `src`, printed by the wasm
+// backend, and return its result. Only the instructions the backend
+// emits are known.
+func runWat(src string) (int64, error) {
+	var code [][]string
+	for _, line := range strings.Split(src, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "(") || strings.HasPrefix(fields[0], ")") || fields[0] == ";;" {
+			continue
+		}
+		code = append(code, fields)
+	}
+	// Match each block, loop and if with its else and end.
+	ends, elses := map[int]int{}, map[int]int{}
+	var open []int
+	for pc, in := range code {
+		switch in[0] {
+		case "block", "loop", "if":
+			open = append(open, pc)
+		case "else":
+			elses[open[len(open)-1]] = pc
+		case "end":
+			ends[open[len(open)-1]] = pc
+			open = open[:len(open)-1]
+		}
+	}
+	type frame struct {
+		label string
+		start int // Index of the block, loop or if
+	}
+	var stack []int64
+	var control []frame
+	mem := make([]byte, 65536)
+	locals := map[string]int64{}
+	sp := int64(65536)
+	pop := func() int64 {
+		v := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		return v
+	}
+	push := func(v int64) {
+		stack = append(stack, v)
+	}
+	b2i := func(b bool) int64 {
+		if b {
+			return 1
+		}
+		return 0
+	}
+	branch := func(label string) (int, error) {
+		for i := len(control) - 1; i >= 0; i-- {
+			if control[i].label == label {
+				f := control[i]
+				if code[f.start][0] == "loop" {
+					control = control[:i+1]
+					return f.start + 1, nil
+				}
+				control = control[:i]
+				return ends[f.start] + 1, nil
+			}
+		}
+		return 0, fmt.Errorf("no label %s", label)
+	}
+	for pc := 0; pc < len(code); {
+		in := code[pc]
+		next := pc + 1
+		var err error
+		switch in[0] {
+		case "i64.const", "i32.const":
+			v, _ := strconv.ParseInt(in[1], 10, 64)
+			push(v)
+		case "local.get":
+			push(locals[in[1]])
+		case "local.set":
+			locals[in[1]] = pop()
+		case "local.tee":
+			locals[in[1]] = stack[len(stack)-1]
+		case "global.get":
+			push(sp)
+		case "global.set":
+			sp = pop()
+		case "block", "loop":
+			control = append(control, frame{in[1], pc})
+		case "if":
+			if pop() != 0 {
+				control = append(control, frame{"", pc})
+			} else if e, ok := elses[pc]; ok {
+				control = append(control, frame{"", pc})
+				next = e + 1
+			} else {
+				next = ends[pc] + 1
+			}
+		case "else":
+			next = ends[control[len(control)-1].start] + 1
+			control = control[:len(control)-1]
+		case "end":
+			control = control[:len(control)-1]
+		case "br":
+			next, err = branch(in[1])
+		case "br_if":
+			if pop() != 0 {
+				next, err = branch(in[1])
+			}
+		case "drop":
+			pop()
+		case "i32.wrap_i64":
+			push(int64(int32(pop())))
+		case "i64.extend_i32_s":
+			push(int64(int32(pop())))
+		case "i64.extend_i32_u":
+			push(int64(uint32(pop())))
+		case "i64.eqz":
+			push(b2i(pop() == 0))
+		case "i64.load":
+			push(int64(binary.LittleEndian.Uint64(mem[uint32(pop()):])))
+		case "i64.load32_s":
+			push(int64(int32(binary.LittleEndian.Uint32(mem[uint32(pop()):]))))
+		case "i64.store":
+			v, a := pop(), pop()
+			binary.LittleEndian.PutUint64(mem[uint32(a):], uint64(v))
+		case "i64.store32":
+			v, a := pop(), pop()
+			binary.LittleEndian.PutUint32(mem[uint32(a):], uint32(v))
+		default:
+			r, l := pop(), pop()
+			switch in[0] {
+			case "i32.sub":
+				push(int64(int32(l - r)))
+			case "i64.add":
+				push(l + r)
+			case "i64.sub":
+				push(l - r)
+			case "i64.mul":
+				push(l * r)
+			case "i64.div_s":
+				if r == 0 {
+					return 0, fmt.Errorf("division by zero")
+				}
+				push(l / r)
+			case "i64.eq":
+				push(b2i(l == r))
+			case "i64.ne":
+				push(b2i(l != r))
+			case "i64.lt_s":
+				push(b2i(l < r))
+			case "i64.le_s":
+				push(b2i(l <= r))
+			default:
+				return 0, fmt.Errorf("unknown instruction %s", in[0])
+			}
+		}
+		if err != nil {
+			return 0, err
+		}
+		pc = next
+	}
+	if len(stack) != 1 {
+		return 0, fmt.Errorf("%d values left on the stack", len(stack))
+	}
+	return stack[0], nil
+}
+
+// Check that the wasm backend computes what the interpreter does, for the
+// programs of test/difftest and programs falling off the end, which
+// return the value of their last expression statement.
+func TestWasm(t *testing.T) {
+	programs := []string{
+		"42==42;",
+		"0==1;",
+		"int a = 3; a + 4;",
+		"int a; for (a = 0; a < 3; a = a + 1) 7; a;",
+		"int a = 5; if (a == 5) a * 2; else 1;",
+		"return 7; 8;",
+	}
+	paths, err := filepath.Glob("../test/difftest/*.c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		programs = append(programs, string(src))
+	}
+	parse := func(src string) *parser.Function {
+		tok, _ := lexer.Tokenize(token.NewSession().AddFile("p.c", "", src))
+		program, err := parser.Parse(tok)
+		if err != nil {
+			t.Fatalf("cannot parse %q: %v", src, err)
+		}
+		return program
+	}
+	for _, src := range programs {
+		want, err := interp.Run(parse(src), interp.Options{})
+		if err != nil {
+			t.Fatalf("cannot interpret %q: %v", src, err)
+		}
+		var out bytes.Buffer
+		if err := NewBackend("wasm32", false, &out, &Options{}).Gen(parse(src)); err != nil {
+			t.Fatalf("cannot compile %q: %v", src, err)
+		}
+		got, err := runWat(out.String())
+		if err != nil {
+			t.Errorf("cannot run %q: %v", src, err)
+		} else if int(int32(got)) != want {
+			t.Errorf("%q returns %d, want %d like the interpreter", src, got, want)
+		}
+	}
+}
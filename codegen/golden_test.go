@@ -0,0 +1,114 @@
+package codegen
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/youngfr/gocc/lexer"
+	"github.com/youngfr/gocc/parser"
+	"github.com/youngfr/gocc/token"
+)
+
+// Golden files
+//
+// TestGolden compiles each program of test/difftest with each
+// configuration below and compares the output with the golden file
+// testdata/<program>.<configuration>, so that a change of the generated
+// code fails until the golden files are updated with
+//
+//	go test ./codegen -run Golden -update
+//
+// which makes the change show instruction by instruction in the diff of
+// the commit.
+
+var update = flag.Bool("update", false, "rewrite the golden files of TestGolden")
+
+var goldenConfigs = []struct {
+	name     string // Suffix of the golden files
+	target   string
+	emitLLVM bool
+	emitQBE  bool
+	opts     Options
+}{
+	{"x86_64.s", "x86_64-linux", false, false, Options{}},
+	{"x86_64-O1.s", "x86_64-linux", false, false, Options{OptLevel: 1}},
+	{"aarch64.s", "aarch64-linux", false, false, Options{}},
+	{"ll", "x86_64-linux", true, false, Options{}},
+	{"ssa", "x86_64-linux", false, true, Options{}},
+}
+
+func TestGolden(t *testing.T) {
+	paths, err := filepath.Glob("../test/difftest/*.c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		name := strings.TrimSuffix(filepath.Base(path), ".c")
+		for _, c := range goldenConfigs {
+			golden := filepath.Join("testdata", name+"."+c.name)
+			got := compileGolden(t, filepath.Base(path), string(src), c.target, c.emitLLVM, c.emitQBE, c.opts)
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				continue
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Errorf("%v; run go test ./codegen -run Golden -update", err)
+				continue
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s differs from %s at %s; run go test ./codegen -run Golden -update to accept the change",
+					path, golden, firstDifference(got, want))
+			}
+		}
+	}
+}
+
+// Return the output of compiling `src`, named `name`, for `target` with `opts`.
+func compileGolden(t *testing.T, name string, src string, target string, emitLLVM bool, emitQBE bool, opts Options) []byte {
+	t.Helper()
+	tok, _ := lexer.Tokenize(token.NewSession().AddFile(name, name, src))
+	program, err := parser.Parse(tok)
+	if err != nil {
+		t.Fatalf("cannot parse %s: %v", name, err)
+	}
+	if err := RunASTPasses(program, &opts); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	backend := NewBackend(target, emitLLVM, &out, &opts)
+	if emitQBE {
+		backend = NewQBE(&out, &opts)
+	}
+	if err := backend.Gen(program); err != nil {
+		t.Fatalf("cannot compile %s for %s: %v", name, target, err)
+	}
+	return out.Bytes()
+}
+
+// Return the first line where `got` differs from `want`, for example
+// "line 3: got "  ret", want "  leave"".
+func firstDifference(got []byte, want []byte) string {
+	gotLines, wantLines := strings.Split(string(got), "\n"), strings.Split(string(want), "\n")
+	for i := 0; ; i++ {
+		switch {
+		case i == len(gotLines):
+			return fmt.Sprintf("line %d: got the end, want %q", i+1, wantLines[i])
+		case i == len(wantLines):
+			return fmt.Sprintf("line %d: got %q, want the end", i+1, gotLines[i])
+		case gotLines[i] != wantLines[i]:
+			return fmt.Sprintf("line %d: got %q, want %q", i+1, gotLines[i], wantLines[i])
+		}
+	}
+}
@@ -0,0 +1,327 @@
+package codegen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/youngfr/gocc/parser"
+	"github.com/youngfr/gocc/token"
+)
+
+// Intermediate representation
+//
+// Before emitting code, a program is lowered from the AST to a list of
+// basic blocks. Each block holds three-address instructions operating on
+// an unlimited number of virtual registers and ends with a jump, a
+// branch or a return. Backends only have to select instructions for
+// each IR operation and decide where virtual registers live.
+//
+// Every virtual register is defined exactly once. Registers are numbered
+// from 1, so 0 can be used to mean "no register".
+
+type IRKind int
+
+const (
+	IRImm    IRKind = iota // dst = value
+	IRLocal                // dst = address of the local at frame offset `value`
+	IRLoad                 // dst = *lhs
+	IRStore                // *lhs = rhs
+	IRNeg                  // dst = -lhs
+	IRAdd                  // dst = lhs + rhs
+	IRSub                  // dst = lhs - rhs
+	IRMul                  // dst = lhs * rhs
+	IRDiv                  // dst = lhs / rhs
+	IREql                  // dst = lhs == rhs
+	IRNeq                  // dst = lhs != rhs
+	IRLss                  // dst = lhs < rhs
+	IRLeq                  // dst = lhs <= rhs
+	IRJmp                  // goto then
+	IRBr                   // if lhs != 0 goto then else goto els
+	IRRet                  // return lhs
+	IRSelect               // dst = cond != 0 ? lhs : rhs
+)
+
+type IRInstr struct {
+	kind  IRKind // Instruction kind
+	dst   int    // Destination register
+	lhs   int    // First operand register
+	rhs   int    // Second operand register
+	cond  int    // Condition register, used if kind == IRSelect
+	value int    // Used if kind == IRImm | IRLocal
+
+	// Operand size in bytes, 4 for int and 8 for pointers. Values are
+	// always held sign-extended to 64 bits; 4-byte loads and arithmetic
+	// extend their result, and 4-byte stores write the low half.
+	size int
+
+	// Used if kind == IRJmp | IRBr
+	then *BasicBlock
+	els  *BasicBlock
+
+	// Token of the AST node the instruction was lowered from
+	token *token.Token
+
+	// Source text of the statement starting at this
+	// instruction, with -fverbose-asm
+	comment string
+}
+
+type BasicBlock struct {
+	label  string     // Assembly label, without the ".L." prefix
+	instrs []*IRInstr // Instructions, the last one being a terminator
+}
+
+type IRFunction struct {
+	name      string
+	opts      *Options // Options the function is compiled with
+	blocks    []*BasicBlock
+	nregs     int // Number of virtual registers
+	stackSize int // Bytes needed by local variables
+}
+
+// Count how many times each virtual register is read.
+func (fn *IRFunction) uses() []int {
+	uses := make([]int, fn.nregs+1)
+	for _, bb := range fn.blocks {
+		for _, in := range bb.instrs {
+			uses[in.lhs]++
+			uses[in.rhs]++
+			uses[in.cond]++
+		}
+	}
+	uses[0] = 0
+	return uses
+}
+
+// Lowering from the AST
+
+type lowerer struct {
+	fn   *IRFunction
+	curr *BasicBlock // Block being filled
+
+	// Value of the last expression statement. A program falling off the
+	// end returns it, like the original stack machine left it in %rax.
+	last int
+
+	// Comment for the next instruction, with -fverbose-asm
+	note string
+
+	labels int // Number of statements given labels so far
+
+	opts *Options
+}
+
+// Lower `program` to IR and run the IR passes on it. Return the
+// errors recorded for its file, if any, instead.
+func lower(program *parser.Function, opts *Options) (*IRFunction, error) {
+	assignLvarOffsets(program, opts)
+	l := &lowerer{fn: &IRFunction{name: program.Name, opts: opts, stackSize: program.StackSize}, opts: opts}
+	l.curr = &BasicBlock{label: "entry"}
+	l.fn.blocks = append(l.fn.blocks, l.curr)
+	for n := program.Body; n != nil; n = n.Next {
+		l.lowerStmt(n)
+	}
+	if l.last == 0 {
+		l.last = l.emit(&IRInstr{kind: IRImm})
+	}
+	l.curr.instrs = append(l.curr.instrs, &IRInstr{kind: IRRet, lhs: l.last})
+	if err := program.File.Err(); err != nil {
+		return nil, err
+	}
+	runIRPasses(l.fn)
+	return l.fn, nil
+}
+
+// Append `in` to the current block, allocating its
+// destination register if it produces a value.
+func (l *lowerer) emit(in *IRInstr) int {
+	if l.note != "" {
+		in.comment, l.note = l.note, ""
+	}
+	if in.kind != IRStore {
+		l.fn.nregs++
+		in.dst = l.fn.nregs
+	}
+	l.curr.instrs = append(l.curr.instrs, in)
+	return in.dst
+}
+
+// Return a new block for statement number `c` of the function. Labels
+// are scoped by the function name and numbered within the function, so
+// they never collide and do not change when other functions do.
+func (l *lowerer) block(kind string, c int) *BasicBlock {
+	return &BasicBlock{label: fmt.Sprintf("%s.%s.%d", kind, l.fn.name, c)}
+}
+
+// Number the next statement needing labels.
+func (l *lowerer) count() int {
+	l.labels++
+	return l.labels
+}
+
+// Close the current block with the terminator `in`
+// and continue by filling the block `next`.
+func (l *lowerer) terminate(in *IRInstr, next *BasicBlock) {
+	if l.note != "" {
+		in.comment, l.note = l.note, ""
+	}
+	l.curr.instrs = append(l.curr.instrs, in)
+	l.fn.blocks = append(l.fn.blocks, next)
+	l.curr = next
+}
+
+// Annotate the next instruction with `text` if -fverbose-asm was given.
+func (l *lowerer) annotate(text string) {
+	if l.opts.VerboseAsm {
+		l.note = text
+	}
+}
+
+func (l *lowerer) lowerStmt(node *parser.Node) {
+	node.Token.File.Session.CurrentStatement = node.Token
+	switch node.Kind {
+	case parser.NodeExprStmt:
+		l.annotate(sourceText(node.Token) + ";")
+		l.last = l.lowerExpr(node.Lhs)
+		return
+	case parser.NodeBlock:
+		for n := node.Body; n != nil; n = n.Next {
+			l.lowerStmt(n)
+		}
+		return
+	case parser.NodeReturn:
+		// Code following a return is unreachable,
+		// but it still needs a block to live in.
+		dead := l.block("dead", l.count())
+		l.annotate(sourceText(node.Token) + ";")
+		l.terminate(&IRInstr{kind: IRRet, lhs: l.lowerExpr(node.Lhs), token: node.Token}, dead)
+		return
+	case parser.NodeIf:
+		c := l.count()
+		then := l.block("then", c)
+		els := l.block("else", c)
+		end := l.block("end", c)
+		l.annotate(headerText(node))
+		cond := l.lowerExpr(node.Condition)
+		l.terminate(&IRInstr{kind: IRBr, lhs: cond, then: then, els: els, token: node.Token}, then)
+		l.lowerStmt(node.ThenBranch)
+		l.terminate(&IRInstr{kind: IRJmp, then: end, token: node.Token}, els)
+		if node.ElseBranch != nil {
+			l.lowerStmt(node.ElseBranch)
+		}
+		l.terminate(&IRInstr{kind: IRJmp, then: end, token: node.Token}, end)
+		return
+	case parser.NodeFor:
+		c := l.count()
+		begin := l.block("begin", c)
+		body := l.block("body", c)
+		end := l.block("end", c)
+		if node.Initializer != nil {
+			l.lowerStmt(node.Initializer)
+		}
+		l.terminate(&IRInstr{kind: IRJmp, then: begin, token: node.Token}, begin)
+		if node.Condition != nil {
+			l.annotate(headerText(node))
+			cond := l.lowerExpr(node.Condition)
+			l.terminate(&IRInstr{kind: IRBr, lhs: cond, then: body, els: end, token: node.Token}, body)
+		}
+		l.lowerStmt(node.ThenBranch)
+		if node.Increment != nil {
+			l.annotate(incrementText(node))
+			l.lowerExpr(node.Increment)
+		}
+		l.terminate(&IRInstr{kind: IRJmp, then: begin, token: node.Token}, end)
+		return
+	}
+}
+
+// Compute the absolute address of a given node.
+func (l *lowerer) lowerAddr(node *parser.Node) int {
+	switch node.Kind {
+	case parser.NodeVar:
+		return l.emit(&IRInstr{kind: IRLocal, value: node.Variable.Offset, token: node.Token})
+	case parser.NodeDeref:
+		return l.lowerExpr(node.Lhs)
+	}
+	node.Token.SemanticErrorf("not addressable")
+	return l.emit(&IRInstr{kind: IRImm, token: node.Token})
+}
+
+func (l *lowerer) lowerExpr(node *parser.Node) int {
+	switch node.Kind {
+	case parser.NodeNum:
+		return l.emit(&IRInstr{kind: IRImm, value: node.Value, token: node.Token})
+	case parser.NodeNeg:
+		return l.emit(&IRInstr{kind: IRNeg, lhs: l.lowerExpr(node.Lhs), size: node.Type.Size, token: node.Token})
+	case parser.NodeDeref:
+		return l.emit(&IRInstr{kind: IRLoad, lhs: l.lowerExpr(node.Lhs), size: node.Type.Size, token: node.Token})
+	case parser.NodeAddr:
+		return l.lowerAddr(node.Lhs)
+	case parser.NodeVar:
+		return l.emit(&IRInstr{kind: IRLoad, lhs: l.lowerAddr(node), size: node.Type.Size, token: node.Token})
+	case parser.NodeAsg:
+		addr := l.lowerAddr(node.Lhs)
+		value := l.lowerExpr(node.Rhs)
+		l.emit(&IRInstr{kind: IRStore, lhs: addr, rhs: value, size: node.Type.Size, token: node.Token})
+		return value
+	}
+	lhs := l.lowerExpr(node.Lhs)
+	rhs := l.lowerExpr(node.Rhs)
+	// Comparisons yield an int but operate on their operands' type.
+	size := node.Type.Size
+	var kind IRKind
+	switch node.Kind {
+	case parser.NodeAdd:
+		kind = IRAdd
+	case parser.NodeSub:
+		kind = IRSub
+	case parser.NodeMul:
+		kind = IRMul
+	case parser.NodeDiv:
+		kind = IRDiv
+	case parser.NodeEql:
+		kind, size = IREql, node.Lhs.Type.Size
+	case parser.NodeNeq:
+		kind, size = IRNeq, node.Lhs.Type.Size
+	case parser.NodeLss:
+		kind, size = IRLss, node.Lhs.Type.Size
+	case parser.NodeLeq:
+		kind, size = IRLeq, node.Lhs.Type.Size
+	}
+	return l.emit(&IRInstr{kind: kind, lhs: lhs, rhs: rhs, size: size, token: node.Token})
+}
+
+// Printing, for debugging
+
+var irNames = map[IRKind]string{
+	IRImm: "imm", IRLocal: "local", IRLoad: "load", IRStore: "store",
+	IRNeg: "neg", IRAdd: "add", IRSub: "sub", IRMul: "mul", IRDiv: "div",
+	IREql: "eq", IRNeq: "ne", IRLss: "lt", IRLeq: "le",
+	IRJmp: "jmp", IRBr: "br", IRRet: "ret", IRSelect: "select",
+}
+
+func (in *IRInstr) String() string {
+	var sb strings.Builder
+	if in.dst != 0 {
+		fmt.Fprintf(&sb, "r%d = ", in.dst)
+	}
+	sb.WriteString(irNames[in.kind])
+	switch in.kind {
+	case IRImm, IRLocal:
+		fmt.Fprintf(&sb, " %d", in.value)
+	case IRJmp:
+		fmt.Fprintf(&sb, " %s", in.then.label)
+	case IRBr:
+		fmt.Fprintf(&sb, " r%d, %s, %s", in.lhs, in.then.label, in.els.label)
+	case IRSelect:
+		fmt.Fprintf(&sb, " r%d, r%d, r%d", in.cond, in.lhs, in.rhs)
+	default:
+		if in.lhs != 0 {
+			fmt.Fprintf(&sb, " r%d", in.lhs)
+		}
+		if in.rhs != 0 {
+			fmt.Fprintf(&sb, ", r%d", in.rhs)
+		}
+	}
+	return sb.String()
+}
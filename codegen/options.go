@@ -0,0 +1,37 @@
+package codegen
+
+// Code generation options, given to each backend when it is created.
+// The zero value selects the defaults of the command line.
+type Options struct {
+	// Whether -g was given, which emits .file/.loc line information.
+	DebugInfo bool
+
+	// Whether -fPIC or -fpic was given. Locals are always addressed
+	// relative to the frame pointer, so only references to symbols
+	// outside of the function are affected by this mode.
+	PIC bool
+
+	// Stack protector mode, one of ProtectNone, ProtectArrays and ProtectAll.
+	StackProtector int
+
+	// Whether -fomit-frame-pointer was given. The x86-64 backend then
+	// addresses locals from %rsp and keeps %rbp free.
+	OmitFramePointer bool
+
+	// Whether -mno-red-zone was given. Leaf functions of the x86-64
+	// backend then keep their frame above %rsp instead of in the red
+	// zone, for code such as kernels where interrupts run on the same
+	// stack.
+	NoRedZone bool
+
+	// Whether -fsanitize=undefined-lite was given. Only the
+	// x86-64 backend inserts the checks, see sanitize.go.
+	SanitizeUndefined bool
+
+	// Whether -fverbose-asm was given, which annotates
+	// the output with the source text of each statement.
+	VerboseAsm bool
+
+	// Optimization level selected with -O.
+	OptLevel int
+}
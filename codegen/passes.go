@@ -0,0 +1,279 @@
+package codegen
+
+import (
+	"github.com/youngfr/gocc/parser"
+)
+
+// Optimization passes
+//
+// Passes work on one of the three program representations: the typed
+// AST, the IR, or the x86-64 instructions buffered by the backend. Each
+// one is registered with the lowest -O level it is enabled at, so -O0
+// compiles as fast as possible and higher levels trade compile time for
+// better code.
+
+type astPass struct {
+	name  string
+	level int
+	run   func(program *parser.Function)
+}
+
+type irPass struct {
+	name  string
+	level int
+	run   func(fn *IRFunction)
+}
+
+type asmPass struct {
+	name  string
+	level int
+	run   func(code []instr) []instr
+}
+
+var astPasses = []astPass{
+	{"fold", 1, parser.FoldProgram},
+}
+
+var irPasses = []irPass{
+	{"select", 2, formSelects},
+	{"simplify-cfg", 2, simplifyCFG},
+	{"dce", 1, eliminateDeadCode},
+}
+
+var asmPasses = []asmPass{
+	{"peephole", 1, peephole},
+}
+
+// Run the AST passes enabled by `opts`. With --verify, return the
+// *parser.VerifyError of the first pass breaking the AST, if any.
+func RunASTPasses(program *parser.Function, opts *Options) error {
+	for _, p := range astPasses {
+		if opts.OptLevel >= p.level {
+			p.run(program)
+			if program.File.Session.VerifyAST {
+				if err := parser.Verify(program, p.name); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func runIRPasses(fn *IRFunction) {
+	for _, p := range irPasses {
+		if fn.opts.OptLevel >= p.level {
+			p.run(fn)
+		}
+	}
+}
+
+func runAsmPasses(code []instr, opts *Options) []instr {
+	for _, p := range asmPasses {
+		if opts.OptLevel >= p.level {
+			code = p.run(code)
+		}
+	}
+	return code
+}
+
+// Dead code elimination
+//
+// Blocks which cannot be reached from the entry block are dropped, then
+// instructions computing a value nobody reads, until none is left.
+
+func eliminateDeadCode(fn *IRFunction) {
+	removeUnreachable(fn)
+	for {
+		uses := fn.uses()
+		removed := false
+		for _, bb := range fn.blocks {
+			live := bb.instrs[:0]
+			for _, in := range bb.instrs {
+				if in.dst != 0 && uses[in.dst] == 0 {
+					removed = true
+					continue
+				}
+				live = append(live, in)
+			}
+			bb.instrs = live
+		}
+		if !removed {
+			return
+		}
+	}
+}
+
+func removeUnreachable(fn *IRFunction) {
+	reached := map[*BasicBlock]bool{}
+	var visit func(bb *BasicBlock)
+	visit = func(bb *BasicBlock) {
+		if bb == nil || reached[bb] {
+			return
+		}
+		reached[bb] = true
+		last := bb.instrs[len(bb.instrs)-1]
+		visit(last.then)
+		visit(last.els)
+	}
+	visit(fn.blocks[0])
+	blocks := fn.blocks[:0]
+	for _, bb := range fn.blocks {
+		if reached[bb] {
+			blocks = append(blocks, bb)
+		}
+	}
+	fn.blocks = blocks
+}
+
+// CFG simplification
+//
+// Jumps and branches to a block which does nothing but jump elsewhere
+// are redirected to the final destination, which often leaves the
+// intermediate block unreachable.
+
+func simplifyCFG(fn *IRFunction) {
+	// Follow a chain of jump-only blocks. The length of the
+	// chain is bounded to stop on empty infinite loops.
+	forward := func(bb *BasicBlock) *BasicBlock {
+		for i := 0; i < len(fn.blocks); i++ {
+			if len(bb.instrs) != 1 || bb.instrs[0].kind != IRJmp {
+				break
+			}
+			bb = bb.instrs[0].then
+		}
+		return bb
+	}
+	for _, bb := range fn.blocks {
+		last := bb.instrs[len(bb.instrs)-1]
+		if last.then != nil {
+			last.then = forward(last.then)
+		}
+		if last.els != nil {
+			last.els = forward(last.els)
+		}
+	}
+	removeUnreachable(fn)
+}
+
+// Select formation
+//
+// An if statement whose arms only assign a value to the same local,
+// such as `if (a < b) m = a; else m = b;`, is turned into a select of
+// the value to store. The arms are executed unconditionally, so they
+// may only contain cheap instructions which can neither fault nor have
+// side effects. A missing else arm stores the current value back.
+
+// Maximum number of instructions an arm computing
+// the value to store may have to be speculated.
+const maxSelectArm = 4
+
+func formSelects(fn *IRFunction) {
+	defs := make([]*IRInstr, fn.nregs+1)
+	preds := map[*BasicBlock]int{}
+	for _, bb := range fn.blocks {
+		for _, in := range bb.instrs {
+			defs[in.dst] = in
+		}
+		last := bb.instrs[len(bb.instrs)-1]
+		preds[last.then]++
+		preds[last.els]++
+	}
+	for _, bb := range fn.blocks {
+		br := bb.instrs[len(bb.instrs)-1]
+		if br.kind != IRBr || preds[br.then] != 1 || preds[br.els] != 1 {
+			continue
+		}
+		then, ok := selectArm(fn, br.then, defs)
+		if !ok || then.store == nil {
+			continue
+		}
+		els, ok := selectArm(fn, br.els, defs)
+		if !ok || els.end != then.end {
+			continue
+		}
+		addr := then.store.lhs
+		if els.store == nil {
+			// A missing else arm keeps the current value.
+			fn.nregs++
+			load := &IRInstr{kind: IRLoad, dst: fn.nregs, lhs: addr, size: then.store.size, token: br.token}
+			els.instrs = []*IRInstr{load}
+			els.store = &IRInstr{kind: IRStore, lhs: addr, rhs: load.dst, size: then.store.size}
+		} else if !sameLocal(defs[addr], defs[els.store.lhs]) || els.store.size != then.store.size {
+			continue
+		}
+		instrs := bb.instrs[:len(bb.instrs)-1]
+		// Move the condition right before the select,
+		// so that it can test the flags of a comparison.
+		var cond *IRInstr
+		if n := len(instrs); n > 0 && instrs[n-1].dst == br.lhs {
+			cond = instrs[n-1]
+			instrs = instrs[:n-1]
+		}
+		instrs = append(instrs, then.instrs...)
+		instrs = append(instrs, els.instrs...)
+		if cond != nil {
+			instrs = append(instrs, cond)
+		}
+		fn.nregs++
+		sel := &IRInstr{kind: IRSelect, dst: fn.nregs, cond: br.lhs, lhs: then.store.rhs, rhs: els.store.rhs, token: br.token}
+		instrs = append(instrs, sel,
+			&IRInstr{kind: IRStore, lhs: addr, rhs: sel.dst, size: then.store.size, token: then.store.token},
+			&IRInstr{kind: IRJmp, then: then.end, token: br.token})
+		bb.instrs = instrs
+		preds[then.end]--
+	}
+	removeUnreachable(fn)
+}
+
+type selectCandidate struct {
+	instrs []*IRInstr  // Instructions computing the value to store
+	store  *IRInstr    // Store to a local, nil if the arm is empty
+	end    *BasicBlock // Block both arms jump to
+}
+
+// Check whether `bb` of `fn` can be an arm of a select: it computes a
+// value without side effects, stores it to a local variable and jumps away.
+func selectArm(fn *IRFunction, bb *BasicBlock, defs []*IRInstr) (arm selectCandidate, ok bool) {
+	n := len(bb.instrs)
+	if last := bb.instrs[n-1]; last.kind != IRJmp {
+		return arm, false
+	}
+	arm.end = bb.instrs[n-1].then
+	if n == 1 {
+		return arm, true
+	}
+	arm.store = bb.instrs[n-2]
+	if arm.store.kind != IRStore || defs[arm.store.lhs].kind != IRLocal {
+		return arm, false
+	}
+	arm.instrs = bb.instrs[:n-2]
+	if len(arm.instrs) > maxSelectArm {
+		return arm, false
+	}
+	for _, in := range arm.instrs {
+		if !speculatable(fn, in, defs) {
+			return arm, false
+		}
+	}
+	return arm, true
+}
+
+// Whether `in` of `fn` may run even if the program would not have run it.
+func speculatable(fn *IRFunction, in *IRInstr, defs []*IRInstr) bool {
+	switch in.kind {
+	case IRImm, IRLocal, IREql, IRNeq, IRLss, IRLeq:
+		return true
+	case IRLoad:
+		// Locals can always be read, other addresses may be invalid.
+		return defs[in.lhs].kind == IRLocal
+	case IRNeg, IRAdd, IRSub, IRMul:
+		// These would report overflows the program never performs.
+		return !fn.opts.SanitizeUndefined
+	}
+	return false
+}
+
+func sameLocal(a, b *IRInstr) bool {
+	return a.kind == IRLocal && b.kind == IRLocal && a.value == b.value
+}
@@ -0,0 +1,129 @@
+package codegen
+
+import "strings"
+
+// Peephole optimizer
+//
+// The x86-64 backend selects instructions for one IR operation at a
+// time, which produces a lot of redundant code: values are stored to
+// a slot and read back right away, or loaded into %rax only to be moved
+// elsewhere. The rules below clean up those patterns by looking at a few
+// neighbouring instructions at a time.
+
+// An instruction of the generated assembly.
+type instr struct {
+	op    string   // Mnemonic, directive or label name
+	args  []string // Operands in AT&T order
+	label bool     // Whether this is a label definition
+
+	// Source position for the .loc directive, 0 if unknown
+	line   int
+	column int
+
+	// Source text printed before the instruction, with -fverbose-asm
+	comment string
+}
+
+func (in instr) String() string {
+	if in.label {
+		return in.op + ":"
+	}
+	if len(in.args) == 0 {
+		return "  " + in.op
+	}
+	return "  " + in.op + " " + strings.Join(in.args, ", ")
+}
+
+// Whether `in` writes to `reg` without reading its old value.
+func overwrites(in instr, reg string) bool {
+	if in.op != "mov" && in.op != "lea" {
+		return false
+	}
+	return in.args[1] == reg && !strings.Contains(in.args[0], reg)
+}
+
+func isinstr(in instr, op string, args ...string) bool {
+	if in.label || in.op != op || len(in.args) != len(args) {
+		return false
+	}
+	for i := range args {
+		if in.args[i] != args[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Remove the instruction at index i. Its comment, if any,
+// moves to the following instruction.
+func remove(code []instr, i int) []instr {
+	if code[i].comment != "" && i+1 < len(code) && code[i+1].comment == "" {
+		code[i+1].comment = code[i].comment
+	}
+	return append(code[:i], code[i+1:]...)
+}
+
+// Each rule tries to rewrite the code starting at index i and
+// reports whether it did.
+var peepholeRules = []func(code []instr, i int) ([]instr, bool){
+	// mov %rax, %rax  =>  (nothing)
+	func(code []instr, i int) ([]instr, bool) {
+		if isinstr(code[i], "mov", "%rax", "%rax") {
+			return remove(code, i), true
+		}
+		return code, false
+	},
+	// mov X, %rax; mov Y, %rax  =>  mov Y, %rax
+	// if the second instruction does not read %rax.
+	func(code []instr, i int) ([]instr, bool) {
+		if i+1 < len(code) && overwrites(code[i], "%rax") && overwrites(code[i+1], "%rax") {
+			return remove(code, i), true
+		}
+		return code, false
+	},
+	// mov %rax, S; mov S, %rax  =>  mov %rax, S
+	func(code []instr, i int) ([]instr, bool) {
+		if i+1 < len(code) && code[i].op == "mov" && len(code[i].args) == 2 && code[i].args[0] == "%rax" &&
+			isinstr(code[i+1], "mov", code[i].args[1], "%rax") {
+			return remove(code, i+1), true
+		}
+		return code, false
+	},
+	// mov X, %rax; mov %rax, %rdi; mov Y, %rax  =>  mov X, %rdi; mov Y, %rax
+	func(code []instr, i int) ([]instr, bool) {
+		if i+2 >= len(code) || !overwrites(code[i], "%rax") || !isinstr(code[i+1], "mov", "%rax", "%rdi") {
+			return code, false
+		}
+		if !overwrites(code[i+2], "%rax") {
+			return code, false
+		}
+		code[i].args = []string{code[i].args[0], "%rdi"}
+		return remove(code, i+1), true
+	},
+	// jmp L; L:  =>  L:
+	func(code []instr, i int) ([]instr, bool) {
+		if i+1 < len(code) && code[i].op == "jmp" && !code[i].label && code[i+1].label && code[i+1].op == code[i].args[0] {
+			return remove(code, i), true
+		}
+		return code, false
+	},
+}
+
+// Apply the peephole rules until none of them matches.
+func peephole(code []instr) []instr {
+	for changed := true; changed; {
+		changed = false
+		for i := 0; i < len(code); i++ {
+			for _, rule := range peepholeRules {
+				var ok bool
+				if code, ok = rule(code, i); ok {
+					changed = true
+				}
+				if i >= len(code) {
+					break
+				}
+			}
+		}
+	}
+	return code
+}
@@ -0,0 +1,64 @@
+package codegen
+
+import "fmt"
+
+// Undefined behavior checks
+//
+// With -fsanitize=undefined-lite, the x86-64 backend checks int
+// arithmetic for signed overflow and division by zero at run time.
+// A failing check jumps to an out-of-line stub which writes the source
+// location of the operation to stderr and aborts. gocc has no shift
+// operators yet, so there are no shift checks.
+
+type ubCheck struct {
+	label   string // Label of the stub reporting the failure
+	message string // Report written to stderr
+}
+
+// Jump to a report of `problem` at the current source position if the
+// condition of `jcc` holds.
+func (x *x86) check(jcc string, problem string) {
+	line, column := x.token.Position()
+	c := ubCheck{
+		label:   x.labelName(fmt.Sprintf("ub.%s.%d", x.fn.name, len(x.checks)+1)),
+		message: fmt.Sprintf("%s:%d:%d: runtime error: %s\n", x.token.File.Name, line, column, problem),
+	}
+	x.emit(jcc, c.label)
+	x.checks = append(x.checks, c)
+}
+
+// Emit the stubs of failed checks and the routine they share, which
+// writes the message at %rsi of length %rdx to stderr and aborts.
+// Checks only jump here from the body of the function, where %rsp
+// is 16-byte aligned.
+func (x *x86) genChecks() {
+	if len(x.checks) == 0 {
+		return
+	}
+	for _, c := range x.checks {
+		x.label(c.label)
+		x.emit("lea", c.label+".msg(%rip)", "%rsi")
+		x.emit("mov", fmt.Sprintf("$%d", len(c.message)), "%edx")
+		x.emit("jmp", x.labelName("ub_report."+x.fn.name))
+	}
+	x.label(x.labelName("ub_report." + x.fn.name))
+	x.emit("mov", "$2", "%edi")
+	x.call("write")
+	x.call("abort")
+}
+
+// Emit the messages of the checks in a read-only data section.
+func (x *x86) genCheckMessages() {
+	if len(x.checks) == 0 {
+		return
+	}
+	if x.darwin {
+		x.emit(".section", "__TEXT,__const")
+	} else {
+		x.emit(".section", ".rodata")
+	}
+	for _, c := range x.checks {
+		x.label(c.label + ".msg")
+		x.emit(".ascii", asmString(c.message))
+	}
+}
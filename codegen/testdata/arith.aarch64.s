@@ -0,0 +1,38 @@
+  .text
+  .globl main
+  .type main, %function
+main:
+  .cfi_startproc
+  stp x29, x30, [sp, #-16]!
+  .cfi_def_cfa_offset 16
+  .cfi_offset w30, -8
+  .cfi_offset w29, -16
+  mov x29, sp
+  .cfi_def_cfa w29, 16
+  mov x9, #48
+  sub sp, sp, x9
+  mov x1, #7
+  mov x0, #6
+  mul w0, w0, w1
+  sxtw x0, w0
+  mov x1, x0
+  mov x0, #5
+  add w0, w0, w1
+  sxtw x0, w0
+  b .L.return.main
+.L.dead.main.1:
+  mov x0, #0
+  b .L.return.main
+.L.return.main:
+  .cfi_remember_state
+  mov sp, x29
+  .cfi_def_cfa sp, 16
+  ldp x29, x30, [sp], #16
+  .cfi_def_cfa_offset 0
+  .cfi_restore w30
+  .cfi_restore w29
+  ret
+  .cfi_restore_state
+  .cfi_endproc
+  .size main, .-main
+  .section .note.GNU-stack,"",%progbits
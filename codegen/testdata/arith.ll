@@ -0,0 +1,19 @@
+target triple = "x86_64-pc-linux-gnu"
+
+define i32 @main() {
+entry:
+  %frame = alloca [16 x i8], align 16
+  %base = ptrtoint [16 x i8]* %frame to i64
+  %fp = add i64 %base, 16
+  %r4.wide = mul i64 6, 7
+  %t1 = trunc i64 %r4.wide to i32
+  %r4 = sext i32 %t1 to i64
+  %r5.wide = add i64 5, %r4
+  %t2 = trunc i64 %r5.wide to i32
+  %r5 = sext i32 %t2 to i64
+  %t3 = trunc i64 %r5 to i32
+  ret i32 %t3
+dead.main.1:
+  %t4 = trunc i64 0 to i32
+  ret i32 %t4
+}
@@ -0,0 +1,20 @@
+  .text
+  .globl main
+  .type main, @function
+main:
+  .cfi_startproc
+  push %rbp
+  .cfi_def_cfa_offset 16
+  .cfi_offset %rbp, -16
+  mov %rsp, %rbp
+  .cfi_def_cfa_register %rbp
+  mov $47, %rax
+.L.return.main:
+  .cfi_remember_state
+  pop %rbp
+  .cfi_def_cfa %rsp, 8
+  ret
+  .cfi_restore_state
+  .cfi_endproc
+  .size main, .-main
+  .section .note.GNU-stack, "", @progbits
@@ -0,0 +1,31 @@
+  .text
+  .globl main
+  .type main, @function
+main:
+  .cfi_startproc
+  push %rbp
+  .cfi_def_cfa_offset 16
+  .cfi_offset %rbp, -16
+  mov %rsp, %rbp
+  .cfi_def_cfa_register %rbp
+  mov $7, %rdi
+  mov $6, %rax
+  imul %edi, %eax
+  movslq %eax, %rax
+  mov %rax, %rdi
+  mov $5, %rax
+  add %edi, %eax
+  movslq %eax, %rax
+  jmp .L.return.main
+.L.dead.main.1:
+  mov $0, %rax
+  jmp .L.return.main
+.L.return.main:
+  .cfi_remember_state
+  pop %rbp
+  .cfi_def_cfa %rsp, 8
+  ret
+  .cfi_restore_state
+  .cfi_endproc
+  .size main, .-main
+  .section .note.GNU-stack, "", @progbits
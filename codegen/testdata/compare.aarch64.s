@@ -0,0 +1,147 @@
+  .text
+  .globl main
+  .type main, %function
+main:
+  .cfi_startproc
+  stp x29, x30, [sp, #-16]!
+  .cfi_def_cfa_offset 16
+  .cfi_offset w30, -8
+  .cfi_offset w29, -16
+  mov x29, sp
+  .cfi_def_cfa w29, 16
+  mov x9, #400
+  sub sp, sp, x9
+  mov x1, #-8
+  add x1, x29, x1
+  mov x0, #7
+  str w0, [x1]
+  mov x1, #-4
+  add x1, x29, x1
+  mov x0, #2
+  str w0, [x1]
+  mov x0, #-8
+  add x0, x29, x0
+  ldrsw x0, [x0]
+  mov x1, #7
+  cmp w0, w1
+  cset x0, eq
+  str x0, [x29, #-80]
+  mov x0, #-8
+  add x0, x29, x0
+  ldrsw x0, [x0]
+  str x0, [x29, #-96]
+  mov x0, #-4
+  add x0, x29, x0
+  ldrsw x0, [x0]
+  mov x1, x0
+  ldr x0, [x29, #-96]
+  cmp w0, w1
+  cset x0, ne
+  mov x1, #2
+  mul w0, w0, w1
+  sxtw x0, w0
+  mov x1, x0
+  ldr x0, [x29, #-80]
+  add w0, w0, w1
+  sxtw x0, w0
+  str x0, [x29, #-144]
+  mov x0, #-8
+  add x0, x29, x0
+  ldrsw x0, [x0]
+  str x0, [x29, #-160]
+  mov x0, #-4
+  add x0, x29, x0
+  ldrsw x0, [x0]
+  mov x1, x0
+  ldr x0, [x29, #-160]
+  cmp w0, w1
+  cset x0, lt
+  mov x1, #4
+  mul w0, w0, w1
+  sxtw x0, w0
+  mov x1, x0
+  ldr x0, [x29, #-144]
+  add w0, w0, w1
+  sxtw x0, w0
+  str x0, [x29, #-208]
+  mov x0, #-4
+  add x0, x29, x0
+  ldrsw x0, [x0]
+  str x0, [x29, #-224]
+  mov x0, #-8
+  add x0, x29, x0
+  ldrsw x0, [x0]
+  mov x1, x0
+  ldr x0, [x29, #-224]
+  cmp w0, w1
+  cset x0, le
+  mov x1, #8
+  mul w0, w0, w1
+  sxtw x0, w0
+  mov x1, x0
+  ldr x0, [x29, #-208]
+  add w0, w0, w1
+  sxtw x0, w0
+  mov x9, #-272
+  add x9, x29, x9
+  str x0, [x9]
+  mov x0, #-8
+  add x0, x29, x0
+  ldrsw x0, [x0]
+  mov x1, x0
+  mov x0, #7
+  cmp w0, w1
+  cset x0, le
+  mov x1, #16
+  mul w0, w0, w1
+  sxtw x0, w0
+  mov x1, x0
+  mov x9, #-272
+  add x9, x29, x9
+  ldr x0, [x9]
+  add w0, w0, w1
+  sxtw x0, w0
+  mov x9, #-328
+  add x9, x29, x9
+  str x0, [x9]
+  mov x0, #-8
+  add x0, x29, x0
+  ldrsw x0, [x0]
+  mov x9, #-344
+  add x9, x29, x9
+  str x0, [x9]
+  mov x0, #-4
+  add x0, x29, x0
+  ldrsw x0, [x0]
+  mov x1, x0
+  mov x9, #-344
+  add x9, x29, x9
+  ldr x0, [x9]
+  cmp w0, w1
+  cset x0, lt
+  mov x1, #32
+  mul w0, w0, w1
+  sxtw x0, w0
+  mov x1, x0
+  mov x9, #-328
+  add x9, x29, x9
+  ldr x0, [x9]
+  add w0, w0, w1
+  sxtw x0, w0
+  b .L.return.main
+.L.dead.main.1:
+  mov x0, #2
+  b .L.return.main
+.L.return.main:
+  .cfi_remember_state
+  mov sp, x29
+  .cfi_def_cfa sp, 16
+  ldp x29, x30, [sp], #16
+  .cfi_def_cfa_offset 0
+  .cfi_restore w30
+  .cfi_restore w29
+  ret
+  .cfi_restore_state
+  .cfi_endproc
+  .size main, .-main
+  .section .note.GNU-stack,"",%progbits
@@ -0,0 +1,108 @@
+target triple = "x86_64-pc-linux-gnu"
+
+define i32 @main() {
+entry:
+  %frame = alloca [16 x i8], align 16
+  %base = ptrtoint [16 x i8]* %frame to i64
+  %fp = add i64 %base, 16
+  %r4.slot = alloca i64
+  %r1 = add i64 %fp, -8
+  %t1 = inttoptr i64 %r1 to i32*
+  %t2 = trunc i64 7 to i32
+  store i32 %t2, i32* %t1
+  %r3 = add i64 %fp, -4
+  %r4.def = add i64 0, 2
+  store i64 %r4.def, i64* %r4.slot
+  %t3 = inttoptr i64 %r3 to i32*
+  %t5 = load i64, i64* %r4.slot
+  %t4 = trunc i64 %t5 to i32
+  store i32 %t4, i32* %t3
+  %r5 = add i64 %fp, -8
+  %t6 = inttoptr i64 %r5 to i32*
+  %t7 = load i32, i32* %t6
+  %r6 = sext i32 %t7 to i64
+  %t8 = icmp eq i64 %r6, 7
+  %r8 = zext i1 %t8 to i64
+  %r9 = add i64 %fp, -8
+  %t9 = inttoptr i64 %r9 to i32*
+  %t10 = load i32, i32* %t9
+  %r10 = sext i32 %t10 to i64
+  %r11 = add i64 %fp, -4
+  %t11 = inttoptr i64 %r11 to i32*
+  %t12 = load i32, i32* %t11
+  %r12 = sext i32 %t12 to i64
+  %t13 = icmp ne i64 %r10, %r12
+  %r13 = zext i1 %t13 to i64
+  %r15.wide = mul i64 %r13, 2
+  %t14 = trunc i64 %r15.wide to i32
+  %r15 = sext i32 %t14 to i64
+  %r16.wide = add i64 %r8, %r15
+  %t15 = trunc i64 %r16.wide to i32
+  %r16 = sext i32 %t15 to i64
+  %r17 = add i64 %fp, -8
+  %t16 = inttoptr i64 %r17 to i32*
+  %t17 = load i32, i32* %t16
+  %r18 = sext i32 %t17 to i64
+  %r19 = add i64 %fp, -4
+  %t18 = inttoptr i64 %r19 to i32*
+  %t19 = load i32, i32* %t18
+  %r20 = sext i32 %t19 to i64
+  %t20 = icmp slt i64 %r18, %r20
+  %r21 = zext i1 %t20 to i64
+  %r23.wide = mul i64 %r21, 4
+  %t21 = trunc i64 %r23.wide to i32
+  %r23 = sext i32 %t21 to i64
+  %r24.wide = add i64 %r16, %r23
+  %t22 = trunc i64 %r24.wide to i32
+  %r24 = sext i32 %t22 to i64
+  %r25 = add i64 %fp, -4
+  %t23 = inttoptr i64 %r25 to i32*
+  %t24 = load i32, i32* %t23
+  %r26 = sext i32 %t24 to i64
+  %r27 = add i64 %fp, -8
+  %t25 = inttoptr i64 %r27 to i32*
+  %t26 = load i32, i32* %t25
+  %r28 = sext i32 %t26 to i64
+  %t27 = icmp sle i64 %r26, %r28
+  %r29 = zext i1 %t27 to i64
+  %r31.wide = mul i64 %r29, 8
+  %t28 = trunc i64 %r31.wide to i32
+  %r31 = sext i32 %t28 to i64
+  %r32.wide = add i64 %r24, %r31
+  %t29 = trunc i64 %r32.wide to i32
+  %r32 = sext i32 %t29 to i64
+  %r34 = add i64 %fp, -8
+  %t30 = inttoptr i64 %r34 to i32*
+  %t31 = load i32, i32* %t30
+  %r35 = sext i32 %t31 to i64
+  %t32 = icmp sle i64 7, %r35
+  %r36 = zext i1 %t32 to i64
+  %r38.wide = mul i64 %r36, 16
+  %t33 = trunc i64 %r38.wide to i32
+  %r38 = sext i32 %t33 to i64
+  %r39.wide = add i64 %r32, %r38
+  %t34 = trunc i64 %r39.wide to i32
+  %r39 = sext i32 %t34 to i64
+  %r40 = add i64 %fp, -8
+  %t35 = inttoptr i64 %r40 to i32*
+  %t36 = load i32, i32* %t35
+  %r41 = sext i32 %t36 to i64
+  %r42 = add i64 %fp, -4
+  %t37 = inttoptr i64 %r42 to i32*
+  %t38 = load i32, i32* %t37
+  %r43 = sext i32 %t38 to i64
+  %t39 = icmp slt i64 %r41, %r43
+  %r44 = zext i1 %t39 to i64
+  %r46.wide = mul i64 %r44, 32
+  %t40 = trunc i64 %r46.wide to i32
+  %r46 = sext i32 %t40 to i64
+  %r47.wide = add i64 %r39, %r46
+  %t41 = trunc i64 %r47.wide to i32
+  %r47 = sext i32 %t41 to i64
+  %t42 = trunc i64 %r47 to i32
+  ret i32 %t42
+dead.main.1:
+  %t44 = load i64, i64* %r4.slot
+  %t43 = trunc i64 %t44 to i32
+  ret i32 %t43
+}
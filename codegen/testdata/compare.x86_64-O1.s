@@ -0,0 +1,106 @@
+  .text
+  .globl main
+  .type main, @function
+main:
+  .cfi_startproc
+  push %rbp
+  .cfi_def_cfa_offset 16
+  .cfi_offset %rbp, -16
+  mov %rsp, %rbp
+  .cfi_def_cfa_register %rbp
+  mov $7, %rax
+  mov %eax, -8(%rbp)
+  mov $2, %rax
+  mov %eax, -4(%rbp)
+  movslq -8(%rbp), %rax
+  mov $7, %rdi
+  cmp %edi, %eax
+  sete %al
+  movzb %al, %rax
+  mov %rax, -24(%rbp)
+  movslq -8(%rbp), %rax
+  mov %rax, -32(%rbp)
+  movslq -4(%rbp), %rax
+  mov %rax, %rdi
+  mov -32(%rbp), %rax
+  cmp %edi, %eax
+  setne %al
+  movzb %al, %rax
+  mov $2, %rdi
+  imul %edi, %eax
+  movslq %eax, %rax
+  mov %rax, %rdi
+  mov -24(%rbp), %rax
+  add %edi, %eax
+  movslq %eax, %rax
+  mov %rax, -40(%rbp)
+  movslq -8(%rbp), %rax
+  mov %rax, -48(%rbp)
+  movslq -4(%rbp), %rax
+  mov %rax, %rdi
+  mov -48(%rbp), %rax
+  cmp %edi, %eax
+  setl %al
+  movzb %al, %rax
+  mov $4, %rdi
+  imul %edi, %eax
+  movslq %eax, %rax
+  mov %rax, %rdi
+  mov -40(%rbp), %rax
+  add %edi, %eax
+  movslq %eax, %rax
+  mov %rax, -56(%rbp)
+  movslq -4(%rbp), %rax
+  mov %rax, -64(%rbp)
+  movslq -8(%rbp), %rax
+  mov %rax, %rdi
+  mov -64(%rbp), %rax
+  cmp %edi, %eax
+  setle %al
+  movzb %al, %rax
+  mov $8, %rdi
+  imul %edi, %eax
+  movslq %eax, %rax
+  mov %rax, %rdi
+  mov -56(%rbp), %rax
+  add %edi, %eax
+  movslq %eax, %rax
+  mov %rax, -72(%rbp)
+  movslq -8(%rbp), %rax
+  mov %rax, %rdi
+  mov $7, %rax
+  cmp %edi, %eax
+  setle %al
+  movzb %al, %rax
+  mov $16, %rdi
+  imul %edi, %eax
+  movslq %eax, %rax
+  mov %rax, %rdi
+  mov -72(%rbp), %rax
+  add %edi, %eax
+  movslq %eax, %rax
+  mov %rax, -80(%rbp)
+  movslq -8(%rbp), %rax
+  mov %rax, -88(%rbp)
+  movslq -4(%rbp), %rax
+  mov %rax, %rdi
+  mov -88(%rbp), %rax
+  cmp %edi, %eax
+  setl %al
+  movzb %al, %rax
+  mov $32, %rdi
+  imul %edi, %eax
+  movslq %eax, %rax
+  mov %rax, %rdi
+  mov -80(%rbp), %rax
+  add %edi, %eax
+  movslq %eax, %rax
+.L.return.main:
+  .cfi_remember_state
+  pop %rbp
+  .cfi_def_cfa %rsp, 8
+  ret
+  .cfi_restore_state
+  .cfi_endproc
+  .size main, .-main
+  .section .note.GNU-stack, "", @progbits
@@ -0,0 +1,64 @@
+  .text
+  .globl main
+  .type main, %function
+main:
+  .cfi_startproc
+  stp x29, x30, [sp, #-16]!
+  .cfi_def_cfa_offset 16
+  .cfi_offset w30, -8
+  .cfi_offset w29, -16
+  mov x29, sp
+  .cfi_def_cfa w29, 16
+  mov x9, #160
+  sub sp, sp, x9
+  mov x0, #7
+  neg w0, w0
+  sxtw x0, w0
+  mov x1, #-8
+  add x1, x29, x1
+  str w0, [x1]
+  mov x1, #-4
+  add x1, x29, x1
+  mov x0, #100
+  str w0, [x1]
+  mov x0, #-8
+  add x0, x29, x0
+  ldrsw x0, [x0]
+  mov x1, #2
+  sdiv w0, w0, w1
+  sxtw x0, w0
+  mov x1, x0
+  mov x0, #0
+  sub w0, w0, w1
+  sxtw x0, w0
+  str x0, [x29, #-104]
+  mov x0, #-4
+  add x0, x29, x0
+  ldrsw x0, [x0]
+  mov x1, #7
+  sdiv w0, w0, w1
+  sxtw x0, w0
+  mov x1, #2
+  sdiv w0, w0, w1
+  sxtw x0, w0
+  mov x1, x0
+  ldr x0, [x29, #-104]
+  add w0, w0, w1
+  sxtw x0, w0
+  b .L.return.main
+.L.dead.main.1:
+  mov x0, #100
+  b .L.return.main
+.L.return.main:
+  .cfi_remember_state
+  mov sp, x29
+  .cfi_def_cfa sp, 16
+  ldp x29, x30, [sp], #16
+  .cfi_def_cfa_offset 0
+  .cfi_restore w30
+  .cfi_restore w29
+  ret
+  .cfi_restore_state
+  .cfi_endproc
+  .size main, .-main
+  .section .note.GNU-stack,"",%progbits
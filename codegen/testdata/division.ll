@@ -0,0 +1,52 @@
+target triple = "x86_64-pc-linux-gnu"
+
+define i32 @main() {
+entry:
+  %frame = alloca [16 x i8], align 16
+  %base = ptrtoint [16 x i8]* %frame to i64
+  %fp = add i64 %base, 16
+  %r5.slot = alloca i64
+  %r1 = add i64 %fp, -8
+  %r3.wide = sub i64 0, 7
+  %t1 = trunc i64 %r3.wide to i32
+  %r3 = sext i32 %t1 to i64
+  %t2 = inttoptr i64 %r1 to i32*
+  %t3 = trunc i64 %r3 to i32
+  store i32 %t3, i32* %t2
+  %r4 = add i64 %fp, -4
+  %r5.def = add i64 0, 100
+  store i64 %r5.def, i64* %r5.slot
+  %t4 = inttoptr i64 %r4 to i32*
+  %t6 = load i64, i64* %r5.slot
+  %t5 = trunc i64 %t6 to i32
+  store i32 %t5, i32* %t4
+  %r7 = add i64 %fp, -8
+  %t7 = inttoptr i64 %r7 to i32*
+  %t8 = load i32, i32* %t7
+  %r8 = sext i32 %t8 to i64
+  %r10.wide = sdiv i64 %r8, 2
+  %t9 = trunc i64 %r10.wide to i32
+  %r10 = sext i32 %t9 to i64
+  %r11.wide = sub i64 0, %r10
+  %t10 = trunc i64 %r11.wide to i32
+  %r11 = sext i32 %t10 to i64
+  %r12 = add i64 %fp, -4
+  %t11 = inttoptr i64 %r12 to i32*
+  %t12 = load i32, i32* %t11
+  %r13 = sext i32 %t12 to i64
+  %r15.wide = sdiv i64 %r13, 7
+  %t13 = trunc i64 %r15.wide to i32
+  %r15 = sext i32 %t13 to i64
+  %r17.wide = sdiv i64 %r15, 2
+  %t14 = trunc i64 %r17.wide to i32
+  %r17 = sext i32 %t14 to i64
+  %r18.wide = add i64 %r11, %r17
+  %t15 = trunc i64 %r18.wide to i32
+  %r18 = sext i32 %t15 to i64
+  %t16 = trunc i64 %r18 to i32
+  ret i32 %t16
+dead.main.1:
+  %t18 = load i64, i64* %r5.slot
+  %t17 = trunc i64 %t18 to i32
+  ret i32 %t17
+}
@@ -0,0 +1,52 @@
+  .text
+  .globl main
+  .type main, @function
+main:
+  .cfi_startproc
+  push %rbp
+  .cfi_def_cfa_offset 16
+  .cfi_offset %rbp, -16
+  mov %rsp, %rbp
+  .cfi_def_cfa_register %rbp
+  mov $7, %rax
+  neg %eax
+  movslq %eax, %rax
+  mov %eax, -8(%rbp)
+  mov $100, %rax
+  mov %eax, -4(%rbp)
+  movslq -8(%rbp), %rax
+  mov $2, %rdi
+  cdq
+  idiv %edi
+  movslq %eax, %rax
+  mov %rax, %rdi
+  mov $0, %rax
+  sub %edi, %eax
+  movslq %eax, %rax
+  mov %rax, -24(%rbp)
+  movslq -4(%rbp), %rax
+  mov $7, %rdi
+  cdq
+  idiv %edi
+  movslq %eax, %rax
+  mov $2, %rdi
+  cdq
+  idiv %edi
+  movslq %eax, %rax
+  mov %rax, %rdi
+  mov -24(%rbp), %rax
+  add %edi, %eax
+  movslq %eax, %rax
+  jmp .L.return.main
+.L.dead.main.1:
+  mov $100, %rax
+  jmp .L.return.main
+.L.return.main:
+  .cfi_remember_state
+  pop %rbp
+  .cfi_def_cfa %rsp, 8
+  ret
+  .cfi_restore_state
+  .cfi_endproc
+  .size main, .-main
+  .section .note.GNU-stack, "", @progbits
@@ -0,0 +1,100 @@
+target triple = "x86_64-pc-linux-gnu"
+
+define i32 @main() {
+entry:
+  %frame = alloca [32 x i8], align 16
+  %base = ptrtoint [32 x i8]* %frame to i64
+  %fp = add i64 %base, 32
+  %r27.slot = alloca i64
+  %r1 = add i64 %fp, -20
+  %t1 = inttoptr i64 %r1 to i32*
+  %t2 = trunc i64 10 to i32
+  store i32 %t2, i32* %t1
+  %r3 = add i64 %fp, -16
+  %t3 = inttoptr i64 %r3 to i32*
+  %t4 = trunc i64 0 to i32
+  store i32 %t4, i32* %t3
+  %r5 = add i64 %fp, -12
+  %t5 = inttoptr i64 %r5 to i32*
+  %t6 = trunc i64 1 to i32
+  store i32 %t6, i32* %t5
+  %r7 = add i64 %fp, -8
+  %t7 = inttoptr i64 %r7 to i32*
+  %t8 = load i32, i32* %t7
+  %r8 = sext i32 %t8 to i64
+  %r9 = add i64 %fp, -8
+  %t9 = inttoptr i64 %r9 to i32*
+  %t10 = trunc i64 0 to i32
+  store i32 %t10, i32* %t9
+  br label %begin.main.1
+begin.main.1:
+  %r11 = add i64 %fp, -8
+  %t11 = inttoptr i64 %r11 to i32*
+  %t12 = load i32, i32* %t11
+  %r12 = sext i32 %t12 to i64
+  %r13 = add i64 %fp, -20
+  %t13 = inttoptr i64 %r13 to i32*
+  %t14 = load i32, i32* %t13
+  %r14 = sext i32 %t14 to i64
+  %t15 = icmp slt i64 %r12, %r14
+  %r15 = zext i1 %t15 to i64
+  %t16 = icmp ne i64 %r15, 0
+  br i1 %t16, label %body.main.1, label %end.main.1
+body.main.1:
+  %r16 = add i64 %fp, -4
+  %r17 = add i64 %fp, -16
+  %t17 = inttoptr i64 %r17 to i32*
+  %t18 = load i32, i32* %t17
+  %r18 = sext i32 %t18 to i64
+  %r19 = add i64 %fp, -12
+  %t19 = inttoptr i64 %r19 to i32*
+  %t20 = load i32, i32* %t19
+  %r20 = sext i32 %t20 to i64
+  %r21.wide = add i64 %r18, %r20
+  %t21 = trunc i64 %r21.wide to i32
+  %r21 = sext i32 %t21 to i64
+  %t22 = inttoptr i64 %r16 to i32*
+  %t23 = trunc i64 %r21 to i32
+  store i32 %t23, i32* %t22
+  %r22 = add i64 %fp, -16
+  %r23 = add i64 %fp, -12
+  %t24 = inttoptr i64 %r23 to i32*
+  %t25 = load i32, i32* %t24
+  %r24 = sext i32 %t25 to i64
+  %t26 = inttoptr i64 %r22 to i32*
+  %t27 = trunc i64 %r24 to i32
+  store i32 %t27, i32* %t26
+  %r25 = add i64 %fp, -12
+  %r26 = add i64 %fp, -4
+  %t28 = inttoptr i64 %r26 to i32*
+  %t29 = load i32, i32* %t28
+  %r27.def = sext i32 %t29 to i64
+  store i64 %r27.def, i64* %r27.slot
+  %t30 = inttoptr i64 %r25 to i32*
+  %t32 = load i64, i64* %r27.slot
+  %t31 = trunc i64 %t32 to i32
+  store i32 %t31, i32* %t30
+  %r28 = add i64 %fp, -8
+  %r29 = add i64 %fp, -8
+  %t33 = inttoptr i64 %r29 to i32*
+  %t34 = load i32, i32* %t33
+  %r30 = sext i32 %t34 to i64
+  %r32.wide = add i64 %r30, 1
+  %t35 = trunc i64 %r32.wide to i32
+  %r32 = sext i32 %t35 to i64
+  %t36 = inttoptr i64 %r28 to i32*
+  %t37 = trunc i64 %r32 to i32
+  store i32 %t37, i32* %t36
+  br label %begin.main.1
+end.main.1:
+  %r33 = add i64 %fp, -16
+  %t38 = inttoptr i64 %r33 to i32*
+  %t39 = load i32, i32* %t38
+  %r34 = sext i32 %t39 to i64
+  %t40 = trunc i64 %r34 to i32
+  ret i32 %t40
+dead.main.2:
+  %t42 = load i64, i64* %r27.slot
+  %t41 = trunc i64 %t42 to i32
+  ret i32 %t41
+}
@@ -0,0 +1,56 @@
+  .text
+  .globl main
+  .type main, @function
+main:
+  .cfi_startproc
+  push %rbp
+  .cfi_def_cfa_offset 16
+  .cfi_offset %rbp, -16
+  mov %rsp, %rbp
+  .cfi_def_cfa_register %rbp
+  mov $10, %rax
+  mov %eax, -20(%rbp)
+  mov $0, %rax
+  mov %eax, -16(%rbp)
+  mov $1, %rax
+  mov %eax, -12(%rbp)
+  mov $0, %rax
+  mov %eax, -8(%rbp)
+.L.begin.main.1:
+  movslq -8(%rbp), %rax
+  mov %rax, -40(%rbp)
+  movslq -20(%rbp), %rax
+  mov %rax, %rdi
+  mov -40(%rbp), %rax
+  cmp %edi, %eax
+  jge .L.end.main.1
+.L.body.main.1:
+  movslq -16(%rbp), %rax
+  mov %rax, -48(%rbp)
+  movslq -12(%rbp), %rax
+  mov %rax, %rdi
+  mov -48(%rbp), %rax
+  add %edi, %eax
+  movslq %eax, %rax
+  mov %eax, -4(%rbp)
+  movslq -12(%rbp), %rax
+  mov %eax, -16(%rbp)
+  movslq -4(%rbp), %rax
+  mov %eax, -12(%rbp)
+  movslq -8(%rbp), %rax
+  mov $1, %rdi
+  add %edi, %eax
+  movslq %eax, %rax
+  mov %eax, -8(%rbp)
+  jmp .L.begin.main.1
+.L.end.main.1:
+  movslq -16(%rbp), %rax
+.L.return.main:
+  .cfi_remember_state
+  pop %rbp
+  .cfi_def_cfa %rsp, 8
+  ret
+  .cfi_restore_state
+  .cfi_endproc
+  .size main, .-main
+  .section .note.GNU-stack, "", @progbits
@@ -0,0 +1,80 @@
+  .text
+  .globl main
+  .type main, %function
+main:
+  .cfi_startproc
+  stp x29, x30, [sp, #-16]!
+  .cfi_def_cfa_offset 16
+  .cfi_offset w30, -8
+  .cfi_offset w29, -16
+  mov x29, sp
+  .cfi_def_cfa w29, 16
+  mov x9, #208
+  sub sp, sp, x9
+  mov x1, #-8
+  add x1, x29, x1
+  mov x0, #0
+  str w0, [x1]
+  mov x1, #-4
+  add x1, x29, x1
+  mov x0, #0
+  str w0, [x1]
+  mov x1, #-8
+  add x1, x29, x1
+  mov x0, #0
+  str w0, [x1]
+.L.begin.main.1:
+  mov x0, #-8
+  add x0, x29, x0
+  ldrsw x0, [x0]
+  mov x1, #10
+  cmp w0, w1
+  b.gt .L.end.main.1
+.L.body.main.1:
+  mov x0, #-8
+  add x0, x29, x0
+  ldrsw x0, [x0]
+  str x0, [x29, #-120]
+  mov x0, #-4
+  add x0, x29, x0
+  ldrsw x0, [x0]
+  mov x1, x0
+  ldr x0, [x29, #-120]
+  add w0, w0, w1
+  sxtw x0, w0
+  str x0, [x29, #-144]
+  mov x1, #-4
+  add x1, x29, x1
+  ldr x0, [x29, #-144]
+  str w0, [x1]
+  mov x0, #-8
+  add x0, x29, x0
+  ldrsw x0, [x0]
+  mov x1, #1
+  add w0, w0, w1
+  sxtw x0, w0
+  mov x1, #-8
+  add x1, x29, x1
+  str w0, [x1]
+  b .L.begin.main.1
+.L.end.main.1:
+  mov x0, #-4
+  add x0, x29, x0
+  ldrsw x0, [x0]
+  b .L.return.main
+.L.dead.main.2:
+  ldr x0, [x29, #-144]
+  b .L.return.main
+.L.return.main:
+  .cfi_remember_state
+  mov sp, x29
+  .cfi_def_cfa sp, 16
+  ldp x29, x30, [sp], #16
+  .cfi_def_cfa_offset 0
+  .cfi_restore w30
+  .cfi_restore w29
+  ret
+  .cfi_restore_state
+  .cfi_endproc
+  .size main, .-main
+  .section .note.GNU-stack,"",%progbits
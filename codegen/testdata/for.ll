@@ -0,0 +1,72 @@
+target triple = "x86_64-pc-linux-gnu"
+
+define i32 @main() {
+entry:
+  %frame = alloca [16 x i8], align 16
+  %base = ptrtoint [16 x i8]* %frame to i64
+  %fp = add i64 %base, 16
+  %r16.slot = alloca i64
+  %r1 = add i64 %fp, -8
+  %t1 = inttoptr i64 %r1 to i32*
+  %t2 = trunc i64 0 to i32
+  store i32 %t2, i32* %t1
+  %r3 = add i64 %fp, -4
+  %t3 = inttoptr i64 %r3 to i32*
+  %t4 = trunc i64 0 to i32
+  store i32 %t4, i32* %t3
+  %r5 = add i64 %fp, -8
+  %t5 = inttoptr i64 %r5 to i32*
+  %t6 = trunc i64 0 to i32
+  store i32 %t6, i32* %t5
+  br label %begin.main.1
+begin.main.1:
+  %r7 = add i64 %fp, -8
+  %t7 = inttoptr i64 %r7 to i32*
+  %t8 = load i32, i32* %t7
+  %r8 = sext i32 %t8 to i64
+  %t9 = icmp sle i64 %r8, 10
+  %r10 = zext i1 %t9 to i64
+  %t10 = icmp ne i64 %r10, 0
+  br i1 %t10, label %body.main.1, label %end.main.1
+body.main.1:
+  %r11 = add i64 %fp, -4
+  %r12 = add i64 %fp, -8
+  %t11 = inttoptr i64 %r12 to i32*
+  %t12 = load i32, i32* %t11
+  %r13 = sext i32 %t12 to i64
+  %r14 = add i64 %fp, -4
+  %t13 = inttoptr i64 %r14 to i32*
+  %t14 = load i32, i32* %t13
+  %r15 = sext i32 %t14 to i64
+  %r16.wide = add i64 %r13, %r15
+  %t15 = trunc i64 %r16.wide to i32
+  %r16.def = sext i32 %t15 to i64
+  store i64 %r16.def, i64* %r16.slot
+  %t16 = inttoptr i64 %r11 to i32*
+  %t18 = load i64, i64* %r16.slot
+  %t17 = trunc i64 %t18 to i32
+  store i32 %t17, i32* %t16
+  %r17 = add i64 %fp, -8
+  %r18 = add i64 %fp, -8
+  %t19 = inttoptr i64 %r18 to i32*
+  %t20 = load i32, i32* %t19
+  %r19 = sext i32 %t20 to i64
+  %r21.wide = add i64 %r19, 1
+  %t21 = trunc i64 %r21.wide to i32
+  %r21 = sext i32 %t21 to i64
+  %t22 = inttoptr i64 %r17 to i32*
+  %t23 = trunc i64 %r21 to i32
+  store i32 %t23, i32* %t22
+  br label %begin.main.1
+end.main.1:
+  %r22 = add i64 %fp, -4
+  %t24 = inttoptr i64 %r22 to i32*
+  %t25 = load i32, i32* %t24
+  %r23 = sext i32 %t25 to i64
+  %t26 = trunc i64 %r23 to i32
+  ret i32 %t26
+dead.main.2:
+  %t28 = load i64, i64* %r16.slot
+  %t27 = trunc i64 %t28 to i32
+  ret i32 %t27
+}
@@ -0,0 +1,53 @@
+  .text
+  .globl main
+  .type main, @function
+main:
+  .cfi_startproc
+  push %rbp
+  .cfi_def_cfa_offset 16
+  .cfi_offset %rbp, -16
+  mov %rsp, %rbp
+  .cfi_def_cfa_register %rbp
+  mov $0, %rax
+  mov %eax, -8(%rbp)
+  mov $0, %rax
+  mov %eax, -4(%rbp)
+  mov $0, %rax
+  mov %eax, -8(%rbp)
+.L.begin.main.1:
+  movslq -8(%rbp), %rax
+  mov $10, %rdi
+  cmp %edi, %eax
+  jg .L.end.main.1
+.L.body.main.1:
+  movslq -8(%rbp), %rax
+  mov %rax, -24(%rbp)
+  movslq -4(%rbp), %rax
+  mov %rax, %rdi
+  mov -24(%rbp), %rax
+  add %edi, %eax
+  movslq %eax, %rax
+  mov %rax, -32(%rbp)
+  mov -32(%rbp), %rax
+  mov %eax, -4(%rbp)
+  movslq -8(%rbp), %rax
+  mov $1, %rdi
+  add %edi, %eax
+  movslq %eax, %rax
+  mov %eax, -8(%rbp)
+  jmp .L.begin.main.1
+.L.end.main.1:
+  movslq -4(%rbp), %rax
+  jmp .L.return.main
+.L.dead.main.2:
+  mov -32(%rbp), %rax
+  jmp .L.return.main
+.L.return.main:
+  .cfi_remember_state
+  pop %rbp
+  .cfi_def_cfa %rsp, 8
+  ret
+  .cfi_restore_state
+  .cfi_endproc
+  .size main, .-main
+  .section .note.GNU-stack, "", @progbits
@@ -0,0 +1,66 @@
+target triple = "x86_64-pc-linux-gnu"
+
+define i32 @main() {
+entry:
+  %frame = alloca [16 x i8], align 16
+  %base = ptrtoint [16 x i8]* %frame to i64
+  %fp = add i64 %base, 16
+  %r17.slot = alloca i64
+  %r1 = add i64 %fp, -12
+  %t1 = inttoptr i64 %r1 to i32*
+  %t2 = trunc i64 3 to i32
+  store i32 %t2, i32* %t1
+  %r3 = add i64 %fp, -8
+  %t3 = inttoptr i64 %r3 to i32*
+  %t4 = trunc i64 5 to i32
+  store i32 %t4, i32* %t3
+  %r5 = add i64 %fp, -4
+  %t5 = inttoptr i64 %r5 to i32*
+  %t6 = load i32, i32* %t5
+  %r6 = sext i32 %t6 to i64
+  %r7 = add i64 %fp, -8
+  %t7 = inttoptr i64 %r7 to i32*
+  %t8 = load i32, i32* %t7
+  %r8 = sext i32 %t8 to i64
+  %r9 = add i64 %fp, -12
+  %t9 = inttoptr i64 %r9 to i32*
+  %t10 = load i32, i32* %t9
+  %r10 = sext i32 %t10 to i64
+  %t11 = icmp slt i64 %r8, %r10
+  %r11 = zext i1 %t11 to i64
+  %t12 = icmp ne i64 %r11, 0
+  br i1 %t12, label %then.main.1, label %else.main.1
+then.main.1:
+  %r12 = add i64 %fp, -4
+  %r13 = add i64 %fp, -12
+  %t13 = inttoptr i64 %r13 to i32*
+  %t14 = load i32, i32* %t13
+  %r14 = sext i32 %t14 to i64
+  %t15 = inttoptr i64 %r12 to i32*
+  %t16 = trunc i64 %r14 to i32
+  store i32 %t16, i32* %t15
+  br label %end.main.1
+else.main.1:
+  %r15 = add i64 %fp, -4
+  %r16 = add i64 %fp, -8
+  %t17 = inttoptr i64 %r16 to i32*
+  %t18 = load i32, i32* %t17
+  %r17.def = sext i32 %t18 to i64
+  store i64 %r17.def, i64* %r17.slot
+  %t19 = inttoptr i64 %r15 to i32*
+  %t21 = load i64, i64* %r17.slot
+  %t20 = trunc i64 %t21 to i32
+  store i32 %t20, i32* %t19
+  br label %end.main.1
+end.main.1:
+  %r18 = add i64 %fp, -4
+  %t22 = inttoptr i64 %r18 to i32*
+  %t23 = load i32, i32* %t22
+  %r19 = sext i32 %t23 to i64
+  %t24 = trunc i64 %r19 to i32
+  ret i32 %t24
+dead.main.2:
+  %t26 = load i64, i64* %r17.slot
+  %t25 = trunc i64 %t26 to i32
+  ret i32 %t25
+}
@@ -0,0 +1,39 @@
+  .text
+  .globl main
+  .type main, @function
+main:
+  .cfi_startproc
+  push %rbp
+  .cfi_def_cfa_offset 16
+  .cfi_offset %rbp, -16
+  mov %rsp, %rbp
+  .cfi_def_cfa_register %rbp
+  mov $3, %rax
+  mov %eax, -12(%rbp)
+  mov $5, %rax
+  mov %eax, -8(%rbp)
+  movslq -8(%rbp), %rax
+  mov %rax, -24(%rbp)
+  movslq -12(%rbp), %rax
+  mov %rax, %rdi
+  mov -24(%rbp), %rax
+  cmp %edi, %eax
+  jge .L.else.main.1
+.L.then.main.1:
+  movslq -12(%rbp), %rax
+  mov %eax, -4(%rbp)
+  jmp .L.end.main.1
+.L.else.main.1:
+  movslq -8(%rbp), %rax
+  mov %eax, -4(%rbp)
+.L.end.main.1:
+  movslq -4(%rbp), %rax
+.L.return.main:
+  .cfi_remember_state
+  pop %rbp
+  .cfi_def_cfa %rsp, 8
+  ret
+  .cfi_restore_state
+  .cfi_endproc
+  .size main, .-main
+  .section .note.GNU-stack, "", @progbits
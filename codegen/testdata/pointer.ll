@@ -0,0 +1,42 @@
+target triple = "x86_64-pc-linux-gnu"
+
+define i32 @main() {
+entry:
+  %frame = alloca [32 x i8], align 16
+  %base = ptrtoint [32 x i8]* %frame to i64
+  %fp = add i64 %base, 32
+  %r10.slot = alloca i64
+  %r1 = add i64 %fp, -20
+  %t1 = inttoptr i64 %r1 to i32*
+  %t2 = trunc i64 3 to i32
+  store i32 %t2, i32* %t1
+  %r3 = add i64 %fp, -16
+  %r4 = add i64 %fp, -20
+  %t3 = inttoptr i64 %r3 to i64*
+  store i64 %r4, i64* %t3
+  %r5 = add i64 %fp, -8
+  %r6 = add i64 %fp, -16
+  %t4 = inttoptr i64 %r5 to i64*
+  store i64 %r6, i64* %t4
+  %r7 = add i64 %fp, -8
+  %t5 = inttoptr i64 %r7 to i64*
+  %r8 = load i64, i64* %t5
+  %t6 = inttoptr i64 %r8 to i64*
+  %r9 = load i64, i64* %t6
+  %r10.def = add i64 0, 5
+  store i64 %r10.def, i64* %r10.slot
+  %t7 = inttoptr i64 %r9 to i32*
+  %t9 = load i64, i64* %r10.slot
+  %t8 = trunc i64 %t9 to i32
+  store i32 %t8, i32* %t7
+  %r11 = add i64 %fp, -20
+  %t10 = inttoptr i64 %r11 to i32*
+  %t11 = load i32, i32* %t10
+  %r12 = sext i32 %t11 to i64
+  %t12 = trunc i64 %r12 to i32
+  ret i32 %t12
+dead.main.1:
+  %t14 = load i64, i64* %r10.slot
+  %t13 = trunc i64 %t14 to i32
+  ret i32 %t13
+}
@@ -0,0 +1,35 @@
+  .text
+  .globl main
+  .type main, @function
+main:
+  .cfi_startproc
+  push %rbp
+  .cfi_def_cfa_offset 16
+  .cfi_offset %rbp, -16
+  mov %rsp, %rbp
+  .cfi_def_cfa_register %rbp
+  mov $3, %rax
+  mov %eax, -20(%rbp)
+  lea -20(%rbp), %rax
+  mov %rax, -16(%rbp)
+  lea -16(%rbp), %rax
+  mov %rax, -8(%rbp)
+  mov -8(%rbp), %rax
+  mov (%rax), %rax
+  mov %rax, %rdi
+  mov $5, %rax
+  mov %eax, (%rdi)
+  movslq -20(%rbp), %rax
+  jmp .L.return.main
+.L.dead.main.1:
+  mov $5, %rax
+  jmp .L.return.main
+.L.return.main:
+  .cfi_remember_state
+  pop %rbp
+  .cfi_def_cfa %rsp, 8
+  ret
+  .cfi_restore_state
+  .cfi_endproc
+  .size main, .-main
+  .section .note.GNU-stack, "", @progbits
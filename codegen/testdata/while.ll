@@ -0,0 +1,49 @@
+target triple = "x86_64-pc-linux-gnu"
+
+define i32 @main() {
+entry:
+  %frame = alloca [16 x i8], align 16
+  %base = ptrtoint [16 x i8]* %frame to i64
+  %fp = add i64 %base, 16
+  %r11.slot = alloca i64
+  %r1 = add i64 %fp, -4
+  %t1 = inttoptr i64 %r1 to i32*
+  %t2 = trunc i64 0 to i32
+  store i32 %t2, i32* %t1
+  br label %begin.main.1
+begin.main.1:
+  %r3 = add i64 %fp, -4
+  %t3 = inttoptr i64 %r3 to i32*
+  %t4 = load i32, i32* %t3
+  %r4 = sext i32 %t4 to i64
+  %t5 = icmp slt i64 %r4, 9
+  %r6 = zext i1 %t5 to i64
+  %t6 = icmp ne i64 %r6, 0
+  br i1 %t6, label %body.main.1, label %end.main.1
+body.main.1:
+  %r7 = add i64 %fp, -4
+  %r8 = add i64 %fp, -4
+  %t7 = inttoptr i64 %r8 to i32*
+  %t8 = load i32, i32* %t7
+  %r9 = sext i32 %t8 to i64
+  %r11.wide = add i64 %r9, 1
+  %t9 = trunc i64 %r11.wide to i32
+  %r11.def = sext i32 %t9 to i64
+  store i64 %r11.def, i64* %r11.slot
+  %t10 = inttoptr i64 %r7 to i32*
+  %t12 = load i64, i64* %r11.slot
+  %t11 = trunc i64 %t12 to i32
+  store i32 %t11, i32* %t10
+  br label %begin.main.1
+end.main.1:
+  %r12 = add i64 %fp, -4
+  %t13 = inttoptr i64 %r12 to i32*
+  %t14 = load i32, i32* %t13
+  %r13 = sext i32 %t14 to i64
+  %t15 = trunc i64 %r13 to i32
+  ret i32 %t15
+dead.main.2:
+  %t17 = load i64, i64* %r11.slot
+  %t16 = trunc i64 %t17 to i32
+  ret i32 %t16
+}
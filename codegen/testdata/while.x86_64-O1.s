@@ -0,0 +1,35 @@
+  .text
+  .globl main
+  .type main, @function
+main:
+  .cfi_startproc
+  push %rbp
+  .cfi_def_cfa_offset 16
+  .cfi_offset %rbp, -16
+  mov %rsp, %rbp
+  .cfi_def_cfa_register %rbp
+  mov $0, %rax
+  mov %eax, -4(%rbp)
+.L.begin.main.1:
+  movslq -4(%rbp), %rax
+  mov $9, %rdi
+  cmp %edi, %eax
+  jge .L.end.main.1
+.L.body.main.1:
+  movslq -4(%rbp), %rax
+  mov $1, %rdi
+  add %edi, %eax
+  movslq %eax, %rax
+  mov %eax, -4(%rbp)
+  jmp .L.begin.main.1
+.L.end.main.1:
+  movslq -4(%rbp), %rax
+.L.return.main:
+  .cfi_remember_state
+  pop %rbp
+  .cfi_def_cfa %rsp, 8
+  ret
+  .cfi_restore_state
+  .cfi_endproc
+  .size main, .-main
+  .section .note.GNU-stack, "", @progbits
@@ -0,0 +1,65 @@
+package codegen
+
+import (
+	"strings"
+
+	"github.com/youngfr/gocc/parser"
+	"github.com/youngfr/gocc/token"
+)
+
+// Source annotations
+//
+// With -fverbose-asm, each statement and loop condition is preceded in
+// the output by a comment holding its source text. Nodes only keep a
+// representative token, and folding may replace whole subtrees, so the
+// text is recovered by scanning the source from the token starting a
+// statement. The language has no string literals or comma operator,
+// which makes the scan straightforward.
+
+// Return the index of the first byte of `source` from `begin` which is
+// either in `stops` and outside of parentheses, or an unbalanced `)`.
+func scan(source string, begin int, stops string) int {
+	depth := 0
+	for i := begin; i < len(source); i++ {
+		switch c := source[i]; {
+		case c == '(':
+			depth++
+		case c == ')' && depth == 0:
+			return i
+		case c == ')':
+			depth--
+		case depth == 0 && strings.IndexByte(stops, c) >= 0:
+			return i
+		}
+	}
+	return len(source)
+}
+
+// Return the source text starting at `tok`
+// up to the `;` or `,` ending it.
+func sourceText(tok *token.Token) string {
+	source := tok.File.Contents
+	return oneLine(source[tok.Begin:scan(source, tok.Begin, ";,")])
+}
+
+// Return the header of an if, for or while statement: its keyword
+// followed by everything up to the matching closing parenthesis.
+func headerText(node *parser.Node) string {
+	source, begin := node.Token.File.Contents, node.Token.Begin
+	open := begin + strings.IndexByte(source[begin:], '(')
+	end := scan(source, open+1, "")
+	if end < len(source) {
+		end++
+	}
+	return oneLine(source[begin:end])
+}
+
+// Return the increment of a for statement.
+func incrementText(node *parser.Node) string {
+	header := headerText(node)
+	return strings.TrimSpace(header[strings.LastIndexByte(header, ';')+1 : len(header)-1])
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
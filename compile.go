@@ -0,0 +1,57 @@
+package gocc
+
+import (
+	"bytes"
+
+	"github.com/youngfr/gocc/codegen"
+	"github.com/youngfr/gocc/lexer"
+	"github.com/youngfr/gocc/parser"
+	"github.com/youngfr/gocc/token"
+)
+
+// Name of the file of the program given to Compile, in its diagnostics.
+const CompileFilename = "<input>"
+
+// Compile the program `src` with `cfg` in a session of its own, like
+// "gocc -S" does. Return the assembly, or the LLVM IR with EmitLLVM or
+// the QBE IL with EmitQBE, and the errors and warnings of the program,
+// in source order. The error is a token.ErrorList if the program has
+// errors, in which case there is no assembly, or reports an invalid
+// configuration.
+func Compile(src string, cfg Config) (asm []byte, diags []*token.Diagnostic, err error) {
+	return compile(src, CompileFilename, 1, cfg)
+}
+
+// Compile `src` like Compile, as the file `name` starting at its `line`.
+func compile(src string, name string, line int, cfg Config) ([]byte, []*token.Diagnostic, error) {
+	session, err := cfg.NewSession()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer session.Free()
+	var out bytes.Buffer
+	backend, err := cfg.NewBackend(&out)
+	if err != nil {
+		return nil, nil, err
+	}
+	reporter := &token.MemoryReporter{}
+	session.Reporter = reporter
+	file := session.AddFile(name, "", src)
+	if line != 1 {
+		file.AddLineInfo(0, name, line)
+	}
+	// The parser reports the errors of the lexer with its own.
+	tok, _ := lexer.Tokenize(file)
+	program, err := parser.Parse(tok)
+	if err == nil {
+		err = codegen.RunASTPasses(program, &cfg.Options)
+	}
+	if err == nil {
+		err = backend.Gen(program)
+	}
+	file.Report()
+	if err != nil {
+		return nil, reporter.Diagnostics, err
+	}
+	return out.Bytes(), reporter.Diagnostics, nil
+}
@@ -0,0 +1,44 @@
+package gocc
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/youngfr/gocc/token"
+)
+
+func TestCompile(t *testing.T) {
+	asm, diags, err := Compile("int a; return 42;", Config{Warnings: []string{"all"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(asm), "main") {
+		t.Errorf("no main in the assembly:\n%s", asm)
+	}
+	if len(diags) != 1 || diags[0].Severity != token.SeverityWarning || diags[0].Position().String() != CompileFilename+":1:5" {
+		t.Errorf("diagnostics %v, want the unused variable", diags)
+	}
+
+	asm, diags, err = Compile("int a = $;\nreturn b;\n", Config{})
+	var errs token.ErrorList
+	if !errors.As(err, &errs) || len(errs) != 3 || errs.ExitStatus() != token.ExitLexical {
+		t.Errorf("error %v, want the lexical and syntax errors", err)
+	}
+	if asm != nil || len(diags) != 3 {
+		t.Errorf("assembly %q and %d diagnostics for a program with errors", asm, len(diags))
+	}
+
+	if _, _, err := Compile("return 0;", Config{Target: "pdp11"}); err == nil {
+		t.Error("no error for an unknown target")
+	}
+	if asm, _, err := Compile("return 0;", Config{Target: "x86_64-linux", EmitLLVM: true}); err != nil || !strings.Contains(string(asm), "define") {
+		t.Errorf("no LLVM IR with EmitLLVM: %v", err)
+	}
+	if asm, _, err := Compile("return 0;", Config{Target: "aarch64-linux", EmitQBE: true}); err != nil || !strings.Contains(string(asm), "export function w $main()") {
+		t.Errorf("no QBE IL with EmitQBE: %v", err)
+	}
+	if _, _, err := Compile("return 0;", Config{Target: "wasm32", EmitQBE: true}); err == nil {
+		t.Error("no error for EmitQBE with a target QBE does not support")
+	}
+}
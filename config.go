@@ -0,0 +1,127 @@
+// Package gocc is the interface of the compiler for programs embedding
+// it, on top of the packages of its phases.
+package gocc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/youngfr/gocc/codegen"
+	"github.com/youngfr/gocc/token"
+)
+
+// Configuration
+//
+// A Config holds what the command line configures about a compilation,
+// so that programs embedding gocc set one up the same way without
+// knowing which phase reads what. Each phase is given its part: Apply
+// configures the session the lexer and the parser read files in, and
+// NewBackend creates the backend of the target with the options of the
+// code generator. The zero value selects the defaults of the command
+// line.
+
+// Target compiled for when a Config does not select one.
+const DefaultTarget = "x86_64-linux"
+
+type Config struct {
+	// Target triple selected with -target, a key of codegen.Targets,
+	// or "" for DefaultTarget.
+	Target string
+
+	// Whether -emit-llvm was given, which emits LLVM IR for the
+	// target instead of assembly.
+	EmitLLVM bool
+
+	// Whether -emit-qbe was given, which emits QBE IL for the target
+	// instead of assembly, see codegen.QBETargets.
+	EmitQBE bool
+
+	// Options of the code generator, such as the optimization
+	// level selected with -O and whether -fPIC was given.
+	codegen.Options
+
+	// Language standard selected with -std=, a key of
+	// token.Standards, or "" for that of the session.
+	Standard string
+
+	// Whether -funsigned-char was given, which makes plain char
+	// unsigned. There is no char type yet, so it changes nothing.
+	UnsignedChar bool
+
+	// Warning options, without their -W, such as "all", "no-shadow" or
+	// "error". They are applied in order after the default warnings.
+	Warnings []string
+
+	// Whether -w was given, which disables all warnings.
+	SuppressWarnings bool
+
+	// Directories given with -I. There is no #include yet, so they
+	// are not searched.
+	IncludePaths []string
+
+	// Options -D and -U, such as "-DNDEBUG" or "-UX", in order. There
+	// are no macros yet, so they only matter to an external preprocessor.
+	Macros []string
+
+	// Where --trace-parse logs entering and leaving each grammar
+	// function of the parser, or nil without it.
+	Trace io.Writer
+}
+
+// Return the target triple of the configuration.
+func (c *Config) TargetTriple() string {
+	if c.Target == "" {
+		return DefaultTarget
+	}
+	return c.Target
+}
+
+// Configure `s` for the lexer and the parser: select the standard, the
+// warnings and where the parser is traced to. Return an error for an unknown standard or warning.
+func (c *Config) Apply(s *token.Session) error {
+	if c.Standard != "" {
+		if _, ok := token.Standards[c.Standard]; !ok {
+			return errors.New(s.Tr("unrecognized command-line option \"%s\"", "-std="+c.Standard))
+		}
+		s.Standard = c.Standard
+	}
+	for _, option := range c.Warnings {
+		if !s.SetWarning(option) {
+			return errors.New(s.Tr("unknown warning option \"%s\"", "-W"+option))
+		}
+	}
+	if c.SuppressWarnings {
+		s.SuppressWarnings = true
+	}
+	if c.Trace != nil {
+		s.Trace = c.Trace
+	}
+	return nil
+}
+
+// Return a fresh session with the defaults of the command line,
+// configured by Apply.
+func (c *Config) NewSession() (*token.Session, error) {
+	s := token.NewSession()
+	if err := c.Apply(s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Return a fresh backend for the target writing to `out`, or an
+// error if the target is unknown, or has no QBE target with EmitQBE.
+func (c *Config) NewBackend(out io.Writer) (codegen.Backend, error) {
+	target := c.TargetTriple()
+	if _, ok := codegen.Targets[target]; !ok {
+		return nil, fmt.Errorf("unknown target \"%s\"", target)
+	}
+	if c.EmitQBE {
+		if _, ok := codegen.QBETargets[target]; !ok {
+			return nil, fmt.Errorf("target \"%s\" does not support -emit-qbe", target)
+		}
+		return codegen.NewQBE(out, &c.Options), nil
+	}
+	return codegen.NewBackend(target, c.EmitLLVM, out, &c.Options), nil
+}
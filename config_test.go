@@ -0,0 +1,34 @@
+package gocc
+
+import (
+	"io"
+	"testing"
+)
+
+func TestConfig(t *testing.T) {
+	cfg := Config{Standard: "c99", Warnings: []string{"all", "no-unused-variable", "error"}, Trace: io.Discard}
+	s, err := cfg.NewSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Standard != "c99" {
+		t.Errorf("standard %s, want c99", s.Standard)
+	}
+	if s.Warnings["unused-variable"] || !s.Warnings["unused-but-set-variable"] || !s.WarningsAsErrors {
+		t.Errorf("warnings not applied in order: %v, as errors %v", s.Warnings, s.WarningsAsErrors)
+	}
+	if s.Trace != io.Discard {
+		t.Errorf("parser traced to %v, want io.Discard", s.Trace)
+	}
+	for _, bad := range []Config{{Standard: "c2x"}, {Warnings: []string{"no-such-warning"}}} {
+		if _, err := bad.NewSession(); err == nil {
+			t.Errorf("no error for %+v", bad)
+		}
+	}
+	if _, err := (&Config{}).NewBackend(io.Discard); err != nil {
+		t.Errorf("default target: %v", err)
+	}
+	if _, err := (&Config{Target: "pdp11"}).NewBackend(io.Discard); err == nil {
+		t.Error("no error for an unknown target")
+	}
+}
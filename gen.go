@@ -0,0 +1,148 @@
+package gocc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	goparser "go/parser"
+	"go/printer"
+	gotoken "go/token"
+	"io"
+	"strconv"
+
+	"github.com/youngfr/gocc/asm"
+	"github.com/youngfr/gocc/token"
+)
+
+// Generating Go
+//
+// Generate writes a Go file embedding C programs compiled when it is
+// generated, for Go projects using gocc in their code generation step,
+// typically through "gocc gen" and go:generate. Each snippet becomes a
+// declaration of the package named by the snippet:
+//
+//	const Name = "..."       // SnippetAsm: the assembly
+//	var Name = []byte("...") // SnippetObject: the ELF object file
+//	func Name() int32 {...}  // SnippetGo: the program in Go, see the go target
+//
+// Objects are assembled by the asm package, so only for the targets it
+// supports, and the same sources always give the same file. Programs in
+// Go share helpers, whose names start with "gocc" so as not to clash
+// with the rest of the package.
+
+// Kinds of Snippet.
+const (
+	SnippetAsm    = "asm"
+	SnippetObject = "object"
+	SnippetGo     = "go"
+)
+
+// A C program to embed in Go source with Generate.
+type Snippet struct {
+	Name   string // Go identifier of the declaration
+	Kind   string // SnippetAsm, SnippetObject or SnippetGo
+	Source string
+
+	// File and line the source starts at, in diagnostics
+	// and comments, or CompileFilename and 1.
+	Filename string
+	Line     int
+}
+
+// Helpers of the go target, and their names in generated files.
+var goHelperNames = map[string]string{
+	"b2i":    "goccB2i",
+	"setInt": "goccSetInt",
+	"setPtr": "goccSetPtr",
+}
+
+// Write to `out` the Go file of package `pkg` declaring the `snippets`
+// compiled with `cfg`, whose name is given in the header, such as
+// "gocc gen". Return the errors and warnings of the snippets, and an
+// error if one has errors or cannot be compiled as asked.
+func Generate(out io.Writer, pkg string, generator string, snippets []Snippet, cfg Config) ([]*token.Diagnostic, error) {
+	var diags []*token.Diagnostic
+	var decls bytes.Buffer
+	var helpers bytes.Buffer // Helpers of the go target, once
+	for _, s := range snippets {
+		if s.Filename == "" {
+			s.Filename, s.Line = CompileFilename, 1
+		}
+		c := cfg
+		if s.Kind == SnippetGo {
+			c.Target, c.EmitLLVM, c.EmitQBE = "go", false, false
+		}
+		src, d, err := compile(s.Source, s.Filename, s.Line, c)
+		diags = append(diags, d...)
+		if err != nil {
+			return diags, err
+		}
+		switch s.Kind {
+		case SnippetAsm:
+			fmt.Fprintf(&decls, "// %s is the assembly of the program of %s:%d, for %s.\n", s.Name, s.Filename, s.Line, c.TargetTriple())
+			fmt.Fprintf(&decls, "const %s = %s\n\n", s.Name, strconv.Quote(string(src)))
+		case SnippetObject:
+			if t := c.TargetTriple(); t != "x86_64-linux" && t != "amd64-linux" || c.EmitLLVM || c.EmitQBE {
+				return diags, fmt.Errorf("%s: cannot assemble an object for target \"%s\"", s.Name, c.TargetTriple())
+			}
+			obj, err := asm.Assemble(string(src))
+			if err != nil {
+				return diags, fmt.Errorf("%s: %v", s.Name, err)
+			}
+			fmt.Fprintf(&decls, "// %s is the object file of the program of %s:%d, for %s.\n", s.Name, s.Filename, s.Line, c.TargetTriple())
+			fmt.Fprintf(&decls, "var %s = []byte(%s)\n\n", s.Name, strconv.Quote(string(obj)))
+		case SnippetGo:
+			fset := gotoken.NewFileSet()
+			file, err := goparser.ParseFile(fset, "", src, goparser.ParseComments)
+			if err != nil {
+				return diags, err
+			}
+			renameGoHelpers(file)
+			first := helpers.Len() == 0
+			for _, decl := range file.Decls {
+				fn, ok := decl.(*ast.FuncDecl)
+				switch {
+				case !ok || fn.Name.Name == "main":
+				case fn.Name.Name == "program":
+					fn.Name.Name, fn.Doc = s.Name, nil
+					fmt.Fprintf(&decls, "// %s runs the program of %s:%d, and returns its exit status.\n", s.Name, s.Filename, s.Line)
+					format.Node(&decls, fset, &printer.CommentedNode{Node: fn, Comments: file.Comments})
+					decls.WriteString("\n\n")
+				case first:
+					format.Node(&helpers, fset, &printer.CommentedNode{Node: fn, Comments: file.Comments})
+					helpers.WriteString("\n\n")
+				}
+			}
+		default:
+			return diags, fmt.Errorf("%s: unknown kind \"%s\"", s.Name, s.Kind)
+		}
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by %s. DO NOT EDIT.\n\npackage %s\n\n", generator, pkg)
+	if helpers.Len() > 0 {
+		b.WriteString("import \"unsafe\"\n\n")
+	}
+	b.Write(decls.Bytes())
+	b.Write(helpers.Bytes())
+	formatted, err := format.Source(b.Bytes())
+	if err != nil {
+		return diags, errors.New("cannot format the generated file: " + err.Error())
+	}
+	_, err = out.Write(formatted)
+	return diags, err
+}
+
+// Rename the helpers of the go target in `file`. Locals never take
+// their names, see the backend.
+func renameGoHelpers(file *ast.File) {
+	ast.Inspect(file, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok {
+			if name, ok := goHelperNames[id.Name]; ok {
+				id.Name = name
+			}
+		}
+		return true
+	})
+}
@@ -0,0 +1,51 @@
+package gocc
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	snippets := []Snippet{
+		{Name: "Answer", Kind: SnippetGo, Source: "int a; a = 6; return a * 7 == 42;"},
+		{Name: "AnswerAsm", Kind: SnippetAsm, Source: "return 42;"},
+		{Name: "AnswerObj", Kind: SnippetObject, Source: "return 42;"},
+		{Name: "Twice", Kind: SnippetGo, Source: "int b = 1; return b + b;"},
+	}
+	var out bytes.Buffer
+	if _, err := Generate(&out, "answers", "a test", snippets, Config{}); err != nil {
+		t.Fatal(err)
+	}
+	src := out.String()
+	file, err := parser.ParseFile(token.NewFileSet(), "", src, 0)
+	if err != nil {
+		t.Fatalf("%v in\n%s", err, src)
+	}
+	for _, want := range []string{"Answer", "AnswerAsm", "AnswerObj", "Twice", "goccB2i"} {
+		if file.Scope.Lookup(want) == nil {
+			t.Errorf("%s is not declared in\n%s", want, src)
+		}
+	}
+	if !strings.HasPrefix(src, "// Code generated by a test. DO NOT EDIT.\n\npackage answers\n") ||
+		strings.Count(src, "func goccB2i") != 1 || !strings.Contains(src, `const AnswerAsm = "  .text\n`) ||
+		!strings.Contains(src, `var AnswerObj = []byte("\x7fELF`) {
+		t.Errorf("generated\n%s", src)
+	}
+
+	out.Reset()
+	if _, err := Generate(&out, "p", "a test", snippets[1:2], Config{}); err != nil || strings.Contains(out.String(), "unsafe") {
+		t.Errorf("generated\n%s\nwith %v", out.String(), err)
+	}
+	diags, err := Generate(&out, "p", "a test", []Snippet{{Name: "X", Kind: SnippetAsm, Source: "\nreturn x;", Filename: "p.go", Line: 10}}, Config{})
+	if err == nil || len(diags) != 1 || diags[0].Error() != "p.go:11:8: error: undefined variable" {
+		t.Errorf("a program with errors gives %v and %v", diags, err)
+	}
+	for _, s := range []Snippet{{Name: "X", Kind: "binary", Source: "return 1;"}, {Name: "X", Kind: SnippetObject, Source: "return 1;"}} {
+		if _, err := Generate(&out, "p", "a test", []Snippet{s}, Config{Target: "aarch64-linux"}); err == nil {
+			t.Errorf("no error for %v", s)
+		}
+	}
+}
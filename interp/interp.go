@@ -0,0 +1,264 @@
+// Package interp runs programs by evaluating their typed AST, without
+// compiling them.
+package interp
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/youngfr/gocc/parser"
+	"github.com/youngfr/gocc/token"
+)
+
+// Interpreter
+//
+// Run evaluates the tree built by the parser, before any AST pass, with
+// the semantics of the code gocc generates for x86-64, so that programs
+// run where there is no toolchain for it, and so that the output of the
+// code generator can be checked against it:
+//
+//   - Values are held in 64 bits. Arithmetic on int wraps around to 32
+//     bits and pointer arithmetic is done in 64 bits, on addresses the
+//     parser has already scaled by the size of what they point to.
+//   - Variables live in a simulated stack frame, each at an address of
+//     its own aligned to its size. Loads and stores must fall within a
+//     variable, so that stray pointers are reported instead of reading
+//     whatever is next to it.
+//   - The implicit main is the only function, so there are no calls.
+//     When it falls off its end, it returns the value of the last
+//     expression statement it ran, or 0.
+//
+// Operations that make a native program die are runtime errors, which
+// tell the exit status it would have died with. Without
+// -fsanitize=undefined-lite, signed overflow wraps around like in the
+// native program, which only traps on division by zero or overflow.
+
+// Address of the first variable. Null and small pointers are invalid.
+const stackBase = 0x10000
+
+// Exit statuses of native programs killed by a signal, as the shell
+// reports them: 128 plus the number of the signal.
+const (
+	statusAbort    = 128 + 6  // SIGABRT, by a failed check of the sanitizer
+	statusFPE      = 128 + 8  // SIGFPE, by a division that traps
+	statusSegfault = 128 + 11 // SIGSEGV, by an invalid access
+)
+
+type Options struct {
+	// Whether -fsanitize=undefined-lite was given, which makes signed
+	// overflow a runtime error, like the checks of the code generator.
+	SanitizeUndefined bool
+}
+
+// An operation that stopped the program.
+type RuntimeError struct {
+	Token   *token.Token // Operation that failed
+	Message string
+	Status  int // Exit status the native program would have died with
+}
+
+// Return the error like the sanitizer reports it.
+func (e *RuntimeError) Error() string {
+	line, column := e.Token.Position()
+	return fmt.Sprintf("%s:%d:%d: runtime error: %s", e.Token.File.Name, line, column, e.Message)
+}
+
+// State of the program being run.
+type interpreter struct {
+	opts      Options
+	stack     []byte                   // Memory of the variables
+	addresses map[*parser.Object]int64 // Address of each variable
+	last      int64                    // Value of the last expression statement run
+}
+
+// Raised to return from main with the value.
+type returned struct {
+	value int64
+}
+
+// Run `program`, which must have no errors nor have been changed by
+// an AST pass, and return the value its main returns, or the runtime
+// error that stopped it.
+func Run(program *parser.Function, opts Options) (value int, err error) {
+	in := &interpreter{opts: opts, addresses: map[*parser.Object]int64{}}
+	size := int64(0)
+	// Locals are kept newest first.
+	var locals []*parser.Object
+	for v := program.Locals; v != nil; v = v.Next {
+		locals = append([]*parser.Object{v}, locals...)
+	}
+	for _, v := range locals {
+		align := int64(v.Type.Size)
+		size = (size + align - 1) / align * align
+		in.addresses[v] = stackBase + size
+		size += int64(v.Type.Size)
+	}
+	in.stack = make([]byte, size)
+	defer func() {
+		switch r := recover().(type) {
+		case nil:
+		case returned:
+			value = int(int32(r.value))
+		case *RuntimeError:
+			err = r
+		default:
+			panic(r)
+		}
+	}()
+	for n := program.Body; n != nil; n = n.Next {
+		in.stmt(n)
+	}
+	return int(int32(in.last)), nil
+}
+
+// Stop the program with a runtime error at `tok`.
+func fail(tok *token.Token, status int, format string, args ...any) {
+	panic(&RuntimeError{tok, fmt.Sprintf(format, args...), status})
+}
+
+func (in *interpreter) stmt(node *parser.Node) {
+	switch node.Kind {
+	case parser.NodeExprStmt:
+		in.last = in.expr(node.Lhs)
+	case parser.NodeBlock:
+		for n := node.Body; n != nil; n = n.Next {
+			in.stmt(n)
+		}
+	case parser.NodeReturn:
+		panic(returned{in.expr(node.Lhs)})
+	case parser.NodeIf:
+		if in.expr(node.Condition) != 0 {
+			in.stmt(node.ThenBranch)
+		} else if node.ElseBranch != nil {
+			in.stmt(node.ElseBranch)
+		}
+	case parser.NodeFor:
+		if node.Initializer != nil {
+			in.stmt(node.Initializer)
+		}
+		for node.Condition == nil || in.expr(node.Condition) != 0 {
+			in.stmt(node.ThenBranch)
+			if node.Increment != nil {
+				in.expr(node.Increment)
+			}
+		}
+	default:
+		panic(fmt.Sprintf("interp: unexpected statement %s", node.Kind))
+	}
+}
+
+// Return the address of the object designated by `node`.
+func (in *interpreter) addr(node *parser.Node) int64 {
+	switch node.Kind {
+	case parser.NodeVar:
+		return in.addresses[node.Variable]
+	case parser.NodeDeref:
+		return in.expr(node.Lhs)
+	}
+	panic(fmt.Sprintf("interp: %s is not addressable", node.Kind))
+}
+
+// Return the offset in the stack of the `size` bytes at `address`,
+// or fail at `tok` if they are not within a variable.
+func (in *interpreter) access(tok *token.Token, address int64, size int) int64 {
+	for v, start := range in.addresses {
+		if start <= address && address+int64(size) <= start+int64(v.Type.Size) {
+			return address - stackBase
+		}
+	}
+	fail(tok, statusSegfault, "invalid access to %d bytes at address %#x", size, address)
+	return 0
+}
+
+// Return the value of `size` bytes at `address`, sign-extended.
+func (in *interpreter) load(tok *token.Token, address int64, size int) int64 {
+	offset := in.access(tok, address, size)
+	if size == 4 {
+		return int64(int32(binary.LittleEndian.Uint32(in.stack[offset:])))
+	}
+	return int64(binary.LittleEndian.Uint64(in.stack[offset:]))
+}
+
+// Store the low `size` bytes of `value` at `address`.
+func (in *interpreter) store(tok *token.Token, address int64, size int, value int64) {
+	offset := in.access(tok, address, size)
+	if size == 4 {
+		binary.LittleEndian.PutUint32(in.stack[offset:], uint32(value))
+	} else {
+		binary.LittleEndian.PutUint64(in.stack[offset:], uint64(value))
+	}
+}
+
+// Return `value` as the result of an operation of `node` on its type:
+// wrapped around to 32 bits for int, checking for overflow with the
+// sanitizer, and left alone for pointers.
+func (in *interpreter) result(node *parser.Node, value int64, problem string) int64 {
+	if node.Type.Size != 4 {
+		return value
+	}
+	if in.opts.SanitizeUndefined && value != int64(int32(value)) {
+		fail(node.Token, statusAbort, "%s", problem)
+	}
+	return int64(int32(value))
+}
+
+func (in *interpreter) expr(node *parser.Node) int64 {
+	switch node.Kind {
+	case parser.NodeNum:
+		return int64(node.Value)
+	case parser.NodeVar:
+		return in.load(node.Token, in.addr(node), node.Type.Size)
+	case parser.NodeAddr:
+		return in.addr(node.Lhs)
+	case parser.NodeDeref:
+		return in.load(node.Token, in.expr(node.Lhs), node.Type.Size)
+	case parser.NodeAsg:
+		address := in.addr(node.Lhs)
+		value := in.expr(node.Rhs)
+		in.store(node.Token, address, node.Lhs.Type.Size, value)
+		return value
+	case parser.NodeNeg:
+		return in.result(node, -in.expr(node.Lhs), "negation overflow")
+	}
+	lhs := in.expr(node.Lhs)
+	rhs := in.expr(node.Rhs)
+	switch node.Kind {
+	case parser.NodeAdd:
+		return in.result(node, lhs+rhs, "signed integer overflow")
+	case parser.NodeSub:
+		return in.result(node, lhs-rhs, "signed integer overflow")
+	case parser.NodeMul:
+		return in.result(node, lhs*rhs, "signed integer overflow")
+	case parser.NodeDiv:
+		if rhs == 0 {
+			status := statusFPE
+			if in.opts.SanitizeUndefined {
+				status = statusAbort
+			}
+			fail(node.Token, status, "division by zero")
+		}
+		if node.Type.Size == 4 && int32(lhs) == -1<<31 && int32(rhs) == -1 {
+			fail(node.Token, statusFPE, "division overflow")
+		}
+		if node.Type.Size == 4 {
+			return int64(int32(lhs) / int32(rhs))
+		}
+		return lhs / rhs
+	case parser.NodeEql:
+		return btoi(lhs == rhs)
+	case parser.NodeNeq:
+		return btoi(lhs != rhs)
+	case parser.NodeLss:
+		return btoi(lhs < rhs)
+	case parser.NodeLeq:
+		return btoi(lhs <= rhs)
+	}
+	panic(fmt.Sprintf("interp: unexpected expression %s", node.Kind))
+}
+
+func btoi(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
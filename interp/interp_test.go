@@ -0,0 +1,52 @@
+package interp
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/youngfr/gocc/lexer"
+	"github.com/youngfr/gocc/parser"
+	"github.com/youngfr/gocc/token"
+)
+
+func TestRun(t *testing.T) {
+	for _, c := range []struct {
+		src      string
+		sanitize bool
+		value    int
+		err      string // Runtime error, if any
+		status   int
+	}{
+		{"return -(7 + 2) * 3 <= 1 == 1;", false, 1, "", 0},
+		{"int a = 3; int b = 5; a * b + 2;", false, 17, "", 0},
+		{"int i; int s = 0; for (i = 0; i <= 10; i = i + 1) s = s + i; return s;", false, 55, "", 0},
+		{"int n = 0; while (n < 7) { if (n == 4) return 40; n = n + 1; } return n;", false, 40, "", 0},
+		{"int a; int b; int *p = &a; *p = 9; p = &b; *p = 2; return a - b;", false, 7, "", 0},
+		{"int x; int y; int *p = &y; int *q = &y; return (q + 3) - p;", false, 3, "", 0},
+		{"int a = 2147483647; return a + 1;", false, -2147483648, "", 0},
+		{"int a = 2147483647; return a + 1;", true, 0, "<test>:1:30: runtime error: signed integer overflow", 134},
+		{"int a = -2147483647 - 1; return -a;", true, 0, "<test>:1:33: runtime error: negation overflow", 134},
+		{"int a = 0; return 1 / a;", false, 0, "<test>:1:21: runtime error: division by zero", 136},
+		{"int a = 0; return 1 / a;", true, 0, "<test>:1:21: runtime error: division by zero", 134},
+		{"int a; int *p = &a; p = p + 5; return *p;", false, 0, "<test>:1:39: runtime error: invalid access to 4 bytes at address 0x10014", 139},
+		{"int *p; return *p;", false, 0, "<test>:1:16: runtime error: invalid access to 4 bytes at address 0x0", 139},
+	} {
+		tok, _ := lexer.Tokenize(token.NewSession().AddFile("<test>", "", c.src))
+		program, err := parser.Parse(tok)
+		if err != nil {
+			t.Fatalf("cannot parse %q: %v", c.src, err)
+		}
+		value, err := Run(program, Options{SanitizeUndefined: c.sanitize})
+		var runtimeErr *RuntimeError
+		switch {
+		case c.err == "" && err != nil:
+			t.Errorf("%q fails: %v", c.src, err)
+		case c.err == "" && value != c.value:
+			t.Errorf("%q returns %d, want %d", c.src, value, c.value)
+		case c.err != "" && !errors.As(err, &runtimeErr):
+			t.Errorf("%q returns %d, %v, want %s", c.src, value, err, c.err)
+		case c.err != "" && (runtimeErr.Error() != c.err || runtimeErr.Status != c.status):
+			t.Errorf("%q fails with %s, status %d, want %s, status %d", c.src, runtimeErr, runtimeErr.Status, c.err, c.status)
+		}
+	}
+}
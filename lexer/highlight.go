@@ -0,0 +1,103 @@
+package lexer
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/youngfr/gocc/token"
+)
+
+// Highlighting
+//
+// Classify splits a file into the spans of text that syntax highlighting
+// colors differently, from the tokens the lexer read in it, so that the
+// source is colored the way gocc reads it. The text between tokens is
+// whitespace, which no span covers, comments, and characters the lexer
+// rejected, such as unsupported operators.
+
+// Classes of spans.
+const (
+	ClassKeyword     = "keyword"
+	ClassType        = "type"
+	ClassIdentifier  = "identifier"
+	ClassNumber      = "number"
+	ClassOperator    = "operator"
+	ClassPunctuation = "punctuation"
+	ClassComment     = "comment"
+	ClassInvalid     = "invalid"
+)
+
+// A span of text of a file and its class.
+type Span struct {
+	Class string
+	Begin int          // Offset of the first byte
+	End   int          // Offset past the last byte
+	Token *token.Token // Token of the span, nil for comments and invalid text
+}
+
+// Classes of the tokens that are not operators.
+var tokenClasses = map[token.TokenKind]string{
+	token.RETURN: ClassKeyword,
+	token.IF:     ClassKeyword,
+	token.ELSE:   ClassKeyword,
+	token.FOR:    ClassKeyword,
+	token.WHILE:  ClassKeyword,
+	token.INT:    ClassType,
+	token.IDENT:  ClassIdentifier,
+	token.NUM:    ClassNumber,
+	token.LPAREN: ClassPunctuation,
+	token.RPAREN: ClassPunctuation,
+	token.LBRACE: ClassPunctuation,
+	token.RBRACE: ClassPunctuation,
+	token.SEMI:   ClassPunctuation,
+	token.COMMA:  ClassPunctuation,
+}
+
+// Return the spans of `file` in order, given the tokens that Tokenize
+// returned for it, even with errors.
+func Classify(file *token.File, tokens []token.Token) []Span {
+	var spans []Span
+	p := 0
+	for i := 0; i < len(tokens) && tokens[i].Kind != token.EOF; i++ {
+		t := &tokens[i]
+		spans = classifyGap(spans, file.Contents, p, t.Begin)
+		class, ok := tokenClasses[t.Kind]
+		if !ok {
+			class = ClassOperator
+		}
+		spans = append(spans, Span{class, t.Begin, t.Begin + t.Length, t})
+		p = t.Begin + t.Length
+	}
+	return classifyGap(spans, file.Contents, p, len(file.Contents))
+}
+
+// Append the spans of the text of `source` from `p` to `end`, between
+// tokens, to `spans` and return them.
+func classifyGap(spans []Span, source string, p int, end int) []Span {
+	for p < end {
+		q := p
+		switch {
+		case unicode.IsSpace(rune(source[p])):
+			p++
+			continue
+		case strings.HasPrefix(source[p:end], "//"):
+			for p < end && source[p] != '\n' {
+				p++
+			}
+			spans = append(spans, Span{ClassComment, q, p, nil})
+		case strings.HasPrefix(source[p:end], "/*"):
+			if i := strings.Index(source[p+2:end], "*/"); i >= 0 {
+				p += 2 + i + 2
+			} else {
+				p = end
+			}
+			spans = append(spans, Span{ClassComment, q, p, nil})
+		default:
+			for p < end && !unicode.IsSpace(rune(source[p])) && !strings.HasPrefix(source[p:end], "//") && !strings.HasPrefix(source[p:end], "/*") {
+				p++
+			}
+			spans = append(spans, Span{ClassInvalid, q, p, nil})
+		}
+	}
+	return spans
+}
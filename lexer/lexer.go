@@ -0,0 +1,301 @@
+// Package lexer splits a C source file into a list of tokens.
+package lexer
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/youngfr/gocc/token"
+)
+
+// Tokenizer
+
+func lookahead(source string, p int, expected ...byte) int {
+	n := len(expected)
+	if p+n >= len(source) {
+		return -1
+	}
+	toklen := 1
+	for i := 1; i <= n; i++ {
+		if source[p+i] == expected[i-1] {
+			toklen++
+		} else {
+			return toklen
+		}
+	}
+	return toklen
+}
+
+// Create a tokens list
+// Return the tokens, ending with EOF, and the errors recorded
+// for the file. Invalid tokens are skipped, so the list is
+// complete even if there are errors.
+func Tokenize(file *token.File) ([]token.Token, error) {
+	source := file.Contents
+	// Programs rarely have more than a token every 2 bytes, so the
+	// tokens are seldom copied to a larger slice.
+	tokens := make([]token.Token, 0, len(source)/2+1)
+	p := 0
+	for p < len(source) {
+		switch {
+		case unicode.IsSpace(rune(source[p])):
+			p++
+		case source[p] == '#':
+			q := lineMarker(file, p)
+			if q == p {
+				file.ErrorAt(token.ExitLexical, p, 1, "invalid token")
+				q++
+			}
+			p = q
+		case unicode.IsDigit(rune(source[p])):
+			q := p
+			for p < len(source) && unicode.IsDigit(rune(source[p])) {
+				p++
+			}
+			tok := token.NewToken(file, token.NUM, q, p)
+			// int is the only integer type, so a constant must fit in it.
+			value, err := strconv.Atoi(tok.Lexeme)
+			if err != nil || value > math.MaxInt32 {
+				file.ErrorAt(token.ExitLexical, q, p-q, "integer constant is too large for its type")
+			}
+			tok.Value = value
+			tokens = append(tokens, tok)
+		case source[p] == '+':
+			switch {
+			case lookahead(source, p, '+') == 2:
+				p = unsupported(file, p, 2)
+			case lookahead(source, p, '=') == 2:
+				p = unsupported(file, p, 2)
+			case lookahead(source, p) == 1:
+				tokens = append(tokens, token.NewToken(file, token.ADD, p, p+1))
+				p += 1
+			}
+		case source[p] == '-':
+			switch {
+			case lookahead(source, p, '>') == 2:
+				p = unsupported(file, p, 2)
+			case lookahead(source, p, '-') == 2:
+				p = unsupported(file, p, 2)
+			case lookahead(source, p, '=') == 2:
+				p = unsupported(file, p, 2)
+			case lookahead(source, p) == 1:
+				tokens = append(tokens, token.NewToken(file, token.SUB, p, p+1))
+				p += 1
+			}
+		case source[p] == '*':
+			switch {
+			case lookahead(source, p, '=') == 2:
+				p = unsupported(file, p, 2)
+			case lookahead(source, p) == 1:
+				tokens = append(tokens, token.NewToken(file, token.ASTERISK, p, p+1))
+				p += 1
+			}
+		case strings.HasPrefix(source[p:], "//"):
+			// Comments to the end of the line came with C99, but
+			// GNU C had them before.
+			if !file.Session.StandardAtLeast(1999) && !file.Session.GNUExtensions() {
+				file.ErrorAt(token.ExitLexical, p, 2, "\"//\" comments require -std=c99 or later")
+			}
+			for p < len(source) && source[p] != '\n' {
+				p++
+			}
+		case strings.HasPrefix(source[p:], "/*"):
+			end := strings.Index(source[p+2:], "*/")
+			if end < 0 {
+				file.ErrorAt(token.ExitLexical, p, 2, "unterminated comment")
+				p = len(source)
+			} else {
+				p += 2 + end + 2
+			}
+		case source[p] == '/':
+			switch {
+			case lookahead(source, p, '=') == 2:
+				p = unsupported(file, p, 2)
+			case lookahead(source, p) == 1:
+				tokens = append(tokens, token.NewToken(file, token.DIV, p, p+1))
+				p += 1
+			}
+		case source[p] == '=':
+			switch {
+			case lookahead(source, p, '=') == 2:
+				tokens = append(tokens, token.NewToken(file, token.EQL, p, p+2))
+				p += 2
+			case lookahead(source, p) == 1:
+				tokens = append(tokens, token.NewToken(file, token.ASG, p, p+1))
+				p += 1
+			}
+		case source[p] == '!':
+			switch {
+			case lookahead(source, p, '=') == 2:
+				tokens = append(tokens, token.NewToken(file, token.NEQ, p, p+2))
+				p += 2
+			case lookahead(source, p) == 1:
+				tokens = append(tokens, token.NewToken(file, token.NOT, p, p+1))
+				p += 1
+			}
+		case source[p] == '<':
+			switch {
+			case lookahead(source, p, '<', '=') == 3:
+				p = unsupported(file, p, 3)
+			case lookahead(source, p, '<') == 2:
+				p = unsupported(file, p, 2)
+			case lookahead(source, p, '=') == 2:
+				tokens = append(tokens, token.NewToken(file, token.LEQ, p, p+2))
+				p += 2
+			case lookahead(source, p) == 1:
+				tokens = append(tokens, token.NewToken(file, token.LSS, p, p+1))
+				p += 1
+			}
+		case source[p] == '>':
+			switch {
+			case lookahead(source, p, '>', '=') == 3:
+				p = unsupported(file, p, 3)
+			case lookahead(source, p, '>') == 2:
+				p = unsupported(file, p, 2)
+			case lookahead(source, p, '=') == 2:
+				tokens = append(tokens, token.NewToken(file, token.GEQ, p, p+2))
+				p += 2
+			case lookahead(source, p) == 1:
+				tokens = append(tokens, token.NewToken(file, token.GTR, p, p+1))
+				p += 1
+			}
+		case source[p] == '&':
+			switch {
+			case lookahead(source, p, '&') == 2:
+				p = unsupported(file, p, 2)
+			case lookahead(source, p, '=') == 2:
+				p = unsupported(file, p, 2)
+			case lookahead(source, p) == 1:
+				tokens = append(tokens, token.NewToken(file, token.AND, p, p+1))
+				p += 1
+			}
+		case source[p] == '(':
+			tokens = append(tokens, token.NewToken(file, token.LPAREN, p, p+1))
+			p++
+		case source[p] == ')':
+			tokens = append(tokens, token.NewToken(file, token.RPAREN, p, p+1))
+			p++
+		case source[p] == '{':
+			tokens = append(tokens, token.NewToken(file, token.LBRACE, p, p+1))
+			p++
+		case source[p] == '}':
+			tokens = append(tokens, token.NewToken(file, token.RBRACE, p, p+1))
+			p++
+		case source[p] == ';':
+			tokens = append(tokens, token.NewToken(file, token.SEMI, p, p+1))
+			p++
+		case source[p] == ',':
+			tokens = append(tokens, token.NewToken(file, token.COMMA, p, p+1))
+			p++
+		case isLetter(source[p]):
+			q := p
+			for p < len(source) && (isLetter(source[p]) || isDigit(source[p])) {
+				p++
+			}
+			if kind, ok := keywords[source[q:p]]; ok {
+				tokens = append(tokens, token.NewToken(file, kind, q, p))
+			} else {
+				tokens = append(tokens, token.NewToken(file, token.IDENT, q, p))
+			}
+		default:
+			// Skip the byte and keep going to find more errors.
+			file.ErrorAt(token.ExitLexical, p, 1, "invalid token")
+			p++
+		}
+	}
+	tokens = append(tokens, token.NewToken(file, token.EOF, p, p))
+	for i := range tokens {
+		tokens[i].Index = i
+	}
+	return tokens, file.Err()
+}
+
+// Return the offset following the line marker at `p` and record its line
+// info, or `p` if there is none. Line markers are left by preprocessors
+// at the beginning of lines to give the file and line the next line
+// comes from, optionally followed by flags:
+//
+//	# 12 "prog.c" 2
+//	#line 12 "prog.c"
+func lineMarker(file *token.File, p int) int {
+	source := file.Contents
+	if p > 0 && strings.TrimLeft(source[strings.LastIndexByte(source[:p], '\n')+1:p], " \t") != "" {
+		return p
+	}
+	end := strings.IndexByte(source[p:], '\n')
+	if end < 0 {
+		end = len(source)
+	} else {
+		end += p
+	}
+	fields := strings.Fields(source[p+1 : end])
+	if len(fields) > 0 && fields[0] == "line" {
+		fields = fields[1:]
+	}
+	if len(fields) == 0 {
+		return p
+	}
+	line, err := strconv.Atoi(fields[0])
+	if err != nil || line < 0 {
+		return p
+	}
+	filename := file.PositionFor(p).Filename
+	if len(fields) > 1 {
+		rest := source[p+1 : end]
+		i := strings.IndexByte(rest, '"')
+		if i < 0 {
+			return p
+		}
+		name, err := strconv.QuotedPrefix(rest[i:])
+		if err != nil {
+			return p
+		}
+		if filename, err = strconv.Unquote(name); err != nil {
+			return p
+		}
+	}
+	if end < len(source) {
+		end++
+		file.AddLineInfo(end, filename, line)
+	}
+	return end
+}
+
+var keywords = map[string]token.TokenKind{
+	"return": token.RETURN,
+	"if":     token.IF,
+	"else":   token.ELSE,
+	"for":    token.FOR,
+	"while":  token.WHILE,
+	"int":    token.INT,
+}
+
+// Record an error for the operator of `length` bytes at `p`, which is
+// not supported yet, and return the index following it.
+func unsupported(file *token.File, p int, length int) int {
+	file.ErrorAt(token.ExitLexical, p, length, "unsupported operator \"%s\"", file.Contents[p:p+length])
+	return p + length
+}
+
+func isLetter(c byte) bool {
+	return (c == '_') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// Printing, for debugging
+
+// Print the tokens, one per line with its position, kind and
+// lexeme, for --dump-tokens.
+func DumpTokens(out io.Writer, tokens []token.Token) {
+	for _, t := range tokens {
+		line, column := t.Position()
+		fmt.Fprintf(out, "%s:%d:%d: %s %q\n", t.File.Name, line, column, t.Kind, t.Lexeme)
+	}
+}
@@ -0,0 +1,67 @@
+package lexer
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/youngfr/gocc/token"
+)
+
+// Integer constants must fit in an int, the only integer type.
+func TestIntegerTooLarge(t *testing.T) {
+	for _, c := range []struct {
+		src  string
+		want string // Error reported, if any
+	}{
+		{"2147483647", ""},
+		{"2147483648", "a.c:1:1: error: integer constant is too large for its type"},
+		{"1 + 99999999999999999999", "a.c:1:5: error: integer constant is too large for its type"},
+	} {
+		_, err := Tokenize(token.NewSession().AddFile("a.c", "a.c", c.src))
+		if c.want == "" {
+			if err != nil {
+				t.Errorf("%s: %v", c.src, err)
+			}
+			continue
+		}
+		var errs token.ErrorList
+		if !errors.As(err, &errs) || errs.Error() != c.want || errs.ExitStatus() != token.ExitLexical {
+			t.Errorf("%s is reported as %v, want %s", c.src, err, c.want)
+		}
+	}
+}
+
+// Line markers are skipped, and the tokens following them are positioned
+// in the file and line they give.
+func TestLineMarkers(t *testing.T) {
+	src := "# 1 \"a.c\"\nint a;\n# 1 \"/usr/include/h.h\" 1 3 4\n\n  a = 1;\n# 7 \"a.c\" 2\nreturn a;\n#line 20\n #  \t3 \"b\\\\c.c\"\nx\n"
+	file := token.NewSession().AddFile("pp.c", "pp.c", src)
+	tokens, err := Tokenize(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for i, tok := range tokens[:len(tokens)-1] {
+		if tok.Index != i {
+			t.Errorf("token %d has index %d", i, tok.Index)
+		}
+		got = append(got, fmt.Sprintf("%s %s", tok.Lexeme, file.PositionFor(tok.Begin)))
+	}
+	want := []string{
+		"int a.c:1:1", "a a.c:1:5", "; a.c:1:6",
+		"a /usr/include/h.h:2:3", "= /usr/include/h.h:2:5", "1 /usr/include/h.h:2:7", "; /usr/include/h.h:2:8",
+		"return a.c:7:1", "a a.c:7:8", "; a.c:7:9",
+		"x b\\c.c:3:1",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("tokens are:\n%q\nwant:\n%q", got, want)
+	}
+
+	// A # that is not a line marker is an invalid token.
+	file = token.NewSession().AddFile("a.c", "a.c", "a # 1\n#x\n")
+	Tokenize(file)
+	if got := fmt.Sprint(file.Diagnostics); got != "[a.c:1:3: error: invalid token a.c:2:1: error: invalid token]" {
+		t.Errorf("invalid # are reported as %s", got)
+	}
+}
@@ -0,0 +1,130 @@
+package lexer
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/youngfr/gocc/token"
+)
+
+// Preprocessed output
+//
+// With -E, the driver prints each file the way the preprocessor hands it
+// to the compiler, laid out like the output of gcc -E so that both can be
+// compared and fed to other compilers. There are no directives nor macros
+// yet, so that is the tokens of the file without its comments, introduced
+// by the line markers gcc starts with when it does not include the
+// predefined macros of the system:
+//
+//	# 0 "prog.c"
+//	# 0 "<built-in>"
+//	# 0 "<command-line>"
+//	# 1 "prog.c"
+//
+// Like gcc, tokens stay on the line they were on, indented by as many
+// spaces as their column, and separated by one space where there was
+// whitespace or a comment. Up to 7 blank lines are kept, and longer runs
+// are replaced by a line marker giving the line of the next token.
+// Tokens following a comment across lines start a new line. The text
+// the lexer rejects is copied as is, since it is only invalid C, not
+// invalid preprocessing tokens.
+
+// Lines up to which blank lines are printed instead of a line marker,
+// like gcc.
+const maxBlankLines = 8
+
+// State of the output of a file.
+type preprocessor struct {
+	out     io.Writer
+	file    *token.File
+	line    int  // Line of the file the output is on
+	printed bool // Whether text was printed on the current line
+}
+
+// Write the preprocessed `file`, whose tokens are `tokens`, to `out`.
+func WritePreprocessed(out io.Writer, file *token.File, tokens []token.Token) {
+	pp := &preprocessor{out: out, file: file}
+	name := quoteFileName(file.Name)
+	fmt.Fprintf(out, "# 0 %s\n# 0 \"<built-in>\"\n# 0 \"<command-line>\"\n", name)
+	pp.marker(1)
+	end := 0 // End of the previous token
+	for _, span := range Classify(file, tokens) {
+		if span.Class == ClassComment {
+			continue
+		}
+		line, column := file.Position(span.Begin)
+		gap := file.Contents[end:span.Begin]
+		switch {
+		case end == 0 || newLine(file, end, span.Begin):
+			pp.lineChange(line, column)
+		case gap != "" && line != pp.line:
+			pp.lineChange(line, column)
+		case gap != "":
+			io.WriteString(out, " ")
+		}
+		io.WriteString(out, file.Contents[span.Begin:span.End])
+		end = span.End
+	}
+	if pp.printed {
+		io.WriteString(out, "\n")
+	}
+}
+
+// Return whether there is a newline outside comments in the text of
+// `file` from `begin` to `end`, between tokens.
+func newLine(file *token.File, begin int, end int) bool {
+	gap := file.Contents[begin:end]
+	for gap != "" {
+		switch {
+		case strings.HasPrefix(gap, "//"):
+			i := strings.IndexByte(gap, '\n')
+			if i < 0 {
+				return false
+			}
+			gap = gap[i:]
+		case strings.HasPrefix(gap, "/*"):
+			i := strings.Index(gap[2:], "*/")
+			if i < 0 {
+				return false
+			}
+			gap = gap[2+i+2:]
+		case gap[0] == '\n':
+			return true
+		default:
+			gap = gap[1:]
+		}
+	}
+	return false
+}
+
+// Move the output to the `line` of the file, and indent it for a token at
+// `column`.
+func (pp *preprocessor) lineChange(line int, column int) {
+	if pp.printed {
+		io.WriteString(pp.out, "\n")
+		pp.line++
+	}
+	if line >= pp.line && line < pp.line+maxBlankLines {
+		io.WriteString(pp.out, strings.Repeat("\n", line-pp.line))
+		pp.line = line
+	} else {
+		pp.marker(line)
+	}
+	if column > 1 {
+		io.WriteString(pp.out, strings.Repeat(" ", column-1))
+	}
+	pp.printed = true
+}
+
+// Print a line marker telling that the next line is `line` of the file.
+func (pp *preprocessor) marker(line int) {
+	fmt.Fprintf(pp.out, "# %d %s\n", line, quoteFileName(pp.file.Name))
+	pp.line = line
+	pp.printed = false
+}
+
+// Return `name` quoted like in the line markers of gcc.
+func quoteFileName(name string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(name) + `"`
+}
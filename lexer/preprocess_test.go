@@ -0,0 +1,35 @@
+package lexer
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/youngfr/gocc/token"
+)
+
+// The expected outputs are those of gcc -E -ffreestanding.
+func TestWritePreprocessed(t *testing.T) {
+	for _, c := range []struct {
+		src  string
+		want string
+	}{
+		{"int a = 1; /* c */ a  =  2;\n\n\tint b;   // x\n    return a +b;\n/* multi\nline */ int c;\n\n\n\n\n\n\n\n\n\n\nreturn c;\n\n\n",
+			"# 0 \"a.c\"\n# 0 \"<built-in>\"\n# 0 \"<command-line>\"\n# 1 \"a.c\"\nint a = 1; a = 2;\n\n int b;\n    return a +b;\n\n        int c;\n# 17 \"a.c\"\nreturn c;\n"},
+		{"a /* x\n y */ b\nc\n",
+			"# 0 \"a.c\"\n# 0 \"<built-in>\"\n# 0 \"<command-line>\"\n# 1 \"a.c\"\na\n      b\nc\n"},
+		{"a\n\n\n\n\n\n\n\nb\n\n\n\n\n\n\n\n\nc\n",
+			"# 0 \"a.c\"\n# 0 \"<built-in>\"\n# 0 \"<command-line>\"\n# 1 \"a.c\"\na\n\n\n\n\n\n\n\nb\n# 18 \"a.c\"\nc\n"},
+		{"",
+			"# 0 \"a.c\"\n# 0 \"<built-in>\"\n# 0 \"<command-line>\"\n# 1 \"a.c\"\n"},
+		{"x += 1;",
+			"# 0 \"a.c\"\n# 0 \"<built-in>\"\n# 0 \"<command-line>\"\n# 1 \"a.c\"\nx += 1;\n"},
+	} {
+		file := token.NewSession().AddFile("a.c", "a.c", c.src)
+		tok, _ := Tokenize(file)
+		var out bytes.Buffer
+		WritePreprocessed(&out, file, tok)
+		if out.String() != c.want {
+			t.Errorf("%q is preprocessed as:\n%s\nwant:\n%s", c.src, out.String(), c.want)
+		}
+	}
+}
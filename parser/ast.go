@@ -0,0 +1,177 @@
+package parser
+
+import (
+	"github.com/youngfr/gocc/token"
+	"github.com/youngfr/gocc/types"
+)
+
+// Abstract syntax tree
+//
+// The parser returns the program as a Function whose body is a list of
+// statement nodes linked by Next. Expressions are typed as they are
+// built. Each node keeps the token it is reported at, such as the
+// operator of a binary expression, and the span of source it was
+// parsed from, from its first to its last token. Nodes created by the
+// compiler itself, such as the scaling of pointer arithmetic, take the
+// token and the span of the node they were made for.
+//
+// The tree is read by analyzers built on gocc through the exported
+// fields and the methods below. Walk and Inspect traverse it.
+
+type NodeKind int
+
+const (
+	NodeAdd      NodeKind = iota // lhs + rhs
+	NodeSub                      // lhs - rhs
+	NodeMul                      // lhs * rhs
+	NodeDiv                      // lhs / rhs
+	NodeEql                      // lhs == rhs
+	NodeNeq                      // lhs != rhs
+	NodeLss                      // lhs < rhs, also for rhs > lhs
+	NodeLeq                      // lhs <= rhs, also for rhs >= lhs
+	NodeAsg                      // lhs = rhs
+	NodeNeg                      // - lhs
+	NodeAddr                     // & lhs
+	NodeDeref                    // * lhs
+	NodeVar                      // variable
+	NodeNum                      // number
+	NodeExprStmt                 // expression statement
+	NodeReturn                   // return statement
+	NodeBlock                    // block statement, also for declarations and ";"
+	NodeIf                       // if statement
+	NodeFor                      // for or while statement
+)
+
+var nodeNames = map[NodeKind]string{
+	NodeAdd: "Add", NodeSub: "Sub", NodeMul: "Mul", NodeDiv: "Div",
+	NodeEql: "Eql", NodeNeq: "Neq", NodeLss: "Lss", NodeLeq: "Leq",
+	NodeAsg: "Asg", NodeNeg: "Neg", NodeAddr: "Addr", NodeDeref: "Deref",
+	NodeVar: "Var", NodeNum: "Num", NodeExprStmt: "ExprStmt",
+	NodeReturn: "Return", NodeBlock: "Block", NodeIf: "If", NodeFor: "For",
+}
+
+// Return the name of the kind, as printed by --dump-ast.
+func (kind NodeKind) String() string {
+	return nodeNames[kind]
+}
+
+// Whether nodes of the kind are statements rather than expressions.
+func (kind NodeKind) IsStmt() bool {
+	return kind >= NodeExprStmt
+}
+
+type Node struct {
+	Kind NodeKind // Node kind
+	Lhs  *Node    // Left-hand side
+	Rhs  *Node    // Right-hand side
+
+	// int, pointer to int, ...
+	Type *types.Type
+
+	// Representative token
+	Token *token.Token
+
+	// Used if kind == NodeIf | NodeFor
+	Condition  *Node
+	ThenBranch *Node
+
+	// Used if kind == NodeIf
+	ElseBranch *Node
+
+	// Used if kind == NodeFor
+	Initializer *Node
+	Increment   *Node
+
+	// Used if kind == NodeBlock
+	// The list of statements within the block
+	Body *Node
+	Next *Node
+
+	// Used if kind == NodeVar
+	// Variable's struct representation
+	Variable *Object
+
+	// Used if kind == NodeNum
+	Value int
+
+	// First and last tokens of the source of the node
+	first, last *token.Token
+}
+
+// Return the first and the last token of the source of the node.
+func (n *Node) Span() (first *token.Token, last *token.Token) {
+	return n.first, n.last
+}
+
+// Return the position of the first byte of the source of the node.
+func (n *Node) Pos() token.Pos {
+	return n.first.Pos()
+}
+
+// Return the position of the byte following the source of the node.
+func (n *Node) End() token.Pos {
+	return n.last.File.Pos(n.last.Begin + n.last.Length)
+}
+
+// Return the source text of the node.
+func (n *Node) Source() string {
+	return n.first.File.Contents[n.first.Begin : n.last.Begin+n.last.Length]
+}
+
+// Widen the span of the node to include the tokens from `first` to `last`.
+func (n *Node) widen(first *token.Token, last *token.Token) {
+	if n.first == nil || first.Begin < n.first.Begin {
+		n.first = first
+	}
+	if n.last == nil || last.Begin > n.last.Begin {
+		n.last = last
+	}
+}
+
+// Give the node the span of `other`, which it was made for, and return it.
+func (n *Node) spanLike(other *Node) *Node {
+	n.first, n.last = other.first, other.last
+	return n
+}
+
+// Widen the span of the node to include that of `child`, if any.
+func (n *Node) cover(child *Node) {
+	if child != nil {
+		n.widen(child.first, child.last)
+	}
+}
+
+// Object represents a local variable.
+type Object struct {
+	Next   *Object      // Next variable
+	name   string       // Variable's name
+	Type   *types.Type  // Variable's type
+	Offset int          // Offset from RBP
+	token  *token.Token // Name in the declaration
+	used   bool         // Whether the variable is referred to after its declaration
+	reads  int          // Number of references other than assignments to it
+}
+
+// Return the name of the variable.
+func (o *Object) Name() string {
+	return o.name
+}
+
+// Return the token naming the variable in its declaration.
+func (o *Object) Decl() *token.Token {
+	return o.token
+}
+
+// Return the position of the name of the variable in its declaration.
+func (o *Object) Pos() token.Pos {
+	return o.token.Pos()
+}
+
+// The program, which is the body of an implicit main function.
+type Function struct {
+	Name      string
+	File      *token.File
+	Body      *Node   // List of top-level statements
+	Locals    *Object // Local variables, the last declared first
+	StackSize int     // Bytes needed by the locals, set by the backends
+}
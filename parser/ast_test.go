@@ -0,0 +1,73 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/youngfr/gocc/token"
+)
+
+func TestNodeSource(t *testing.T) {
+	program := parseString(t, "int x = 0; int *p = &x; if (p) { *p = (1 + 2) * 3; } else ; while (*p) *p = *p - 1; p = p + 1; return +*p;")
+	var got []string
+	InspectList(program.Body, func(node *Node) bool {
+		got = append(got, node.Kind.String()+" "+node.Source())
+		return true
+	})
+	want := []string{
+		"Block int x = 0;",
+		"ExprStmt x = 0",
+		"Asg x = 0",
+		"Var x",
+		"Num 0",
+		"Block int *p = &x;",
+		"ExprStmt p = &x",
+		"Asg p = &x",
+		"Var p",
+		"Addr &x",
+		"Var x",
+		"If if (p) { *p = (1 + 2) * 3; } else ;",
+		"Var p",
+		"Block { *p = (1 + 2) * 3; }",
+		"ExprStmt *p = (1 + 2) * 3;",
+		"Asg *p = (1 + 2) * 3",
+		"Deref *p",
+		"Var p",
+		"Mul (1 + 2) * 3",
+		"Add (1 + 2)",
+		"Num 1",
+		"Num 2",
+		"Num 3",
+		"Block ;",
+		"For while (*p) *p = *p - 1;",
+		"Deref *p",
+		"Var p",
+		"ExprStmt *p = *p - 1;",
+		"Asg *p = *p - 1",
+		"Deref *p",
+		"Var p",
+		"Sub *p - 1",
+		"Deref *p",
+		"Var p",
+		"Num 1",
+		"ExprStmt p = p + 1;",
+		"Asg p = p + 1",
+		"Var p",
+		"Add p + 1",
+		"Var p",
+		// The scaling of 1 by the size of int.
+		"Mul 1",
+		"Num 1",
+		"Num 1",
+		"Return return +*p;",
+		"Deref +*p",
+		"Var p",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("sources:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	ret := program.Body.Next.Next.Next.Next.Next
+	if pos, end := ret.Pos(), ret.End(); end-pos != token.Pos(len("return +*p;")) {
+		t.Errorf("return spans %d to %d", pos, end)
+	}
+}
@@ -0,0 +1,156 @@
+package parser
+
+import "github.com/youngfr/gocc/types"
+
+// Structural differences
+//
+// Diff compares the trees of two programs regardless of how their
+// source is laid out, for "gocc astdiff". Two nodes are alike when they
+// have the same kind, variable name, constant value and type. The
+// statements of two lists are matched along their longest common
+// subsequence of identical subtrees; the statements left between two
+// matches are compared pairwise, in order, and the extra ones are
+// removed or added. A node unlike its counterpart is changed as a
+// whole, except that the children of nodes of the same kind are
+// compared in turn. Comments, parentheses and the spelling of constants
+// do not count, nor does a while loop differ from the for loop with the
+// same parts.
+
+// A difference between two programs: a node of the old one changed to
+// one of the new one, a node of the old one removed if New is nil, or a
+// node of the new one added if Old is nil.
+type Difference struct {
+	Old *Node
+	New *Node
+}
+
+// Return the differences between the programs `old` and `new`, in
+// source order.
+func Diff(old *Function, new *Function) []Difference {
+	var d differ
+	d.list(old.Body, new.Body)
+	return d.diffs
+}
+
+type differ struct {
+	diffs []Difference
+}
+
+// Compare the nodes `a` and `b`, either of which may be missing.
+func (d *differ) node(a *Node, b *Node) {
+	switch {
+	case a == nil && b == nil:
+		return
+	case a == nil || b == nil || a.Kind != b.Kind:
+		d.diffs = append(d.diffs, Difference{a, b})
+		return
+	case !alike(a, b):
+		d.diffs = append(d.diffs, Difference{a, b})
+	}
+	d.node(a.Lhs, b.Lhs)
+	d.node(a.Rhs, b.Rhs)
+	d.node(initializer(a), initializer(b))
+	d.node(a.Condition, b.Condition)
+	d.node(a.Increment, b.Increment)
+	d.node(a.ThenBranch, b.ThenBranch)
+	d.node(a.ElseBranch, b.ElseBranch)
+	d.list(a.Body, b.Body)
+}
+
+// Compare the statements of the linked lists `a` and `b`.
+func (d *differ) list(a *Node, b *Node) {
+	var as, bs []*Node
+	for n := a; n != nil; n = n.Next {
+		as = append(as, n)
+	}
+	for n := b; n != nil; n = n.Next {
+		bs = append(bs, n)
+	}
+	// lcs[i][j] is the length of that of as[i:] and bs[j:].
+	lcs := make([][]int, len(as)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(bs)+1)
+	}
+	for i := len(as) - 1; i >= 0; i-- {
+		for j := len(bs) - 1; j >= 0; j-- {
+			switch {
+			case identical(as[i], bs[j]):
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < len(as) || j < len(bs) {
+		// Find the next match, and compare the statements before it.
+		k, l := i, j
+		for k < len(as) && l < len(bs) && !(identical(as[k], bs[l]) && lcs[k][l] == lcs[k+1][l+1]+1) {
+			if lcs[k+1][l] >= lcs[k][l+1] {
+				k++
+			} else {
+				l++
+			}
+		}
+		if k == len(as) || l == len(bs) {
+			k, l = len(as), len(bs)
+		}
+		for ; i < k && j < l; i, j = i+1, j+1 {
+			d.node(as[i], bs[j])
+		}
+		for ; i < k; i++ {
+			d.node(as[i], nil)
+		}
+		for ; j < l; j++ {
+			d.node(nil, bs[j])
+		}
+		if i < len(as) {
+			i, j = i+1, j+1
+		}
+	}
+}
+
+// Whether the nodes `a` and `b` are alike, regardless of their children.
+func alike(a *Node, b *Node) bool {
+	if a.Kind != b.Kind || (a.Type == nil) != (b.Type == nil) || a.Type != nil && !types.Identical(a.Type, b.Type) {
+		return false
+	}
+	switch a.Kind {
+	case NodeVar:
+		return a.Variable.name == b.Variable.name
+	case NodeNum:
+		return a.Value == b.Value
+	}
+	return true
+}
+
+// Return the initializer of the for loop `node`, or nil if it is empty,
+// as that of a while loop.
+func initializer(node *Node) *Node {
+	if init := node.Initializer; init != nil && init.Kind == NodeBlock && init.Body == nil {
+		return nil
+	}
+	return node.Initializer
+}
+
+// Whether the subtrees of `a` and `b` are alike node for node.
+func identical(a *Node, b *Node) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if !alike(a, b) || !identical(a.Lhs, b.Lhs) || !identical(a.Rhs, b.Rhs) ||
+		!identical(initializer(a), initializer(b)) || !identical(a.Condition, b.Condition) ||
+		!identical(a.Increment, b.Increment) || !identical(a.ThenBranch, b.ThenBranch) ||
+		!identical(a.ElseBranch, b.ElseBranch) {
+		return false
+	}
+	m, n := a.Body, b.Body
+	for ; m != nil && n != nil; m, n = m.Next, n.Next {
+		if !identical(m, n) {
+			return false
+		}
+	}
+	return m == nil && n == nil
+}
@@ -0,0 +1,52 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	for _, c := range []struct {
+		old, new string
+		want     string
+	}{
+		{"int a=1; if(a){a=2;} return a;", "int a = 1;\n// x\nif (a) {\n\ta = 2;\n}\nreturn (a);\n", ""},
+		{"int i; for (i=0; i<3;) i=i+1;", "int i; i=0; while (i<3) i=i+1;", "changed For to ExprStmt; added For"},
+		{"int i; i=0; for (; i<3;) i=i+1;", "int i; i=0; while (i<3) i=i+1;", ""},
+		{"int a; a=1; a=2; return a;", "int a; a=1; a=3; a=4; return a;", "changed Num 2 to Num 3; added ExprStmt"},
+		{"int a; return a+1;", "int a; return a-1;", "changed Add to Sub"},
+		{"int a; return a;", "int b; return b;", "changed Var a to Var b; changed Var a to Var b"},
+		{"int a; return *&a;", "int *a; return *&a;", "changed Var a to Var a; changed Deref to Deref; changed Addr to Addr; changed Var a to Var a"},
+		{"if (1) 2; else 3; return 4;", "if (1) 2; return 4;", "removed ExprStmt"},
+		{"return 1;", "1; 2; return 1;", "added ExprStmt; added ExprStmt"},
+	} {
+		var got []string
+		for _, d := range Diff(parseString(t, c.old), parseString(t, c.new)) {
+			got = append(got, differenceString(d))
+		}
+		if strings.Join(got, "; ") != c.want {
+			t.Errorf("%q to %q: %s, want %s", c.old, c.new, strings.Join(got, "; "), c.want)
+		}
+	}
+}
+
+// Return the difference `d` in the terms of the cases above.
+func differenceString(d Difference) string {
+	describe := func(n *Node) string {
+		switch n.Kind {
+		case NodeVar:
+			return fmt.Sprintf("Var %s", n.Variable.name)
+		case NodeNum:
+			return fmt.Sprintf("Num %d", n.Value)
+		}
+		return n.Kind.String()
+	}
+	switch {
+	case d.New == nil:
+		return "removed " + describe(d.Old)
+	case d.Old == nil:
+		return "added " + describe(d.New)
+	}
+	return "changed " + describe(d.Old) + " to " + describe(d.New)
+}
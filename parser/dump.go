@@ -0,0 +1,219 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/youngfr/gocc/token"
+	"github.com/youngfr/gocc/types"
+)
+
+// AST dumps
+//
+// --dump-ast prints the tree built by the parser, before any AST pass
+// runs, with one node per line. Each line holds the node kind, the
+// variable name or constant value if any, the source span of the
+// node's representative token and, for expressions, the type.
+//
+// --dump-ast=json prints the same tree as a JSON object following the
+// schema below, for use by other tools. Fields are only ever added to
+// it; a change to existing ones increments "version".
+//
+//	Function: {
+//	  "version": 1,
+//	  "name":    string,        // Always "main"
+//	  "file":    string,        // Name of the input
+//	  "locals":  [Variable],    // In order of declaration
+//	  "body":    [Node]         // Top-level statements
+//	}
+//	Variable: {"name": string, "type": Type, "span": Span}
+//	Type:     {"kind": "int" | "pointer", "size": int, "base"?: Type}
+//	Span:     {"begin": Position, "end": Position}, end is exclusive
+//	Position: {"offset": int, "line": int, "column": int}, 1-based lines and columns
+//	Node: {
+//	  "kind":  string,          // Node kind, as printed by --dump-ast
+//	  "span":  Span,            // Span of the representative token
+//	  "range": Span,            // Span of the source of the node
+//	  "type"?: Type,            // Type of expressions
+//	  "name"?: string,          // Variable name of "Var"
+//	  "value"?: int,            // Value of "Num"
+//	  "lhs"?, "rhs"?: Node,     // Operands
+//	  "init"?, "cond"?, "inc"?, "then"?, "else"?: Node, // Parts of "If" and "For"
+//	  "body"?: [Node]           // Statements of "Block"
+//	}
+
+// Return the source span of `tok` as "line:col-line:col".
+func span(tok *token.Token) string {
+	line, column := tok.Position()
+	endLine, endColumn := tok.File.Position(tok.Begin + tok.Length)
+	return fmt.Sprintf("%d:%d-%d:%d", line, column, endLine, endColumn)
+}
+
+func DumpAST(out io.Writer, program *Function) {
+	fmt.Fprintf(out, "Function %s %q\n", program.Name, program.File.Name)
+	for n := program.Body; n != nil; n = n.Next {
+		dumpNode(out, n, 1, "")
+	}
+}
+
+// Print `node` indented by `depth` levels, prefixed with its `role`
+// in the parent if it is not an operand, followed by its children.
+func dumpNode(out io.Writer, node *Node, depth int, role string) {
+	if node == nil {
+		return
+	}
+	fmt.Fprintf(out, "%s%s%s", strings.Repeat("  ", depth), role, nodeNames[node.Kind])
+	switch node.Kind {
+	case NodeVar:
+		fmt.Fprintf(out, " %s", node.Variable.name)
+	case NodeNum:
+		fmt.Fprintf(out, " %d", node.Value)
+	}
+	fmt.Fprintf(out, " <%s>", span(node.Token))
+	if node.Type != nil {
+		fmt.Fprintf(out, " '%s'", node.Type)
+	}
+	fmt.Fprintln(out)
+	dumpNode(out, node.Lhs, depth+1, "")
+	dumpNode(out, node.Rhs, depth+1, "")
+	dumpNode(out, node.Initializer, depth+1, "init: ")
+	dumpNode(out, node.Condition, depth+1, "cond: ")
+	dumpNode(out, node.Increment, depth+1, "inc: ")
+	dumpNode(out, node.ThenBranch, depth+1, "then: ")
+	dumpNode(out, node.ElseBranch, depth+1, "else: ")
+	for n := node.Body; n != nil; n = n.Next {
+		dumpNode(out, n, depth+1, "")
+	}
+}
+
+// Version of the JSON schema of --dump-ast=json.
+const jsonASTVersion = 1
+
+type jsonFunction struct {
+	Version int             `json:"version"`
+	Name    string          `json:"name"`
+	File    string          `json:"file"`
+	Locals  []*jsonVariable `json:"locals"`
+	Body    []*jsonNode     `json:"body"`
+}
+
+type jsonVariable struct {
+	Name string    `json:"name"`
+	Type *jsonType `json:"type"`
+	Span *jsonSpan `json:"span"`
+}
+
+type jsonType struct {
+	Kind string    `json:"kind"`
+	Size int       `json:"size"`
+	Base *jsonType `json:"base,omitempty"`
+}
+
+type jsonPosition struct {
+	Offset int `json:"offset"`
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+type jsonSpan struct {
+	Begin jsonPosition `json:"begin"`
+	End   jsonPosition `json:"end"`
+}
+
+type jsonNode struct {
+	Kind  string      `json:"kind"`
+	Span  *jsonSpan   `json:"span"`
+	Range *jsonSpan   `json:"range"`
+	Type  *jsonType   `json:"type,omitempty"`
+	Name  string      `json:"name,omitempty"`
+	Value *int        `json:"value,omitempty"`
+	Lhs   *jsonNode   `json:"lhs,omitempty"`
+	Rhs   *jsonNode   `json:"rhs,omitempty"`
+	Init  *jsonNode   `json:"init,omitempty"`
+	Cond  *jsonNode   `json:"cond,omitempty"`
+	Inc   *jsonNode   `json:"inc,omitempty"`
+	Then  *jsonNode   `json:"then,omitempty"`
+	Else  *jsonNode   `json:"else,omitempty"`
+	Body  []*jsonNode `json:"body,omitempty"`
+}
+
+func DumpASTJSON(out io.Writer, program *Function) {
+	fn := &jsonFunction{
+		Version: jsonASTVersion,
+		Name:    program.Name,
+		File:    program.File.Name,
+		Locals:  []*jsonVariable{},
+		Body:    jsonNodes(program.Body),
+	}
+	// Locals are kept newest first.
+	for v := program.Locals; v != nil; v = v.Next {
+		variable := &jsonVariable{Name: v.name, Type: jsonTypeOf(v.Type), Span: jsonSpanOf(v.token)}
+		fn.Locals = append([]*jsonVariable{variable}, fn.Locals...)
+	}
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	enc.Encode(fn)
+}
+
+func jsonTypeOf(t *types.Type) *jsonType {
+	if t == nil {
+		return nil
+	}
+	if t.Kind == types.TPPTR {
+		return &jsonType{Kind: "pointer", Size: t.Size, Base: jsonTypeOf(t.Base)}
+	}
+	return &jsonType{Kind: "int", Size: t.Size}
+}
+
+func jsonSpanOf(tok *token.Token) *jsonSpan {
+	return jsonSpanBetween(tok, tok)
+}
+
+// Return the span from the beginning of `first` to the end of `last`.
+func jsonSpanBetween(first *token.Token, last *token.Token) *jsonSpan {
+	position := func(offset int) jsonPosition {
+		line, column := first.File.Position(offset)
+		return jsonPosition{Offset: offset, Line: line, Column: column}
+	}
+	return &jsonSpan{Begin: position(first.Begin), End: position(last.Begin + last.Length)}
+}
+
+func jsonNodes(list *Node) []*jsonNode {
+	nodes := []*jsonNode{}
+	for n := list; n != nil; n = n.Next {
+		nodes = append(nodes, jsonNodeOf(n))
+	}
+	return nodes
+}
+
+func jsonNodeOf(node *Node) *jsonNode {
+	if node == nil {
+		return nil
+	}
+	n := &jsonNode{
+		Kind:  nodeNames[node.Kind],
+		Span:  jsonSpanOf(node.Token),
+		Range: jsonSpanBetween(node.first, node.last),
+		Type:  jsonTypeOf(node.Type),
+		Lhs:   jsonNodeOf(node.Lhs),
+		Rhs:   jsonNodeOf(node.Rhs),
+		Init:  jsonNodeOf(node.Initializer),
+		Cond:  jsonNodeOf(node.Condition),
+		Inc:   jsonNodeOf(node.Increment),
+		Then:  jsonNodeOf(node.ThenBranch),
+		Else:  jsonNodeOf(node.ElseBranch),
+	}
+	switch node.Kind {
+	case NodeVar:
+		n.Name = node.Variable.name
+	case NodeNum:
+		value := node.Value
+		n.Value = &value
+	case NodeBlock:
+		n.Body = jsonNodes(node.Body)
+	}
+	return n
+}
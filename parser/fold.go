@@ -0,0 +1,229 @@
+package parser
+
+// Constant folding
+//
+// This pass runs on the typed AST. It evaluates operators whose operands
+// are all constants, drops identities such as `x*1` and `x+0`, and
+// removes if/for branches whose condition is known at compile time.
+//
+// EvalConst evaluates constant expressions the same way without folding
+// them, for the parser to check operands and for tools.
+
+func FoldProgram(program *Function) {
+	program.Body = foldList(program.Body)
+}
+
+// Fold every statement of a linked list, relinking the
+// results since a statement may be replaced by another.
+func foldList(list *Node) *Node {
+	head := Node{}
+	curr := &head
+	for n := list; n != nil; {
+		next := n.Next
+		curr.Next = fold(n)
+		curr = curr.Next
+		curr.Next = nil
+		n = next
+	}
+	return head.Next
+}
+
+// The operands of `=` and `&` must stay addressable, so only
+// their children are folded. Otherwise `(x+0) = 1` would turn
+// into a valid assignment.
+func foldChildren(node *Node) {
+	if node.Kind == NodeAsg || node.Kind == NodeAddr {
+		foldChildren(node.Lhs)
+	} else {
+		node.Lhs = fold(node.Lhs)
+	}
+	node.Rhs = fold(node.Rhs)
+	node.Condition = fold(node.Condition)
+	node.ThenBranch = fold(node.ThenBranch)
+	node.ElseBranch = fold(node.ElseBranch)
+	node.Initializer = fold(node.Initializer)
+	node.Increment = fold(node.Increment)
+	node.Body = foldList(node.Body)
+}
+
+func isnum(node *Node, value int) bool {
+	return node.Kind == NodeNum && node.Value == value
+}
+
+func fold(node *Node) *Node {
+	if node == nil {
+		return nil
+	}
+	foldChildren(node)
+	switch node.Kind {
+	case NodeIf:
+		if node.Condition.Kind != NodeNum {
+			return node
+		}
+		if node.Condition.Value != 0 {
+			return node.ThenBranch
+		}
+		if node.ElseBranch != nil {
+			return node.ElseBranch
+		}
+		return emptyStmt(node)
+	case NodeFor:
+		if node.Condition == nil || node.Condition.Kind != NodeNum {
+			return node
+		}
+		if node.Condition.Value != 0 {
+			node.Condition = nil
+			return node
+		}
+		// The body never runs, only the initializer is left.
+		if node.Initializer != nil {
+			return node.Initializer
+		}
+		return emptyStmt(node)
+	case NodeNeg:
+		if node.Lhs.Kind == NodeNum {
+			return foldedNumber(-node.Lhs.Value, node)
+		}
+		return node
+	case NodeAdd, NodeSub, NodeMul, NodeDiv, NodeEql, NodeNeq, NodeLss, NodeLeq:
+		return foldBinary(node)
+	}
+	return node
+}
+
+func foldBinary(node *Node) *Node {
+	lhs, rhs := node.Lhs, node.Rhs
+	if lhs.Kind == NodeNum && rhs.Kind == NodeNum {
+		switch node.Kind {
+		case NodeAdd:
+			return foldedNumber(lhs.Value+rhs.Value, node)
+		case NodeSub:
+			return foldedNumber(lhs.Value-rhs.Value, node)
+		case NodeMul:
+			return foldedNumber(lhs.Value*rhs.Value, node)
+		case NodeDiv:
+			// Leave division by zero to run time.
+			if rhs.Value != 0 {
+				return foldedNumber(lhs.Value/rhs.Value, node)
+			}
+		case NodeEql:
+			return foldedNumber(btoi(lhs.Value == rhs.Value), node)
+		case NodeNeq:
+			return foldedNumber(btoi(lhs.Value != rhs.Value), node)
+		case NodeLss:
+			return foldedNumber(btoi(lhs.Value < rhs.Value), node)
+		case NodeLeq:
+			return foldedNumber(btoi(lhs.Value <= rhs.Value), node)
+		}
+		return node
+	}
+	switch node.Kind {
+	case NodeAdd:
+		if isnum(rhs, 0) {
+			return lhs
+		}
+		if isnum(lhs, 0) {
+			return rhs
+		}
+	case NodeSub:
+		if isnum(rhs, 0) {
+			return lhs
+		}
+	case NodeMul:
+		if isnum(rhs, 1) {
+			return lhs
+		}
+		if isnum(lhs, 1) {
+			return rhs
+		}
+	case NodeDiv:
+		if isnum(rhs, 1) {
+			return lhs
+		}
+	}
+	return node
+}
+
+// Create a number node replacing `node`, keeping its type, token and
+// span. Results of type int wrap around to 32 bits like at run time.
+func foldedNumber(value int, node *Node) *Node {
+	num := NewNumber(wrap(value, node), node.Token)
+	num.Type = node.Type
+	return num.spanLike(node)
+}
+
+// Create an empty statement replacing `node`, keeping its token and span.
+func emptyStmt(node *Node) *Node {
+	return NewNode(NodeBlock, node.Token).spanLike(node)
+}
+
+// Gives the value of an expression that EvalConst cannot evaluate on its
+// own, such as a variable, and whether it has one. Tools evaluating
+// expressions of their own, like the conditions of #if, resolve their
+// identifiers with it, and so will enumerators and sizeof once they exist.
+type Resolver func(node *Node) (int, bool)
+
+// Return the value of the expression `node` and true if it is a constant
+// expression, without changing it. Numbers and the arithmetic and
+// comparison operators are evaluated like at run time, and any other
+// node is given to `resolve`, which may be nil to resolve none. Division
+// by zero has no value.
+func EvalConst(node *Node, resolve Resolver) (int, bool) {
+	switch node.Kind {
+	case NodeNum:
+		return node.Value, true
+	case NodeNeg:
+		value, ok := EvalConst(node.Lhs, resolve)
+		return wrap(-value, node), ok
+	case NodeAdd, NodeSub, NodeMul, NodeDiv, NodeEql, NodeNeq, NodeLss, NodeLeq:
+		lhs, ok := EvalConst(node.Lhs, resolve)
+		if !ok {
+			return 0, false
+		}
+		rhs, ok := EvalConst(node.Rhs, resolve)
+		if !ok {
+			return 0, false
+		}
+		switch node.Kind {
+		case NodeAdd:
+			return wrap(lhs+rhs, node), true
+		case NodeSub:
+			return wrap(lhs-rhs, node), true
+		case NodeMul:
+			return wrap(lhs*rhs, node), true
+		case NodeDiv:
+			if rhs == 0 {
+				return 0, false
+			}
+			return wrap(lhs/rhs, node), true
+		case NodeEql:
+			return btoi(lhs == rhs), true
+		case NodeNeq:
+			return btoi(lhs != rhs), true
+		case NodeLss:
+			return btoi(lhs < rhs), true
+		case NodeLeq:
+			return btoi(lhs <= rhs), true
+		}
+	}
+	if resolve != nil {
+		return resolve(node)
+	}
+	return 0, false
+}
+
+// Wrap `value` around to 32 bits if `node` has type int.
+func wrap(value int, node *Node) int {
+	addtype(node)
+	if node.Type.Size == 4 {
+		return int(int32(value))
+	}
+	return value
+}
+
+func btoi(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
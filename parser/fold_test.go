@@ -0,0 +1,35 @@
+package parser
+
+import "testing"
+
+func TestEvalConst(t *testing.T) {
+	x := func(node *Node) (int, bool) {
+		if node.Kind == NodeVar && node.Variable.Name() == "x" {
+			return 4, true
+		}
+		return 0, false
+	}
+	for _, c := range []struct {
+		src     string
+		resolve Resolver
+		value   int
+		ok      bool
+	}{
+		{"return -(7 + 2) * 3 <= 1 == 1;", nil, 1, true},
+		{"return 2147483647 + 1;", nil, -2147483648, true},
+		{"return 1 / 0;", nil, 0, false},
+		{"int x = 1; return x * 3 + 1;", nil, 0, false},
+		{"int x = 1; return x * 3 + 1;", x, 13, true},
+		{"int x = 1, y; return x - y;", x, 0, false},
+	} {
+		program := parseString(t, c.src)
+		var expr *Node
+		for n := program.Body; n != nil; n = n.Next {
+			expr = n.Lhs
+		}
+		value, ok := EvalConst(expr, c.resolve)
+		if value != c.value || ok != c.ok {
+			t.Errorf("%q evaluates to %d, %v, want %d, %v", c.src, value, ok, c.value, c.ok)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package parser
+
+import (
+	"github.com/youngfr/gocc/token"
+	"github.com/youngfr/gocc/types"
+)
+
+// Semantic information
+//
+// NewInfo gathers what the parser found out about a program while
+// typing it, for tools such as editors to query: the variables it
+// declares, the declaration each identifier refers to, and the type of
+// each expression keyed by its source range. The queries take positions
+// in the file set of the session, see token.File.Pos for offsets.
+//
+// Nodes made by the parser for the scaling of pointer arithmetic have
+// the range of the operand they scale, which is reported instead.
+
+// A source range, from the first byte of a node to the byte following it.
+type Range struct {
+	Pos token.Pos
+	End token.Pos
+}
+
+// Return whether `pos` is within the range.
+func (r Range) Contains(pos token.Pos) bool {
+	return r.Pos <= pos && pos < r.End
+}
+
+type Info struct {
+	Program *Function
+
+	// Variables of the program, in order of declaration.
+	Objects []*Object
+
+	// Declaration of each identifier declaring a variable.
+	Defs map[*token.Token]*Object
+
+	// Declaration of each identifier referring to a variable.
+	Uses map[*token.Token]*Object
+
+	// Type of each expression, keyed by its source range. When
+	// expressions have the same range, such as "(a)" and the "a"
+	// within, the type is that of the outermost one.
+	Types map[Range]*types.Type
+}
+
+// Return the semantic information of `program`, which must not have
+// been changed by an AST pass. If it has errors, the statements with
+// errors are not part of it, and neither is their information.
+func NewInfo(program *Function) *Info {
+	info := &Info{
+		Program: program,
+		Defs:    map[*token.Token]*Object{},
+		Uses:    map[*token.Token]*Object{},
+		Types:   map[Range]*types.Type{},
+	}
+	// Locals are kept newest first.
+	for o := program.Locals; o != nil; o = o.Next {
+		info.Objects = append([]*Object{o}, info.Objects...)
+	}
+	InspectList(program.Body, func(node *Node) bool {
+		if node.Kind.IsStmt() || isScale(node) {
+			return true
+		}
+		if r := rangeOf(node); info.Types[r] == nil {
+			info.Types[r] = node.Type
+		}
+		if node.Kind == NodeVar {
+			if node.Token == node.Variable.token {
+				info.Defs[node.Token] = node.Variable
+			} else {
+				info.Uses[node.Token] = node.Variable
+			}
+		}
+		return true
+	})
+	return info
+}
+
+func rangeOf(node *Node) Range {
+	return Range{node.Pos(), node.End()}
+}
+
+// Return the innermost expression whose range holds `pos`, or nil.
+func (info *Info) ExprAt(pos token.Pos) *Node {
+	var found *Node
+	InspectList(info.Program.Body, func(node *Node) bool {
+		if !rangeOf(node).Contains(pos) {
+			return false
+		}
+		if !node.Kind.IsStmt() && !isScale(node) {
+			found = node
+		}
+		return true
+	})
+	return found
+}
+
+// Return the type of the innermost expression whose range holds `pos`, or nil.
+func (info *Info) TypeAt(pos token.Pos) *types.Type {
+	if node := info.ExprAt(pos); node != nil {
+		return node.Type
+	}
+	return nil
+}
+
+// Return the variable declared or referred to by the
+// identifier at `pos`, and that identifier, or nil.
+func (info *Info) ObjectAt(pos token.Pos) (*Object, *token.Token) {
+	for _, idents := range []map[*token.Token]*Object{info.Defs, info.Uses} {
+		for tok, o := range idents {
+			if tok.Pos() <= pos && pos < tok.Pos()+token.Pos(tok.Length) {
+				return o, tok
+			}
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,63 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInfo(t *testing.T) {
+	src := "int a = 1, *p = &a;\nreturn *p + (a);\n"
+	program := parseString(t, src)
+	info := NewInfo(program)
+	file := program.File
+	at := func(s string) int {
+		i := strings.LastIndex(src, s)
+		if i < 0 {
+			t.Fatalf("%q is not in the source", s)
+		}
+		return i
+	}
+
+	if len(info.Objects) != 2 || info.Objects[0].Name() != "a" || info.Objects[1].Name() != "p" {
+		t.Fatalf("objects %v, want a and p in order", info.Objects)
+	}
+	if len(info.Defs) != 2 || len(info.Uses) != 3 {
+		t.Errorf("%d definitions and %d uses, want 2 and 3", len(info.Defs), len(info.Uses))
+	}
+
+	for _, c := range []struct {
+		offset int
+		expr   string // Source of the innermost expression at the offset
+		typ    string
+	}{
+		{at("*p +"), "*p", "int"},
+		{at("p +"), "p", "int*"},
+		{at("+ ("), "*p + (a)", "int"},
+		{at("(a)"), "(a)", "int"},
+		{at("&a"), "&a", "int*"},
+		{at("1,"), "1", "int"},
+	} {
+		node := info.ExprAt(file.Pos(c.offset))
+		if node == nil {
+			t.Errorf("no expression at %d", c.offset)
+			continue
+		}
+		if node.Source() != c.expr || node.Type.String() != c.typ {
+			t.Errorf("expression at %d is %q of type %s, want %q of type %s", c.offset, node.Source(), node.Type, c.expr, c.typ)
+		}
+		if typ := info.Types[Range{node.Pos(), node.End()}]; typ.String() != c.typ {
+			t.Errorf("type of %q is %s, want %s", c.expr, typ, c.typ)
+		}
+	}
+	if node := info.ExprAt(file.Pos(at("return"))); node != nil {
+		t.Errorf("expression %q at a keyword", node.Source())
+	}
+
+	o, tok := info.ObjectAt(file.Pos(at("p +")))
+	if o == nil || o.Name() != "p" || tok == o.Decl() {
+		t.Errorf("the use of p refers to %v", o)
+	}
+	if o, tok := info.ObjectAt(file.Pos(at("a = 1"))); o == nil || tok != o.Decl() {
+		t.Errorf("the declaration of a is not a definition of it")
+	}
+}
@@ -0,0 +1,689 @@
+// Package parser parses a token list into an AST, checking its types
+// and folding its constants along the way.
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/youngfr/gocc/arena"
+	"github.com/youngfr/gocc/scope"
+	"github.com/youngfr/gocc/token"
+	"github.com/youngfr/gocc/types"
+)
+
+// This file contains a recursive descent parser for C.
+//
+// Most functions in this file are named after the symbols they are
+// supposed to read from an input token list. For example, stmt() is
+// responsible for reading a statement from a token list. The function
+// then construct an AST node representing a statement.
+//
+// Input tokens are read from a token.Stream over the tokens made by
+// the lexer. Each function consumes the tokens of its symbol from the
+// stream and returns the node it built, leaving the stream at the token
+// that follows. The tokens themselves do not change, so the parser can
+// look ahead any number of tokens with Peek, and go back to a Mark to
+// parse the same tokens another way.
+//
+// On an error, the parser records it and abandons the statement being
+// parsed by panicking with a bailout. The statement is dropped, and
+// parsing resumes after the next ";" or at the "}" closing the
+// enclosing block, so that one run reports as many errors as possible.
+
+// State of the parser for one translation unit.
+type parser struct {
+	session *token.Session
+	ts      *token.Stream // Tokens of the translation unit
+
+	// All local variable instances created during
+	// parsing are accumulated to this linked list.
+	locals *Object
+
+	// Scope of the block being parsed
+	scope *scope.Scope[*Object]
+
+	depth int // Nesting of grammar functions, with --trace-parse
+}
+
+// NewLvar creates a new local variable instance declared by `tok`,
+// declares it in the current scope and inserts it into the head of
+// the `locals` linked list.
+func (p *parser) NewLvar(tok *token.Token, tp *types.Type) *Object {
+	variable := &Object{
+		Next:  p.locals,
+		name:  getIdent(tok),
+		Type:  tp,
+		token: tok,
+	}
+	if prev, ok := p.scope.Insert(scope.Ordinary, variable.name, variable); !ok {
+		tok.SemanticErrorf("redefinition of \"%s\"", prev.name).
+			Note(prev.token, "previous declaration is here")
+	} else if prev, _, ok := p.scope.Parent.Lookup(scope.Ordinary, variable.name); ok {
+		tok.Warnf("shadow", "declaration of \"%s\" shadows a previous local", prev.name).
+			Note(prev.token, "previous declaration is here")
+	}
+	p.locals = variable
+	return variable
+}
+
+// Find the variable `tok` refers to in the current scope.
+func (p *parser) findVar(tok *token.Token) *Object {
+	variable, _, _ := p.scope.Lookup(scope.Ordinary, tok.Lexeme)
+	return variable
+}
+
+// Open the scope of a block, nested in the current one.
+func (p *parser) enterScope() {
+	p.scope = scope.New(p.scope)
+}
+
+// Close the scope of the block, returning to the enclosing one.
+func (p *parser) leaveScope() {
+	p.scope = p.scope.Parent
+}
+
+// Return a node for `tok`, allocated from the arenas of its session.
+func NewNode(kind NodeKind, tok *token.Token) *Node {
+	node := arena.Of[Node](tok.File.Arenas()).New()
+	node.Kind = kind
+	node.Token = tok
+	node.first = tok
+	node.last = tok
+	return node
+}
+
+func NewBinary(kind NodeKind, lhs *Node, rhs *Node, tok *token.Token) *Node {
+	node := NewNode(kind, tok)
+	node.Lhs = lhs
+	node.Rhs = rhs
+	node.cover(lhs)
+	node.cover(rhs)
+	return node
+}
+
+func NewNumber(value int, tok *token.Token) *Node {
+	node := NewNode(NodeNum, tok)
+	node.Value = value
+	return node
+}
+
+// NewAdd In C, `+` operator is overloaded to perform the pointer arithmetic.
+// If p is a pointer, p+n adds not n but sizeof(*p)*n to the value of p,
+// so that p+n points to the location n elements (not bytes) ahead of p.
+// In other words, we need to scale an integer value before adding to a
+// pointer value.
+func NewAdd(lhs *Node, rhs *Node, tok *token.Token) *Node {
+	addtype(lhs)
+	addtype(rhs)
+	// num + num
+	if types.IsInt(lhs.Type) && types.IsInt(rhs.Type) {
+		return NewBinary(NodeAdd, lhs, rhs, tok)
+	}
+	// ptr + ptr
+	if lhs.Type.Base != nil && rhs.Type.Base != nil {
+		failSemantic(tok, "invalid operands")
+	}
+	// num + ptr -> ptr + num
+	if lhs.Type.Base == nil && rhs.Type.Base != nil {
+		lhs, rhs = rhs, lhs
+	}
+	// ptr + num
+	rhs = NewBinary(NodeMul, rhs, NewNumber(lhs.Type.Base.Size, tok).spanLike(rhs), tok).spanLike(rhs)
+	return NewBinary(NodeAdd, lhs, rhs, tok)
+}
+
+// NewSub `-` operator is also overloaded to perform the pointer arithmetic.
+func NewSub(lhs *Node, rhs *Node, tok *token.Token) *Node {
+	addtype(lhs)
+	addtype(rhs)
+	// num - num
+	if types.IsInt(lhs.Type) && types.IsInt(rhs.Type) {
+		return NewBinary(NodeSub, lhs, rhs, tok)
+	}
+	// ptr - num
+	if lhs.Type.Base != nil && types.IsInt(rhs.Type) {
+		rhs = NewBinary(NodeMul, rhs, NewNumber(lhs.Type.Base.Size, tok).spanLike(rhs), tok).spanLike(rhs)
+		addtype(rhs)
+		node := NewBinary(NodeSub, lhs, rhs, tok)
+		node.Type = lhs.Type
+		return node
+	}
+	// num - ptr
+	if types.IsInt(lhs.Type) && rhs.Type.Base != nil {
+		failSemantic(tok, "invalid operands")
+	}
+	// ptr - ptr
+	node := NewBinary(NodeSub, lhs, rhs, tok)
+	node.Type = types.Int
+	return NewBinary(NodeDiv, node, NewNumber(lhs.Type.Base.Size, tok).spanLike(node), tok)
+}
+
+func NewUnary(kind NodeKind, expr *Node, tok *token.Token) *Node {
+	node := NewNode(kind, tok)
+	node.Lhs = expr
+	node.cover(expr)
+	return node
+}
+
+// Return whether `node` designates an object, which can be
+// assigned to and have its address taken.
+func islvalue(node *Node) bool {
+	return node.Kind == NodeVar || node.Kind == NodeDeref
+}
+
+func NewVar(variable *Object, tok *token.Token) *Node {
+	node := NewNode(NodeVar, tok)
+	node.Variable = variable
+	return node
+}
+
+// program -> stmt* EOF
+//
+// Return the program, without the statements that have errors, and
+// the errors recorded for its file, including those of the lexer.
+func Parse(tokens []token.Token) (*Function, error) {
+	file := tokens[0].File
+	p := &parser{session: file.Session, ts: token.NewStream(tokens), scope: scope.New[*Object](nil)}
+	head := Node{}
+	curr := &head
+	for p.ts.Peek(0).Kind != token.EOF {
+		curr.Next = p.recoverStmt()
+		curr = curr.Next
+	}
+	for v := p.locals; v != nil; v = v.Next {
+		if !v.used {
+			v.token.Warnf("unused-variable", "unused variable \"%s\"", v.name)
+		} else if v.reads == 0 {
+			v.token.Warnf("unused-but-set-variable", "variable \"%s\" set but not used", v.name)
+		}
+	}
+	if fallsOffWithoutValue(head.Next) {
+		// Like gcc at the "}" of a function, point at the last token.
+		last := &tokens[len(tokens)-1]
+		if len(tokens) > 1 {
+			last = &tokens[len(tokens)-2]
+		}
+		last.Warnf("return-type", "control reaches end of non-void function")
+	}
+	// The whole program is the body of an implicit main function.
+	program := &Function{
+		Name:   "main",
+		File:   file,
+		Body:   head.Next,
+		Locals: p.locals,
+	}
+	return program, file.Err()
+}
+
+// Return whether control can reach the end of the statements from
+// `node` on a path running neither a return nor an expression
+// statement. When main falls off its end, it returns the value of the
+// last expression statement run, so on such a path it has none. As
+// with gcc, conditions are not evaluated, except for loops that only a
+// return leaves.
+func fallsOffWithoutValue(node *Node) bool {
+	for ; node != nil; node = node.Next {
+		switch node.Kind {
+		case NodeExprStmt, NodeReturn:
+			return false
+		case NodeBlock:
+			if !fallsOffWithoutValue(node.Body) {
+				return false
+			}
+		case NodeIf:
+			if node.ElseBranch != nil && !fallsOffWithoutValue(node.ThenBranch) && !fallsOffWithoutValue(node.ElseBranch) {
+				return false
+			}
+		case NodeFor:
+			if node.Condition == nil || node.Condition.Kind == NodeNum && node.Condition.Value != 0 {
+				return false
+			}
+			if node.Initializer != nil && !fallsOffWithoutValue(node.Initializer) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Log entering the grammar function `name` at the next token with
+// --trace-parse, and return a function logging leaving it with the
+// token that follows, for the grammar function to defer.
+func (p *parser) trace(name string) func() {
+	if p.session.Trace == nil {
+		return func() {}
+	}
+	tok := p.ts.Peek(0)
+	line, column := tok.Position()
+	fmt.Fprintf(p.session.Trace, "%s> %s at %d:%d %q\n", strings.Repeat("  ", p.depth), name, line, column, tok.Lexeme)
+	p.depth++
+	return func() {
+		p.depth--
+		indent := strings.Repeat("  ", p.depth)
+		if r := recover(); r != nil {
+			fmt.Fprintf(p.session.Trace, "%s< %s abandoned\n", indent, name)
+			panic(r)
+		}
+		tok := p.ts.Peek(0)
+		line, column := tok.Position()
+		fmt.Fprintf(p.session.Trace, "%s< %s, next %d:%d %q\n", indent, name, line, column, tok.Lexeme)
+	}
+}
+
+// Raised by fail() to abandon the statement being parsed.
+type bailout struct {
+	token *token.Token // Token the error was found at
+}
+
+// Record a syntax error at `tok` and abandon the statement being parsed.
+func fail(tok *token.Token, format string, args ...any) {
+	tok.Errorf(format, args...)
+	panic(bailout{tok})
+}
+
+// Record a semantic error at `tok` and abandon the statement being parsed.
+func failSemantic(tok *token.Token, format string, args ...any) {
+	tok.SemanticErrorf(format, args...)
+	panic(bailout{tok})
+}
+
+// Parse and type a statement. If it has an error, return an empty
+// statement in its place and skip to where the next one begins.
+func (p *parser) recoverStmt() (node *Node) {
+	start := p.ts.Peek(0)
+	p.session.CurrentStatement = start
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		b, ok := r.(bailout)
+		if !ok {
+			panic(r)
+		}
+		p.synchronize(b.token)
+		// Always make progress, even if the error was at a "}"
+		// with no block to close.
+		if p.ts.Peek(0) == start {
+			p.ts.Next()
+		}
+		node = NewNode(NodeBlock, start)
+	}()
+	node = p.stmt()
+	addtype(node)
+	return
+}
+
+// Move the stream from `tok` to the token following the next ";", or
+// to the "}" closing the enclosing block, skipping nested blocks on the
+// way.
+func (p *parser) synchronize(tok *token.Token) {
+	p.ts.Reset(tok.Index)
+	depth := 0
+	for tok := p.ts.Peek(0); tok.Kind != token.EOF; tok = p.ts.Peek(0) {
+		switch {
+		case equal(tok, "{"):
+			depth++
+		case equal(tok, "}") && depth == 0:
+			return
+		case equal(tok, "}"):
+			depth--
+		case equal(tok, ";") && depth == 0:
+			p.ts.Next()
+			return
+		}
+		p.ts.Next()
+	}
+}
+
+func equal(tok *token.Token, lexeme string) bool {
+	return tok.Lexeme == lexeme
+}
+
+// Whether the next token is `lexeme`, without consuming it.
+func (p *parser) at(lexeme string) bool {
+	return equal(p.ts.Peek(0), lexeme)
+}
+
+// Consume the token `lexeme` and return it, or record a
+// syntax error and abandon the statement being parsed.
+func (p *parser) expect(lexeme string) *token.Token {
+	tok, err := p.ts.Expect(lexeme)
+	if err != nil {
+		panic(bailout{tok})
+	}
+	return tok
+}
+
+// stmt -> "return" expr ";"
+// -->   | "{" block
+// -->   | "if" "(" expr ")" stmt ( "else" stmt )?
+// -->   | "for" "(" ( exprStmt | declaration ) expr? ";" expr? ")" stmt
+// -->   | "while" "(" expr ")" stmt
+// -->   | exprStmt
+// -->   | declaration
+func (p *parser) stmt() *Node {
+	defer p.trace("stmt")()
+	tok := p.ts.Peek(0)
+	if p.ts.Accept("return") != nil {
+		node := NewUnary(NodeReturn, p.expr(), tok)
+		// The implicit main returns int.
+		checkAssign("return", types.Int, node.Lhs)
+		node.widen(tok, p.expect(";"))
+		return node
+	}
+	if p.ts.Accept("{") != nil {
+		node := p.block()
+		node.widen(tok, tok)
+		return node
+	}
+	if p.ts.Accept("if") != nil {
+		node := NewNode(NodeIf, tok)
+		p.expect("(")
+		node.Condition = p.expr()
+		p.expect(")")
+		node.ThenBranch = p.subStmt()
+		if p.ts.Accept("else") != nil {
+			node.ElseBranch = p.subStmt()
+		}
+		node.cover(node.ThenBranch)
+		node.cover(node.ElseBranch)
+		return node
+	}
+	if p.ts.Accept("for") != nil {
+		node := NewNode(NodeFor, tok)
+		p.expect("(")
+		// Variables declared by the initializer are in the scope of the loop.
+		p.enterScope()
+		defer p.leaveScope()
+		if p.at("int") {
+			if !p.session.StandardAtLeast(1999) {
+				p.ts.Peek(0).Errorf("\"for\" loop initial declarations require -std=c99 or later")
+			}
+			node.Initializer = p.declaration()
+		} else {
+			node.Initializer = p.exprStmt()
+		}
+		if !p.at(";") {
+			node.Condition = p.expr()
+		}
+		p.expect(";")
+		if !p.at(")") {
+			node.Increment = p.expr()
+		}
+		p.expect(")")
+		node.ThenBranch = p.subStmt()
+		node.cover(node.ThenBranch)
+		return node
+	}
+	if p.ts.Accept("while") != nil {
+		node := NewNode(NodeFor, tok)
+		p.expect("(")
+		node.Condition = p.expr()
+		p.expect(")")
+		node.ThenBranch = p.subStmt()
+		node.cover(node.ThenBranch)
+		return node
+	}
+	if p.at("int") {
+		return p.declaration()
+	}
+	return p.exprStmt()
+}
+
+// declspec -> "int"
+func (p *parser) declspec() *types.Type {
+	p.expect("int")
+	return types.Int
+}
+
+// declarator -> "*"* ident
+func (p *parser) declarator(tp *types.Type) *types.Type {
+	for p.ts.Accept("*") != nil {
+		tp = types.PointerTo(tp)
+	}
+	if p.ts.Peek(0).Kind != token.IDENT {
+		fail(p.ts.Peek(0), "expected a variable name")
+	}
+	// Types such as types.Int are shared, so name a copy.
+	named := *tp
+	named.Name = p.ts.Next()
+	return &named
+}
+
+func getIdent(tok *token.Token) string {
+	if tok.Kind != token.IDENT {
+		fail(tok, "expected an identifier")
+	}
+	return tok.Lexeme
+}
+
+// declaration -> declspec (declarator ( "=" expr )?) ( "," declarator ( "=" expr )?)* ";"
+func (p *parser) declaration() *Node {
+	defer p.trace("declaration")()
+	first := p.ts.Peek(0)
+	baseType := p.declspec()
+	head := Node{}
+	curr := &head
+	for {
+		tp := p.declarator(baseType)
+		variable := p.NewLvar(tp.Name, tp)
+		if start := p.ts.Accept("="); start != nil {
+			init := p.expr()
+			checkAssign("initialization", tp, init)
+			curr.Next = NewUnary(NodeExprStmt, NewBinary(NodeAsg, NewVar(variable, tp.Name), init, start), tp.Name)
+		} else {
+			curr.Next = NewUnary(NodeExprStmt, NewVar(variable, tp.Name), tp.Name)
+		}
+		curr = curr.Next
+		if p.ts.Peek(0).Kind == token.EOF || p.at(";") {
+			break
+		}
+		p.expect(",")
+	}
+	tok := p.ts.Peek(0)
+	node := NewNode(NodeBlock, tok)
+	node.Body = head.Next
+	p.expect(";")
+	node.widen(first, tok)
+	return node
+}
+
+// Parse the body of an if, for or while statement. Like in C99, it has
+// a scope of its own even if it is not a block.
+func (p *parser) subStmt() *Node {
+	p.enterScope()
+	defer p.leaveScope()
+	return p.stmt()
+}
+
+// block -> stmt* "}"
+func (p *parser) block() *Node {
+	defer p.trace("block")()
+	p.enterScope()
+	defer p.leaveScope()
+	node := NewNode(NodeBlock, p.ts.Peek(0))
+	// statements' linked list
+	head := Node{}
+	curr := &head
+	for p.ts.Peek(0).Kind != token.EOF && !p.at("}") {
+		curr.Next = p.recoverStmt()
+		curr = curr.Next
+	}
+	node.Body = head.Next
+	closing := p.expect("}")
+	node.widen(closing, closing)
+	return node
+}
+
+// exprStmt -> expr? ";"
+func (p *parser) exprStmt() *Node {
+	defer p.trace("exprStmt")()
+	if tok := p.ts.Accept(";"); tok != nil {
+		return NewNode(NodeBlock, tok)
+	}
+	start := p.ts.Peek(0)
+	node := NewUnary(NodeExprStmt, p.expr(), start)
+	node.widen(start, p.expect(";"))
+	return node
+}
+
+// expr -> assign
+func (p *parser) expr() *Node {
+	defer p.trace("expr")()
+	return p.assign()
+}
+
+// assign -> equality ( "=" assign )?
+func (p *parser) assign() (node *Node) {
+	defer p.trace("assign")()
+	node = p.equality()
+	if start := p.ts.Accept("="); start != nil {
+		// Assigning to a variable does not read it.
+		if node.Kind == NodeVar {
+			node.Variable.reads--
+		}
+		if !islvalue(node) {
+			start.SemanticErrorf("lvalue required as left operand of assignment")
+		}
+		node = NewBinary(NodeAsg, node, p.assign(), start)
+		addtype(node.Lhs)
+		checkAssign("assignment", node.Lhs.Type, node.Rhs)
+	}
+	return
+}
+
+// equality -> relational ( "==" relational | "!=" relational )*
+func (p *parser) equality() (node *Node) {
+	defer p.trace("equality")()
+	node = p.relational()
+	for {
+		if start := p.ts.Accept("=="); start != nil {
+			node = NewBinary(NodeEql, node, p.relational(), start)
+			continue
+		}
+		if start := p.ts.Accept("!="); start != nil {
+			node = NewBinary(NodeNeq, node, p.relational(), start)
+			continue
+		}
+		return
+	}
+}
+
+// relational -> addsub ( "<" addsub | "<=" addsub | ">" addsub | ">=" addsub )*
+func (p *parser) relational() (node *Node) {
+	defer p.trace("relational")()
+	node = p.addsub()
+	for {
+		if start := p.ts.Accept("<"); start != nil {
+			node = NewBinary(NodeLss, node, p.addsub(), start)
+			continue
+		}
+		if start := p.ts.Accept("<="); start != nil {
+			node = NewBinary(NodeLeq, node, p.addsub(), start)
+			continue
+		}
+		if start := p.ts.Accept(">"); start != nil {
+			node = NewBinary(NodeLss, p.addsub(), node, start)
+			continue
+		}
+		if start := p.ts.Accept(">="); start != nil {
+			node = NewBinary(NodeLeq, p.addsub(), node, start)
+			continue
+		}
+		return
+	}
+}
+
+// addsub -> muldiv ( "+" muldiv | "-" muldiv )*
+func (p *parser) addsub() (node *Node) {
+	defer p.trace("addsub")()
+	node = p.muldiv()
+	for {
+		if start := p.ts.Accept("+"); start != nil {
+			node = NewAdd(node, p.muldiv(), start)
+			continue
+		}
+		if start := p.ts.Accept("-"); start != nil {
+			node = NewSub(node, p.muldiv(), start)
+			continue
+		}
+		return
+	}
+}
+
+// muldiv -> unary ( "*" unary | "/" unary )*
+func (p *parser) muldiv() (node *Node) {
+	defer p.trace("muldiv")()
+	node = p.unary()
+	for {
+		if start := p.ts.Accept("*"); start != nil {
+			node = NewBinary(NodeMul, node, p.unary(), start)
+			continue
+		}
+		if start := p.ts.Accept("/"); start != nil {
+			node = NewBinary(NodeDiv, node, p.unary(), start)
+			if value, ok := EvalConst(node.Rhs, nil); ok && value == 0 {
+				start.Warnf("div-by-zero", "division by zero")
+			}
+			continue
+		}
+		return
+	}
+}
+
+// unary -> ( "+" | "-" | "*" | "&" ) unary
+// -->    | primary
+func (p *parser) unary() *Node {
+	defer p.trace("unary")()
+	tok := p.ts.Peek(0)
+	if p.ts.Accept("+") != nil {
+		node := p.unary()
+		node.widen(tok, tok)
+		return node
+	}
+	if p.ts.Accept("-") != nil {
+		return NewUnary(NodeNeg, p.unary(), tok)
+	}
+	if p.ts.Accept("*") != nil {
+		return NewUnary(NodeDeref, p.unary(), tok)
+	}
+	if p.ts.Accept("&") != nil {
+		node := NewUnary(NodeAddr, p.unary(), tok)
+		if !islvalue(node.Lhs) {
+			tok.SemanticErrorf("lvalue required as unary \"&\" operand")
+		}
+		return node
+	}
+	return p.primary()
+}
+
+// primary -> "(" expr ")"
+// -->      | number
+// -->      | ident
+func (p *parser) primary() (node *Node) {
+	defer p.trace("primary")()
+	tok := p.ts.Peek(0)
+	if p.ts.Accept("(") != nil {
+		node = p.expr()
+		node.widen(tok, p.expect(")"))
+		return
+	}
+	if tok.Kind == token.NUM {
+		p.ts.Next()
+		return NewNumber(tok.Value, tok)
+	}
+	if tok.Kind == token.IDENT {
+		variable := p.findVar(tok)
+		if variable == nil {
+			failSemantic(tok, "undefined variable")
+		}
+		variable.used = true
+		variable.reads++
+		p.ts.Next()
+		return NewVar(variable, tok)
+	}
+	fail(tok, "expected an expression")
+	return
+}
@@ -0,0 +1,81 @@
+package parser
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/youngfr/gocc/lexer"
+	"github.com/youngfr/gocc/token"
+)
+
+func TestParseErrors(t *testing.T) {
+	session := token.NewSession()
+	session.Language = ""
+	tok, err := lexer.Tokenize(session.AddFile("<test>", "", "int a = $1;\nreturn b;\n"))
+	if err == nil {
+		t.Fatal("no lexical error for \"$\"")
+	}
+	program, err := Parse(tok)
+	if program == nil {
+		t.Fatal("no program returned with the errors")
+	}
+	var errs token.ErrorList
+	if !errors.As(err, &errs) {
+		t.Fatalf("Parse returned %v, want a token.ErrorList", err)
+	}
+	want := []string{
+		"<test>:1:9: error: invalid token",
+		"<test>:2:8: error: undefined variable",
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("Parse returned %d errors, want %d: %v", len(errs), len(want), errs)
+	}
+	for i, d := range errs {
+		if got := d.Error(); got != want[i] {
+			t.Errorf("error %d is %q, want %q", i, got, want[i])
+		}
+	}
+	if status := errs.ExitStatus(); status != token.ExitLexical {
+		t.Errorf("exit status %d, want %d", status, token.ExitLexical)
+	}
+}
+
+func TestScopes(t *testing.T) {
+	src := "int a = 1;\n{ int a = 2; a = 3; }\nfor (int i = 0; i < 1; i = i + 1) a = i;\nreturn a;\n"
+	program := parseString(t, src)
+	info := NewInfo(program)
+	if len(info.Objects) != 3 {
+		t.Fatalf("%d objects, want 3", len(info.Objects))
+	}
+	outer, inner := info.Objects[0], info.Objects[1]
+	block := Range{program.File.Pos(strings.Index(src, "{")), program.File.Pos(strings.Index(src, "}"))}
+	for tok, o := range info.Uses {
+		want := outer
+		if block.Contains(tok.Pos()) {
+			want = inner
+		}
+		if o.Name() == "a" && o != want {
+			t.Errorf("the use of a at %d refers to the wrong declaration", tok.Pos())
+		}
+	}
+
+	for _, c := range []struct{ src, err string }{
+		{"{ int a; }\nreturn a;\n", "<test>:2:8: error: undefined variable"},
+		{"for (int i = 0; i < 1; i = i + 1) ;\nreturn i;\n", "<test>:2:8: error: undefined variable"},
+		{"if (1) int a; else int b;\nreturn b;\n", "<test>:2:8: error: undefined variable"},
+		{"int a;\nint *a;\n", "<test>:2:6: error: redefinition of \"a\""},
+	} {
+		session := token.NewSession()
+		session.Language = ""
+		tok, err := lexer.Tokenize(session.AddFile("<test>", "", c.src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = Parse(tok)
+		var errs token.ErrorList
+		if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Error() != c.err {
+			t.Errorf("%q gives %v, want %s", c.src, err, c.err)
+		}
+	}
+}
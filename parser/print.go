@@ -0,0 +1,374 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/youngfr/gocc/token"
+	"github.com/youngfr/gocc/types"
+)
+
+// C printer
+//
+// PrintSource renders the tree built by the parser back as C, in the
+// style of the test programs: one statement per line, indented with
+// tabs, "{" at the end of the line opening a block, and spaces around
+// binary operators. Parsing the output gives the same tree, apart from
+// positions. Comments and redundant parentheses are not kept, since the
+// tree does not hold them.
+//
+// What the parser built from the source is undone on the way:
+//
+//   - A block is a declaration if it starts with "int", and an empty
+//     statement if it starts with neither "int" nor "{". Each declarator
+//     is one of its statements, assigning the initializer if any.
+//   - The scaling of pointer arithmetic is a "*" or "/" by a number
+//     whose token is not a number, but the "+" or "-" it was made for.
+//   - "a > b" is kept as "b < a" and "a >= b" as "b <= a", with the
+//     operator token telling them apart.
+//
+// The printer must run before any AST pass, which change the tree in
+// ways it cannot undo.
+//
+// FormatSource prints the same way for "gocc fmt", keeping the comments
+// of the file and a blank line where statements were separated by some.
+// A comment is printed on a line of its own before the statement it
+// precedes, at its indentation, or at the end of the line printed last
+// if it followed it on the same line in the source. Comments within a
+// statement go before the statement following them, and those at the
+// end of a block before its "}". Printing the output again gives the
+// same output.
+
+// Precedence of each level of the grammar of expressions, from the loosest.
+const (
+	precAssign = iota + 1
+	precEquality
+	precRelational
+	precAdd
+	precMul
+	precUnary
+	precPrimary
+)
+
+var binaryOperators = map[NodeKind]struct {
+	op   string
+	prec int
+}{
+	NodeEql: {"==", precEquality}, NodeNeq: {"!=", precEquality},
+	NodeLss: {"<", precRelational}, NodeLeq: {"<=", precRelational},
+	NodeAdd: {"+", precAdd}, NodeSub: {"-", precAdd},
+	NodeMul: {"*", precMul}, NodeDiv: {"/", precMul},
+}
+
+var unaryOperators = map[NodeKind]string{
+	NodeNeg: "-", NodeAddr: "&", NodeDeref: "*",
+}
+
+type printer struct {
+	out   bytes.Buffer
+	depth int // Indentation of the statement being printed
+
+	// With FormatSource, the file of the program and its comments left
+	// to print, the end of the last statement or comment printed, that
+	// of the source ending the last line printed, or -1, and whether
+	// that line opens a block.
+	file     *token.File
+	comments []Comment
+	prevEnd  int
+	lineEnd  int
+	open     bool
+}
+
+// A comment of the source, by the offsets of its text in the file.
+type Comment struct {
+	Begin int
+	End   int
+}
+
+// Print `program` as C source to `out`.
+func PrintSource(out io.Writer, program *Function) {
+	p := &printer{}
+	for n := program.Body; n != nil; n = n.Next {
+		p.stmt(n)
+	}
+	p.out.WriteTo(out)
+}
+
+// Print `program` as C source to `out` like PrintSource, with the
+// `comments` of its file, in source order.
+func FormatSource(out io.Writer, program *Function, comments []Comment) {
+	p := &printer{file: program.File, comments: comments, prevEnd: -1, lineEnd: -1}
+	for n := program.Body; n != nil; n = n.Next {
+		p.stmt(n)
+	}
+	p.flush(len(p.file.Contents))
+	p.out.WriteTo(out)
+}
+
+// Print the comments left that begin before `offset`.
+func (p *printer) flush(offset int) {
+	for len(p.comments) > 0 && p.comments[0].Begin < offset {
+		c := p.comments[0]
+		p.comments = p.comments[1:]
+		text := p.file.Contents[c.Begin:c.End]
+		if p.lineEnd >= 0 && p.lineEnd <= c.Begin && !strings.Contains(p.file.Contents[p.lineEnd:c.End], "\n") {
+			// Trail the last line printed.
+			p.out.Truncate(p.out.Len() - 1)
+			p.out.WriteString(" " + text + "\n")
+		} else {
+			p.separate(c.Begin)
+			p.line("%s", text)
+			p.open = false
+		}
+		p.prevEnd, p.lineEnd = c.End, c.End
+	}
+}
+
+// Print a blank line before the statement or comment at `offset` if
+// there is one in the source since the one printed last, unless a
+// block or the output starts there.
+func (p *printer) separate(offset int) {
+	if p.prevEnd < 0 || p.prevEnd > offset || p.open {
+		return
+	}
+	gap := p.file.Contents[p.prevEnd:offset]
+	if strings.TrimSpace(gap) == "" && strings.Count(gap, "\n") > 1 {
+		p.out.WriteString("\n")
+	}
+}
+
+// Record that the last line printed ends with the source up to `tok`,
+// which comments following it on the same line trail.
+func (p *printer) ends(tok *token.Token) {
+	p.lineEnd = tok.Begin + tok.Length
+}
+
+// Return the source of the expression `node`, as printed by PrintSource.
+func ExprString(node *Node) string {
+	return exprString(node, precAssign)
+}
+
+// Whether `node` is the number a pointer operand is scaled by.
+func isScale(node *Node) bool {
+	return node.Kind == NodeNum && node.Token.Kind != token.NUM
+}
+
+// Whether the block `node` is a declaration rather than a block statement.
+func isDeclaration(node *Node) bool {
+	return node.Kind == NodeBlock && node.first.Kind == token.INT
+}
+
+// Whether the block `node` is an empty statement rather than a block statement.
+func isEmptyStmt(node *Node) bool {
+	return node.Kind == NodeBlock && !isDeclaration(node) && !equal(node.first, "{")
+}
+
+// Print a line at the indentation of the statement being printed.
+func (p *printer) line(format string, args ...any) {
+	p.out.WriteString(strings.Repeat("\t", p.depth))
+	fmt.Fprintf(&p.out, format, args...)
+	p.out.WriteString("\n")
+}
+
+// Print the statement `node` on lines of its own.
+func (p *printer) stmt(node *Node) {
+	if p.file != nil {
+		p.flush(node.first.Begin)
+		p.separate(node.first.Begin)
+		p.lineEnd, p.open = -1, false
+		defer func() { p.prevEnd = node.last.Begin + node.last.Length }()
+	}
+	switch {
+	case isBlock(node):
+		p.line("{")
+		p.ends(node.first)
+		p.open = true
+		p.list(node.Body)
+		p.close(node)
+	case node.Kind == NodeIf:
+		p.line("if (%s)%s", ExprString(node.Condition), opening(node.ThenBranch))
+		p.opened(node.ThenBranch)
+		p.body(node.ThenBranch)
+		for node.ElseBranch != nil {
+			elseBranch := node.ElseBranch
+			if isBlock(node.ThenBranch) {
+				// Join the "}" of the then branch.
+				p.out.Truncate(p.out.Len() - 1)
+				p.out.WriteString(" ")
+			} else {
+				p.out.WriteString(strings.Repeat("\t", p.depth))
+			}
+			if elseBranch.Kind == NodeIf {
+				fmt.Fprintf(&p.out, "else if (%s)%s\n", ExprString(elseBranch.Condition), opening(elseBranch.ThenBranch))
+				p.opened(elseBranch.ThenBranch)
+				p.body(elseBranch.ThenBranch)
+				node = elseBranch
+				continue
+			}
+			fmt.Fprintf(&p.out, "else%s\n", opening(elseBranch))
+			p.opened(elseBranch)
+			p.body(elseBranch)
+			break
+		}
+	case node.Kind == NodeFor && node.Token.Kind == token.WHILE:
+		p.line("while (%s)%s", ExprString(node.Condition), opening(node.ThenBranch))
+		p.opened(node.ThenBranch)
+		p.body(node.ThenBranch)
+	case node.Kind == NodeFor:
+		header := "for ("
+		if node.Initializer != nil {
+			header += simpleStmt(node.Initializer)
+		} else {
+			header += ";"
+		}
+		if node.Condition != nil {
+			header += " " + ExprString(node.Condition)
+		}
+		header += ";"
+		if node.Increment != nil {
+			header += " " + ExprString(node.Increment)
+		}
+		p.line("%s)%s", header, opening(node.ThenBranch))
+		p.opened(node.ThenBranch)
+		p.body(node.ThenBranch)
+	default:
+		p.line("%s", simpleStmt(node))
+		p.ends(node.last)
+	}
+}
+
+// Print the statements of the linked `list` one level deeper.
+func (p *printer) list(list *Node) {
+	p.depth++
+	for n := list; n != nil; n = n.Next {
+		p.stmt(n)
+	}
+	p.depth--
+}
+
+// Whether `node` is printed as a block statement.
+func isBlock(node *Node) bool {
+	return node.Kind == NodeBlock && !isDeclaration(node) && !isEmptyStmt(node)
+}
+
+// Return what follows the header of a statement whose body is `body`:
+// the "{" of a block on the same line, or nothing.
+func opening(body *Node) string {
+	if isBlock(body) {
+		return " {"
+	}
+	return ""
+}
+
+// Record the end of the line of the header of a statement whose body is
+// `body`, which ends with the "{" of a block.
+func (p *printer) opened(body *Node) {
+	if isBlock(body) {
+		p.ends(body.first)
+		p.open = true
+	} else {
+		p.lineEnd = -1
+	}
+}
+
+// Print the "}" closing the block statement `node`, after the comments
+// at its end.
+func (p *printer) close(node *Node) {
+	if p.file != nil {
+		p.depth++
+		p.flush(node.last.Begin)
+		p.depth--
+	}
+	p.line("}")
+	p.ends(node.last)
+	p.open = false
+}
+
+// Print the body of an if, for or while statement, after its header.
+func (p *printer) body(body *Node) {
+	if isBlock(body) {
+		p.list(body.Body)
+		p.close(body)
+		return
+	}
+	p.depth++
+	p.stmt(body)
+	p.depth--
+}
+
+// Return the source of a statement that fits on one line, with its ";".
+func simpleStmt(node *Node) string {
+	switch {
+	case node.Kind == NodeReturn:
+		return "return " + ExprString(node.Lhs) + ";"
+	case node.Kind == NodeExprStmt:
+		return ExprString(node.Lhs) + ";"
+	case isDeclaration(node):
+		var declarators []string
+		for n := node.Body; n != nil; n = n.Next {
+			declarators = append(declarators, declaratorString(n.Lhs))
+		}
+		return "int " + strings.Join(declarators, ", ") + ";"
+	}
+	return ";"
+}
+
+// Return the declarator and initializer of the variable declared by
+// `node`, either the variable or the assignment of its initializer.
+func declaratorString(node *Node) string {
+	init := ""
+	if node.Kind == NodeAsg {
+		init = " = " + ExprString(node.Rhs)
+		node = node.Lhs
+	}
+	stars := ""
+	for t := node.Variable.Type; t.Kind == types.TPPTR; t = t.Base {
+		stars += "*"
+	}
+	return stars + node.Variable.name + init
+}
+
+// Return the source of the expression `node`, in parentheses
+// if it binds more loosely than the level `prec`.
+func exprString(node *Node, prec int) string {
+	var s string
+	nodePrec := precPrimary
+	switch node.Kind {
+	case NodeNum:
+		s = fmt.Sprint(node.Value)
+	case NodeVar:
+		s = node.Variable.name
+	case NodeAsg:
+		nodePrec = precAssign
+		s = exprString(node.Lhs, precUnary) + " = " + exprString(node.Rhs, precAssign)
+	case NodeNeg, NodeAddr, NodeDeref:
+		nodePrec = precUnary
+		op := unaryOperators[node.Kind]
+		operand := exprString(node.Lhs, precUnary)
+		// Keep "- -a" from reading as "--a".
+		if strings.HasPrefix(operand, op) && op != "*" {
+			op += " "
+		}
+		s = op + operand
+	default:
+		if (node.Kind == NodeMul || node.Kind == NodeDiv) && isScale(node.Rhs) {
+			return exprString(node.Lhs, prec)
+		}
+		b := binaryOperators[node.Kind]
+		nodePrec = b.prec
+		lhs, rhs, op := node.Lhs, node.Rhs, b.op
+		switch node.Token.Kind {
+		case token.GTR:
+			lhs, rhs, op = rhs, lhs, ">"
+		case token.GEQ:
+			lhs, rhs, op = rhs, lhs, ">="
+		}
+		s = exprString(lhs, b.prec) + " " + op + " " + exprString(rhs, b.prec+1)
+	}
+	if nodePrec < prec {
+		return "(" + s + ")"
+	}
+	return s
+}
@@ -0,0 +1,154 @@
+package parser
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/youngfr/gocc/lexer"
+	"github.com/youngfr/gocc/token"
+)
+
+// Programs printed back the same way they are written.
+var printCases = []string{
+	"return 5 + 6 * 7;\n",
+	"int a = 1, *p = &a, **q;\nq = &p;\n**q = - -a;\nreturn (a + 1) * 2 - *(p + 0);\n",
+	"int a = 3;\nreturn a > 2 == a >= 3 != a < (1 <= 0);\n",
+	"int a = 3;\nint *p = &a;\nint *q = p + 1;\nreturn q - p + (a = 4);\n",
+	"int a = 1;\nif (a) {\n\ta = 2;\n} else if (a > 1)\n\ta = 3;\nelse {\n\ta = 4;\n}\nreturn a;\n",
+	"int a = 1;\nif (a)\n\tif (a)\n\t\ta = 2;\n\telse\n\t\t;\nreturn a;\n",
+	"int i;\nfor (;;)\n\treturn 1;\nfor (i = 0; i < 3; i = i + 1) {\n\t{\n\t\ti = i;\n\t}\n}\nwhile (i)\n\ti = i - 1;\nreturn i;\n",
+	"for (int i = 0; i < 3; i = i + 1)\n\t;\nreturn 0;\n",
+}
+
+// Return whether the trees of `a` and `b` are the same apart from positions.
+func sameTree(a *Node, b *Node) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.Kind != b.Kind || a.Value != b.Value || (a.Type == nil) != (b.Type == nil) {
+		return false
+	}
+	if a.Type != nil && a.Type.String() != b.Type.String() {
+		return false
+	}
+	if a.Kind == NodeVar && a.Variable.name != b.Variable.name {
+		return false
+	}
+	return sameTree(a.Lhs, b.Lhs) && sameTree(a.Rhs, b.Rhs) &&
+		sameTree(a.Initializer, b.Initializer) && sameTree(a.Condition, b.Condition) &&
+		sameTree(a.Increment, b.Increment) && sameTree(a.ThenBranch, b.ThenBranch) &&
+		sameTree(a.ElseBranch, b.ElseBranch) && sameList(a.Body, b.Body)
+}
+
+func sameList(a *Node, b *Node) bool {
+	for ; a != nil && b != nil; a, b = a.Next, b.Next {
+		if !sameTree(a, b) {
+			return false
+		}
+	}
+	return a == nil && b == nil
+}
+
+func printString(program *Function) string {
+	var out bytes.Buffer
+	PrintSource(&out, program)
+	return out.String()
+}
+
+func TestPrintSource(t *testing.T) {
+	for _, src := range printCases {
+		if got := printString(parseString(t, src)); got != src {
+			t.Errorf("printed\n%s\nwant\n%s", got, src)
+		}
+	}
+}
+
+// Parse each program, print it, parse the output and check that
+// it gives the same tree, and that printing it again changes nothing.
+func TestPrintRoundTrip(t *testing.T) {
+	srcs := append([]string{
+		"int a=1;int*p=&a;return 1+p-p>0;",
+		"int x; x = (((1)));  return +x + -(-x) * (x/(2*3));",
+		"{ int a = 2; { ; } if (a >= 2) return (a = a + 1); else return 0; }",
+	}, printCases...)
+	paths, err := filepath.Glob("../test/difftest/*.c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		srcs = append(srcs, string(src))
+	}
+	for _, src := range srcs {
+		program := parseString(t, src)
+		printed := printString(program)
+		reparsed := parseString(t, printed)
+		if !sameList(program.Body, reparsed.Body) {
+			t.Errorf("%q printed as\n%s\nwhich parses to a different tree", src, printed)
+			continue
+		}
+		if again := printString(reparsed); again != printed {
+			t.Errorf("%q printed as\n%s\nthen as\n%s", src, printed, again)
+		}
+	}
+}
+
+// Return the source of `src` formatted with its comments, and whether
+// formatting it again changes nothing.
+func formatString(t *testing.T, src string) (string, bool) {
+	t.Helper()
+	format := func(src string) string {
+		file := token.NewSession().AddFile("<test>", "", src)
+		tok, _ := lexer.Tokenize(file)
+		program, err := Parse(tok)
+		if err != nil {
+			t.Fatalf("cannot parse %q: %v", src, err)
+		}
+		var comments []Comment
+		for _, span := range lexer.Classify(file, tok) {
+			if span.Class == lexer.ClassComment {
+				comments = append(comments, Comment{span.Begin, span.End})
+			}
+		}
+		var out bytes.Buffer
+		FormatSource(&out, program, comments)
+		return out.String()
+	}
+	formatted := format(src)
+	return formatted, format(formatted) == formatted
+}
+
+func TestFormatSource(t *testing.T) {
+	for _, c := range []struct {
+		src  string
+		want string
+	}{
+		{"// a\nint a=1; // b\n\n\n\nint  b; /* c */ /* d */\nreturn a;",
+			"// a\nint a = 1; // b\n\nint b; /* c */ /* d */\nreturn a;\n"},
+		{"if(1){ // a\n\n  1;\n\n  // b\n\n}else{2;} /* c */\n",
+			"if (1) { // a\n\t1;\n\n\t// b\n} else {\n\t2;\n} /* c */\n"},
+		{"for (;/* a */;) { /* b\n   c */ return 1; }\nreturn 1 /* d */ + 2;\n// e\n",
+			"for (;;) {\n\t/* a */\n\t/* b\n   c */\n\treturn 1;\n}\nreturn 1 + 2;\n/* d */\n// e\n"},
+		{"", ""},
+		{"/* only */", "/* only */\n"},
+	} {
+		got, idempotent := formatString(t, c.src)
+		if got != c.want {
+			t.Errorf("%q formatted as\n%s\nwant\n%s", c.src, got, c.want)
+		}
+		if !idempotent {
+			t.Errorf("%q formatted as\n%s\nwhich formats differently", c.src, got)
+		}
+	}
+	// Without comments, formatting prints like PrintSource.
+	for _, src := range printCases {
+		if got, _ := formatString(t, src); got != src {
+			t.Errorf("formatted\n%s\nwant\n%s", got, src)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package parser
+
+import (
+	"github.com/youngfr/gocc/types"
+)
+
+// Typing of expressions
+
+func addtype(node *Node) {
+	if node == nil || node.Type != nil {
+		return
+	}
+	EachChild(node, addtype)
+	switch node.Kind {
+	case NodeAdd, NodeSub, NodeMul, NodeDiv, NodeNeg, NodeAsg:
+		node.Type = node.Lhs.Type
+		return
+	case NodeEql, NodeNeq, NodeLss, NodeLeq, NodeNum:
+		node.Type = types.Int
+		return
+	case NodeVar:
+		node.Type = node.Variable.Type
+	case NodeAddr:
+		node.Type = types.PointerTo(node.Lhs.Type)
+		return
+	case NodeDeref:
+		if node.Lhs.Type.Kind != types.TPPTR {
+			failSemantic(node.Token, "invalid pointer dereference")
+		}
+		node.Type = node.Lhs.Type.Base
+		return
+	}
+}
+
+// Check that the value of `from` can be converted to `to` as if by
+// assignment, in `context`: "initialization", "assignment" or "return".
+// Converting between different pointer types is an error. Converting
+// between integers and pointers is only warned about, and the null
+// pointer constant 0 converts to any pointer.
+func checkAssign(context string, to *types.Type, from *Node) {
+	addtype(from)
+	session := from.Token.File.Session
+	conversion := session.Tr("%s of \"%s\" from \"%s\"", session.Translate(context), to, from.Type)
+	if context == "return" {
+		conversion = session.Tr("returning \"%s\" from a function with return type \"%s\"", from.Type, to)
+	}
+	switch {
+	case to.Kind == types.TPPTR && from.Type.Kind == types.TPPTR && !types.Identical(to, from.Type):
+		from.Token.SemanticErrorf("incompatible pointer types in %s", conversion)
+	case to.Kind == types.TPPTR && types.IsInt(from.Type) && !(from.Kind == NodeNum && from.Value == 0):
+		from.Token.Warnf("int-conversion", "%s makes pointer from integer without a cast", conversion)
+	case types.IsInt(to) && from.Type.Kind == types.TPPTR:
+		from.Token.Warnf("int-conversion", "%s makes integer from pointer without a cast", conversion)
+	}
+}
@@ -0,0 +1,159 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/youngfr/gocc/types"
+)
+
+// AST verifier
+//
+// With --verify, the typed AST is checked after parsing and after each
+// AST pass for the invariants the backends rely on: statements and
+// expressions are where they belong, statement lists end, every
+// expression has a type, and the operands of "=" and "&" are lvalues.
+// A violation is a bug in gocc rather than in the program, so the
+// driver reports it as an internal error with a dump of the offending
+// node instead of letting a backend generate bad code from it.
+
+type verifier struct {
+	program *Function
+	stage   string // What ran last, for the report
+}
+
+// An invariant of the AST broken, as found by Verify.
+type VerifyError struct {
+	Stage   string // What ran last
+	Message string
+	Node    *Node // Offending node
+}
+
+// Return the error, followed by a dump of the offending node.
+func (e *VerifyError) Error() string {
+	var dump strings.Builder
+	dumpNode(&dump, e.Node, 1, "")
+	return fmt.Sprintf("after %s: %s\n%s", e.Stage, e.Message, strings.TrimSuffix(dump.String(), "\n"))
+}
+
+// Check the AST of `program` after `stage`, and return
+// a *VerifyError for the first violation found.
+func Verify(program *Function, stage string) (err error) {
+	v := &verifier{program: program, stage: stage}
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(*VerifyError)
+			if !ok {
+				panic(r)
+			}
+			err = e
+		}
+	}()
+	v.list(program.Body)
+	return nil
+}
+
+// Report that `node` breaks an invariant and abandon the check.
+func (v *verifier) fail(node *Node, format string, args ...any) {
+	panic(&VerifyError{v.stage, fmt.Sprintf(format, args...), node})
+}
+
+// Check a linked list of statements.
+func (v *verifier) list(list *Node) {
+	seen := map[*Node]bool{}
+	for n := list; n != nil; n = n.Next {
+		if seen[n] {
+			v.fail(n, "statement list loops back to %s", nodeNames[n.Kind])
+		}
+		seen[n] = true
+		v.stmt(n, n)
+	}
+}
+
+// Check the statement `node`, a child of `parent`.
+func (v *verifier) stmt(parent *Node, node *Node) {
+	if node == nil {
+		v.fail(parent, "%s is missing a statement", nodeNames[parent.Kind])
+	}
+	switch node.Kind {
+	case NodeExprStmt, NodeReturn:
+		v.expr(node, node.Lhs)
+	case NodeBlock:
+		v.list(node.Body)
+	case NodeIf:
+		v.expr(node, node.Condition)
+		v.stmt(node, node.ThenBranch)
+		if node.ElseBranch != nil {
+			v.stmt(node, node.ElseBranch)
+		}
+	case NodeFor:
+		if node.Initializer != nil {
+			v.stmt(node, node.Initializer)
+		}
+		if node.Condition != nil {
+			v.expr(node, node.Condition)
+		}
+		if node.Increment != nil {
+			v.expr(node, node.Increment)
+		}
+		v.stmt(node, node.ThenBranch)
+	default:
+		v.fail(node, "%s is not a statement", nodeNames[node.Kind])
+	}
+}
+
+// Check the expression `node`, a child of `parent`.
+func (v *verifier) expr(parent *Node, node *Node) {
+	if node == nil {
+		v.fail(parent, "%s is missing an operand", nodeNames[parent.Kind])
+	}
+	switch node.Kind {
+	case NodeExprStmt, NodeReturn, NodeBlock, NodeIf, NodeFor:
+		v.fail(node, "%s is not an expression", nodeNames[node.Kind])
+	}
+	if node.Type == nil {
+		v.fail(node, "%s has no type", nodeNames[node.Kind])
+	}
+	switch node.Kind {
+	case NodeNum:
+	case NodeVar:
+		v.variable(node)
+	case NodeNeg:
+		v.expr(node, node.Lhs)
+	case NodeAddr:
+		v.expr(node, node.Lhs)
+		v.lvalue(node, node.Lhs)
+		if node.Type.Kind != types.TPPTR || !types.Identical(node.Type.Base, node.Lhs.Type) {
+			v.fail(node, "Addr has type %s for an operand of type %s", node.Type, node.Lhs.Type)
+		}
+	case NodeDeref:
+		v.expr(node, node.Lhs)
+		if node.Lhs.Type.Kind != types.TPPTR || !types.Identical(node.Type, node.Lhs.Type.Base) {
+			v.fail(node, "Deref has type %s for an operand of type %s", node.Type, node.Lhs.Type)
+		}
+	case NodeAsg:
+		v.expr(node, node.Lhs)
+		v.expr(node, node.Rhs)
+		v.lvalue(node, node.Lhs)
+	default:
+		v.expr(node, node.Lhs)
+		v.expr(node, node.Rhs)
+	}
+}
+
+// Check that `node`, an operand of `parent`, designates an object.
+func (v *verifier) lvalue(parent *Node, node *Node) {
+	if node.Kind != NodeVar && node.Kind != NodeDeref {
+		v.fail(parent, "operand of %s is not an lvalue", nodeNames[parent.Kind])
+	}
+}
+
+// Check that the variable `node` refers to is a local of the program.
+func (v *verifier) variable(node *Node) {
+	for o := v.program.Locals; o != nil; o = o.Next {
+		if o == node.Variable {
+			return
+		}
+	}
+	v.fail(node, "Var refers to a variable that is not a local")
+}
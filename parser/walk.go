@@ -0,0 +1,68 @@
+package parser
+
+// Traversal of the AST
+//
+// The children of a node are its operands, its parts and the statements
+// of its body, in source order: Lhs, Rhs, Initializer, Condition,
+// Increment, ThenBranch, ElseBranch, then each statement of Body.
+// Statements of a list are linked by Next, which is not a child: walking
+// a node visits its subtree, and WalkList visits a whole list.
+
+// A visitor's Visit method is called for each node found by Walk. If it
+// returns a visitor w, Walk visits each child of the node with w, then
+// calls w.Visit(nil). Returning nil skips the children.
+type Visitor interface {
+	Visit(node *Node) (w Visitor)
+}
+
+// Visit `node` with `v`, then its children depth-first, like go/ast.Walk.
+func Walk(v Visitor, node *Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+	EachChild(node, func(child *Node) {
+		Walk(v, child)
+	})
+	v.Visit(nil)
+}
+
+// Walk each statement of the linked `list`.
+func WalkList(v Visitor, list *Node) {
+	for n := list; n != nil; n = n.Next {
+		Walk(v, n)
+	}
+}
+
+type inspector func(*Node) bool
+
+func (f inspector) Visit(node *Node) Visitor {
+	if node != nil && f(node) {
+		return f
+	}
+	return nil
+}
+
+// Call `f` for `node`, then for its children depth-first if it
+// returns true, like go/ast.Inspect. Unlike with Walk, `f` is
+// not called with nil after the children.
+func Inspect(node *Node, f func(*Node) bool) {
+	Walk(inspector(f), node)
+}
+
+// Inspect each statement of the linked `list`.
+func InspectList(list *Node, f func(*Node) bool) {
+	WalkList(inspector(f), list)
+}
+
+// Call `f` for each child of `node` in source order, without
+// descending further. Missing operands and parts are skipped.
+func EachChild(node *Node, f func(child *Node)) {
+	for _, child := range [...]*Node{node.Lhs, node.Rhs, node.Initializer, node.Condition, node.Increment, node.ThenBranch, node.ElseBranch} {
+		if child != nil {
+			f(child)
+		}
+	}
+	for n := node.Body; n != nil; n = n.Next {
+		f(n)
+	}
+}
@@ -0,0 +1,68 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/youngfr/gocc/lexer"
+	"github.com/youngfr/gocc/token"
+)
+
+func parseString(t *testing.T, src string) *Function {
+	t.Helper()
+	tok, _ := lexer.Tokenize(token.NewSession().AddFile("<test>", "", src))
+	program, err := Parse(tok)
+	if err != nil {
+		t.Fatalf("cannot parse %q: %v", src, err)
+	}
+	return program
+}
+
+// Records the nodes visited, and checks that each
+// visit of children ends with a call with nil.
+type recorder struct {
+	t      *testing.T
+	visits []string
+	depth  int
+}
+
+func (r *recorder) Visit(node *Node) Visitor {
+	if node == nil {
+		r.depth--
+		if r.depth < 0 {
+			r.t.Fatal("more calls with nil than nodes visited")
+		}
+		return nil
+	}
+	r.visits = append(r.visits, nodeNames[node.Kind])
+	r.depth++
+	return r
+}
+
+func TestWalk(t *testing.T) {
+	program := parseString(t, "int a = 1; for (a = 0; a < 3; a = a + 1) { if (a) a = 2; else return -a; }")
+	r := &recorder{t: t}
+	WalkList(r, program.Body)
+	if r.depth != 0 {
+		t.Errorf("%d nodes visited were not followed by a call with nil", r.depth)
+	}
+	// Nodes are shown by kind, as in the dump of the AST.
+	want := "Block ExprStmt Asg Var Num For ExprStmt Asg Var Num Lss Var Num Asg Var Add Var Num Block If Var ExprStmt Asg Var Num Return Neg Var"
+	if got := strings.Join(r.visits, " "); got != want {
+		t.Errorf("visited %s\nwant    %s", got, want)
+	}
+}
+
+func TestInspect(t *testing.T) {
+	program := parseString(t, "int a = 1; if (a) { a = a * 2; } return a + 1;")
+	var visits []string
+	InspectList(program.Body, func(node *Node) bool {
+		visits = append(visits, nodeNames[node.Kind])
+		// Skip the branches of the if statement.
+		return node.Kind != NodeIf
+	})
+	want := "Block ExprStmt Asg Var Num If Return Add Var Num"
+	if got := strings.Join(visits, " "); got != want {
+		t.Errorf("inspected %s\nwant      %s", got, want)
+	}
+}
@@ -0,0 +1,68 @@
+// Package scope implements the symbol tables of the parser: nested
+// scopes mapping names to what they declare.
+package scope
+
+// Scopes
+//
+// Each block of a program opens a scope, nested in that of the block
+// around it, and the program itself is the outermost one. A scope maps
+// names to their declarations with a map for each namespace of C, so
+// that an identifier is looked up in constant time in each scope it is
+// nested in. Tags of structures, unions and enumerations, labels and
+// ordinary identifiers such as variables are in different namespaces,
+// and the same name may be declared in each. Labels belong to the scope
+// of the function, which for now is the outermost one.
+
+type Namespace int
+
+const (
+	Ordinary Namespace = iota // Variables, functions, typedefs and enumerators
+	Tag                       // Tags of structures, unions and enumerations
+	Label                     // Labels of goto
+	namespaces
+)
+
+// A scope holding declarations of type T.
+type Scope[T any] struct {
+	Parent *Scope[T] // Enclosing scope, or nil for the outermost one
+
+	names [namespaces]map[string]T
+}
+
+// Return a new empty scope nested in `parent`, or an outermost one if it is nil.
+func New[T any](parent *Scope[T]) *Scope[T] {
+	return &Scope[T]{Parent: parent}
+}
+
+// Declare `name` as `decl` in the namespace `ns` of the scope. If the
+// scope already declares the name, keep that declaration and return it
+// with false instead.
+func (s *Scope[T]) Insert(ns Namespace, name string, decl T) (T, bool) {
+	if prev, ok := s.names[ns][name]; ok {
+		return prev, false
+	}
+	if s.names[ns] == nil {
+		s.names[ns] = map[string]T{}
+	}
+	s.names[ns][name] = decl
+	return decl, true
+}
+
+// Return the declaration of `name` in the namespace `ns` of the scope
+// itself, without looking in those it is nested in.
+func (s *Scope[T]) LookupLocal(ns Namespace, name string) (T, bool) {
+	decl, ok := s.names[ns][name]
+	return decl, ok
+}
+
+// Return the declaration of `name` in the namespace `ns` of the
+// innermost scope declaring it, from this one outwards, and that scope.
+func (s *Scope[T]) Lookup(ns Namespace, name string) (T, *Scope[T], bool) {
+	for ; s != nil; s = s.Parent {
+		if decl, ok := s.names[ns][name]; ok {
+			return decl, s, true
+		}
+	}
+	var zero T
+	return zero, nil, false
+}
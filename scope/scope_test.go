@@ -0,0 +1,32 @@
+package scope
+
+import "testing"
+
+func TestScope(t *testing.T) {
+	outer := New[int](nil)
+	if _, ok := outer.Insert(Ordinary, "a", 1); !ok {
+		t.Fatal("a is already declared in an empty scope")
+	}
+	if prev, ok := outer.Insert(Ordinary, "a", 2); ok || prev != 1 {
+		t.Errorf("redeclaring a gives %d, %v, want 1, false", prev, ok)
+	}
+	outer.Insert(Tag, "a", 3)
+
+	inner := New(outer)
+	inner.Insert(Ordinary, "a", 4)
+	if decl, s, ok := inner.Lookup(Ordinary, "a"); !ok || decl != 4 || s != inner {
+		t.Errorf("a is %d in the inner scope, want 4", decl)
+	}
+	if decl, s, ok := inner.Lookup(Tag, "a"); !ok || decl != 3 || s != outer {
+		t.Errorf("tag a is %d in the inner scope, want 3 from the outer one", decl)
+	}
+	if _, ok := inner.LookupLocal(Tag, "a"); ok {
+		t.Error("the inner scope declares tag a")
+	}
+	if _, _, ok := inner.Lookup(Label, "a"); ok {
+		t.Error("label a is declared")
+	}
+	if decl, _, _ := outer.Lookup(Ordinary, "a"); decl != 1 {
+		t.Errorf("a is %d in the outer scope, want 1", decl)
+	}
+}
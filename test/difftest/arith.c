@@ -0,0 +1 @@
+return 5+6*7;
@@ -0,0 +1,3 @@
+int a = 7;
+int b = 2;
+return (a == 7) + (a != b) * 2 + (a < b) * 4 + (b <= a) * 8 + (a >= 7) * 16 + (b > a) * 32;
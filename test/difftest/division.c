@@ -0,0 +1,3 @@
+int x = -7;
+int y = 100;
+return 0 - x/2 + y/7/2;
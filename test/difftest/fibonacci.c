@@ -0,0 +1,10 @@
+int n = 10;
+int a = 0;
+int b = 1;
+int i;
+for (i = 0; i < n; i = i+1) {
+	int t = a + b;
+	a = b;
+	b = t;
+}
+return a;
@@ -0,0 +1,5 @@
+int i = 0;
+int j = 0;
+for (i = 0; i <= 10; i = i+1)
+	j = i + j;
+return j;
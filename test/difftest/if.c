@@ -0,0 +1,8 @@
+int a = 3;
+int b = 5;
+int m;
+if (a > b)
+	m = a;
+else
+	m = b;
+return m;
@@ -0,0 +1,5 @@
+int x = 3;
+int *y = &x;
+int **z = &y;
+**z = 5;
+return x;
@@ -0,0 +1,4 @@
+int i = 0;
+while (i < 9)
+	i = i + 1;
+return i;
@@ -3,8 +3,7 @@ assert() {
   expected="$1"
   input="$2"
 
-  ../gocc "$input" > tmp.s
-  gcc -o tmp tmp.s
+  ../gocc -o tmp -e "$input" || exit 1
   ./tmp
   actual="$?"
 
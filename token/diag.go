@@ -0,0 +1,345 @@
+package token
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Diagnostics
+//
+// Errors do not stop the compiler. They are recorded on the file they
+// were found in, the lexer and the parser recover and keep going, and
+// each phase returns those of its file as an ErrorList when it is done.
+// Only the driver reports them and exits, so that programs embedding
+// gocc get them back instead.
+//
+// Warnings are recorded the same way but do not fail the compilation.
+// Each belongs to a named group, enabled with -W<group> and disabled
+// with -Wno-<group>. Like with gcc, only int-conversion and div-by-zero
+// are enabled by default.
+//
+// Printed diagnostics are colored when standard error is a terminal,
+// unless NO_COLOR is set. --color=always and --color=never override
+// both. See Reporter for the other ways to render them.
+//
+// The exit status tells the class of error that failed the compilation,
+// so that test harnesses and build tools can tell them apart:
+//
+//	0   success
+//	1   other errors, such as an unreadable file or a failed link
+//	2   invalid command line
+//	3   lexical error, such as an invalid token
+//	4   syntax error
+//	5   semantic error, such as an undefined variable or mismatched types
+//	70  internal compiler error, which is a bug in gocc
+//
+// When a file has errors of several classes, the status is that of the
+// earliest phase, since the others may follow from it. Warnings turned
+// into errors by -Werror are semantic errors.
+
+const (
+	ExitFailure  = 1
+	ExitUsage    = 2
+	ExitLexical  = 3
+	ExitSyntax   = 4
+	ExitSemantic = 5
+	ExitInternal = 70 // EX_SOFTWARE of sysexits.h
+)
+
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+	SeverityNote // Related location attached to another diagnostic
+)
+
+var severityNames = map[Severity]string{
+	SeverityError:   "error",
+	SeverityWarning: "warning",
+	SeverityNote:    "note",
+}
+
+// Return the name of the severity, as printed in English.
+func (s Severity) String() string {
+	return severityNames[s]
+}
+
+// Escape sequences coloring each severity, the same as gcc's.
+var SeverityColors = map[Severity]string{
+	SeverityError:   "\033[31m",
+	SeverityWarning: "\033[35m",
+	SeverityNote:    "\033[36m",
+}
+
+// Return whether to color diagnostics for --color=`when`.
+func ColorFor(when string) bool {
+	switch when {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := os.Stderr.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Return `text` wrapped in the escape sequence
+// `color` if diagnostics are colored.
+func (s *Session) Colored(color string, text string) string {
+	if !s.Color {
+		return text
+	}
+	return color + text + "\033[0m"
+}
+
+// An error, warning or note at a span of a file.
+type Diagnostic struct {
+	File     *File
+	Severity Severity
+	Group    string        // Warning group, for warnings
+	Begin    int           // Starting index of the offending source text
+	Length   int           // Length of the offending source text
+	Message  string        // Message, in the language of the session
+	Notes    []*Diagnostic // Related locations, printed after the diagnostic
+	Status   int           // Exit status it causes, for errors
+
+	reported bool // Whether File.Report reported it
+}
+
+// Return the position of the beginning of the offending source text.
+func (d *Diagnostic) Position() Position {
+	return d.File.PositionFor(d.Begin)
+}
+
+// Return the diagnostic on one line, without its source or notes.
+func (d *Diagnostic) Error() string {
+	return fmt.Sprintf("%s: %s: %s", d.Position(), d.File.Session.Translate(d.Severity.String()), d.Message)
+}
+
+// The errors a phase failed with, in source order. They stay
+// recorded on their files as well.
+type ErrorList []*Diagnostic
+
+// Return the first error, followed by the number of the others.
+func (l ErrorList) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", l[0], len(l)-1)
+}
+
+// Return the exit status of the errors, that of the earliest
+// phase, or 0 if there is none.
+func (l ErrorList) ExitStatus() int {
+	status := 0
+	for _, d := range l {
+		if d.Severity == SeverityError && (status == 0 || d.Status < status) {
+			status = d.Status
+		}
+	}
+	return status
+}
+
+// Warning groups, and whether each is enabled by default. No type is unsigned
+// and no function takes parameters yet, so there is nothing for
+// sign-compare and unused-parameter to report.
+var defaultWarnings = map[string]bool{
+	"unused-variable":         false,
+	"unused-but-set-variable": false,
+	"unused-parameter":        false,
+	"shadow":                  false,
+	"sign-compare":            false,
+	"return-type":             false,
+	"int-conversion":          true,
+	"div-by-zero":             true,
+}
+
+// Warning groups enabled by -Wall.
+var wallGroups = []string{"unused-variable", "unused-but-set-variable", "sign-compare", "return-type"}
+
+// Warning groups enabled by -Wextra.
+var wextraGroups = []string{"unused-parameter", "sign-compare"}
+
+// Enable or disable warnings for -W`option`, and return
+// whether it names a warning group known to the compiler.
+func (s *Session) SetWarning(option string) bool {
+	switch option {
+	case "all":
+		for _, group := range wallGroups {
+			s.Warnings[group] = true
+		}
+		return true
+	case "extra":
+		for _, group := range wextraGroups {
+			s.Warnings[group] = true
+		}
+		return true
+	case "error":
+		s.WarningsAsErrors = true
+		return true
+	case "no-error":
+		s.WarningsAsErrors = false
+		return true
+	}
+	enable := !strings.HasPrefix(option, "no-")
+	group := strings.TrimPrefix(option, "no-")
+	if _, ok := s.Warnings[group]; !ok {
+		return false
+	}
+	s.Warnings[group] = enable
+	return true
+}
+
+// Return whether -W`option` is known to the compiler, see SetWarning.
+func IsWarningOption(option string) bool {
+	switch option {
+	case "all", "extra", "error", "no-error":
+		return true
+	}
+	_, ok := defaultWarnings[strings.TrimPrefix(option, "no-")]
+	return ok
+}
+
+// Record an error of the class of exit `status` at the `length` bytes from `begin`.
+func (f *File) ErrorAt(status int, begin int, length int, format string, args ...any) *Diagnostic {
+	d := &Diagnostic{File: f, Severity: SeverityError, Begin: begin, Length: length, Message: f.Session.Tr(format, args...), Status: status}
+	f.Diagnostics = append(f.Diagnostics, d)
+	return d
+}
+
+// Record a warning of `group` at the `length` bytes from `begin`,
+// unless the group is disabled, in which case return nil.
+func (f *File) warnAt(group string, begin int, length int, format string, args ...any) *Diagnostic {
+	if !f.Session.Warnings[group] || f.Session.SuppressWarnings {
+		return nil
+	}
+	d := &Diagnostic{File: f, Severity: SeverityWarning, Group: group, Begin: begin, Length: length, Message: f.Session.Tr(format, args...)}
+	if f.Session.WarningsAsErrors {
+		d.Severity, d.Status = SeverityError, ExitSemantic
+	}
+	f.Diagnostics = append(f.Diagnostics, d)
+	return d
+}
+
+// Record a syntax error at the token.
+func (t *Token) Errorf(format string, args ...any) *Diagnostic {
+	return t.File.ErrorAt(ExitSyntax, t.Begin, t.Length, format, args...)
+}
+
+// Record a semantic error at the token.
+func (t *Token) SemanticErrorf(format string, args ...any) *Diagnostic {
+	return t.File.ErrorAt(ExitSemantic, t.Begin, t.Length, format, args...)
+}
+
+// Record a warning of `group` at the token.
+func (t *Token) Warnf(group string, format string, args ...any) *Diagnostic {
+	return t.File.warnAt(group, t.Begin, t.Length, format, args...)
+}
+
+// Attach a note at `tok` to `d`, if it was recorded.
+func (d *Diagnostic) Note(tok *Token, format string, args ...any) *Diagnostic {
+	if d != nil {
+		n := &Diagnostic{File: tok.File, Severity: SeverityNote, Begin: tok.Begin, Length: tok.Length, Message: tok.File.Session.Tr(format, args...)}
+		d.Notes = append(d.Notes, n)
+	}
+	return d
+}
+
+// Print the diagnostic to `out` like gcc does: its location, severity
+// and message, then the line holding it after a gutter with the line
+// number, with the offending text underlined. Its notes follow.
+//
+//	file.c:3:9: error: undefined variable
+//	    3 |  return x + 1;
+//	      |         ^
+func (d *Diagnostic) Print(out io.Writer) {
+	pos := d.Position()
+	line, column := pos.Line, pos.Column
+	session := d.File.Session
+	color := SeverityColors[d.Severity]
+	message := d.Message
+	switch {
+	case d.Group != "" && d.Severity == SeverityError:
+		message += " [-Werror=" + d.Group + "]"
+	case d.Group != "":
+		message += " [-W" + d.Group + "]"
+	}
+	fmt.Fprintf(out, "%s: %s %s\n", pos, session.Colored(color, session.Translate(d.Severity.String())+":"), message)
+	// The text comes from the contents, which differ from the
+	// original source when it went through an external preprocessor.
+	physical, _ := d.File.physicalPosition(d.Begin)
+	text := d.File.line(physical)
+	fmt.Fprintf(out, "%5d | %s\n", line, text)
+	// Keep the tabs before the offending text so that the
+	// underline stays aligned with it however they are shown.
+	indent := []byte(text[:column-1])
+	for i, c := range indent {
+		if c != '\t' {
+			indent[i] = ' '
+		}
+	}
+	// Underline at least one column, and not past the end of the line.
+	length := d.Length
+	if column-1+length > len(text) {
+		length = len(text) - (column - 1)
+	}
+	if length < 1 {
+		length = 1
+	}
+	underline := "^" + strings.Repeat("~", length-1)
+	fmt.Fprintf(out, "%5s | %s%s\n", "", indent, session.Colored(color, underline))
+	for _, n := range d.Notes {
+		n.Print(out)
+	}
+}
+
+// Return whether any error was recorded for the file.
+func (f *File) Failed() bool {
+	return f.ExitStatus() != 0
+}
+
+// Return the exit status of the errors recorded for the file,
+// that of the earliest phase, or 0 if there is none.
+func (f *File) ExitStatus() int {
+	return ErrorList(f.Diagnostics).ExitStatus()
+}
+
+// Return the errors recorded for the file in source
+// order as an ErrorList, or nil if there is none.
+func (f *File) Err() error {
+	var errs ErrorList
+	for _, d := range f.sortedDiagnostics() {
+		if d.Severity == SeverityError {
+			errs = append(errs, d)
+		}
+	}
+	if errs == nil {
+		return nil
+	}
+	return errs
+}
+
+// Print the diagnostics recorded for the file to `out` in source order.
+func (f *File) PrintDiagnostics(out io.Writer) {
+	for _, d := range f.sortedDiagnostics() {
+		d.Print(out)
+	}
+}
+
+// Sort the diagnostics recorded for the file in source order and return them.
+func (f *File) sortedDiagnostics() []*Diagnostic {
+	sort.SliceStable(f.Diagnostics, func(i, j int) bool {
+		return f.Diagnostics[i].Begin < f.Diagnostics[j].Begin
+	})
+	return f.Diagnostics
+}
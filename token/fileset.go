@@ -0,0 +1,194 @@
+package token
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Source files
+//
+// A file set owns the contents of the files of a compilation and maps
+// offsets in them to lines and columns, like go/token.FileSet. The
+// bytes of all the files are numbered one after the other, so that a
+// Pos designates a byte in any of them without naming the file.
+// Within a file, tokens and diagnostics keep plain offsets, which
+// File.Pos and File.Offset convert.
+//
+// Files produced by an external preprocessor hold line markers telling
+// which line of which file the lines following them come from. The
+// lexer records them with AddLineInfo, so that positions are those of
+// the original source, like #line directives do for go/token.
+//
+// A file set is not safe for concurrent use. Each session has its own.
+
+// Position of a byte in a file set: the base of its file plus its
+// offset in the file. NoPos, the zero value, is no position at all.
+type Pos int
+
+const NoPos Pos = 0
+
+// Whether the position designates a byte.
+func (p Pos) IsValid() bool {
+	return p != NoPos
+}
+
+// Location of a byte in a source file, as shown to the user.
+type Position struct {
+	Filename string
+	Offset   int // 0-based offset in bytes
+	Line     int // 1-based line
+	Column   int // 1-based column, in bytes
+}
+
+// Whether the position designates a byte.
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// Return the position as "file:line:column", like diagnostics show it.
+func (p Position) String() string {
+	if !p.IsValid() {
+		return p.Filename
+	}
+	return fmt.Sprintf("%s:%d:%d", p.Filename, p.Line, p.Column)
+}
+
+type FileSet struct {
+	base  int     // Base of the next file added
+	files []*File // In the order they were added, so by increasing base
+}
+
+func NewFileSet() *FileSet {
+	return &FileSet{base: 1}
+}
+
+// Add a file named `name` holding `contents` to the set, read from
+// `path`, which is empty for programs given with -e and the standard
+// input. The file follows the files added before it.
+func (s *FileSet) AddFile(name string, path string, contents string) *File {
+	f := &File{Name: name, Path: path, Contents: contents, base: s.base, lines: []int{0}}
+	for i := 0; i < len(contents); i++ {
+		if contents[i] == '\n' {
+			f.lines = append(f.lines, i+1)
+		}
+	}
+	// The end of the file has a position too, that of EOF.
+	s.base += len(contents) + 1
+	s.files = append(s.files, f)
+	return f
+}
+
+// Read a file named `name` from `r` and add it to the set like AddFile.
+func (s *FileSet) ReadFile(name string, path string, r io.Reader) (*File, error) {
+	contents, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return s.AddFile(name, path, string(contents)), nil
+}
+
+// Return the files of the set, in the order they were added.
+func (s *FileSet) Files() []*File {
+	return s.files
+}
+
+// Return the file holding the byte at `p`, or nil if there is none.
+func (s *FileSet) File(p Pos) *File {
+	i := sort.Search(len(s.files), func(i int) bool { return s.files[i].base > int(p) }) - 1
+	if i < 0 || int(p) > s.files[i].base+len(s.files[i].Contents) {
+		return nil
+	}
+	return s.files[i]
+}
+
+// Return the location of the byte at `p`, or the zero
+// Position if `p` is not in any file of the set.
+func (s *FileSet) Position(p Pos) Position {
+	f := s.File(p)
+	if f == nil {
+		return Position{}
+	}
+	return f.PositionFor(f.Offset(p))
+}
+
+// A source file, or a program given with -e.
+type File struct {
+	Name        string        // Name used in diagnostics and debug information
+	Path        string        // Path the program was read from, empty for -e and stdin
+	Contents    string        // Source text, which must not change once the file is added
+	Session     *Session      // Session compiling the file
+	Diagnostics []*Diagnostic // Errors and warnings found in the file so far
+
+	base  int        // Position of the first byte in the file set
+	lines []int      // Offsets at which each line starts
+	infos []lineInfo // Line markers, by increasing offset
+}
+
+// A line marker: the line starting at Offset is Line of Filename.
+type lineInfo struct {
+	Offset   int
+	Filename string
+	Line     int
+}
+
+// Record that the line starting at `offset` is the 1-based `line` of the
+// file named `filename`, and so are the lines following it until the
+// next line info. Line infos must be added by increasing offset.
+func (f *File) AddLineInfo(offset int, filename string, line int) {
+	f.infos = append(f.infos, lineInfo{offset, filename, line})
+}
+
+// Return the position in the file set of the byte at `offset`.
+func (f *File) Pos(offset int) Pos {
+	return Pos(f.base + offset)
+}
+
+// Return the offset in the file of the position `p`.
+func (f *File) Offset(p Pos) int {
+	return int(p) - f.base
+}
+
+// Return the 1-based line and column of the byte at `begin`, in the
+// original source if line infos were recorded.
+func (f *File) Position(begin int) (line int, column int) {
+	_, line, column = f.position(begin)
+	return
+}
+
+// Return the location of the byte at `offset`.
+func (f *File) PositionFor(offset int) Position {
+	filename, line, column := f.position(offset)
+	return Position{Filename: filename, Offset: offset, Line: line, Column: column}
+}
+
+// Return the file name, line and column of the byte at `offset`,
+// adjusted by the line info before it, if any.
+func (f *File) position(offset int) (filename string, line int, column int) {
+	line, column = f.physicalPosition(offset)
+	i := sort.Search(len(f.infos), func(i int) bool { return f.infos[i].Offset > offset }) - 1
+	if i < 0 {
+		return f.Name, line, column
+	}
+	info := f.infos[i]
+	start, _ := f.physicalPosition(info.Offset)
+	return info.Filename, info.Line + line - start, column
+}
+
+// Return the 1-based line and column of the byte at `offset` in the
+// contents of the file, regardless of line infos.
+func (f *File) physicalPosition(offset int) (line int, column int) {
+	line = sort.Search(len(f.lines), func(i int) bool { return f.lines[i] > offset })
+	column = offset - f.lines[line-1] + 1
+	return
+}
+
+// Return the text of the 1-based `line`, without its newline.
+func (f *File) line(line int) string {
+	start := f.lines[line-1]
+	end := len(f.Contents)
+	if line < len(f.lines) {
+		end = f.lines[line] - 1
+	}
+	return f.Contents[start:end]
+}
@@ -0,0 +1,46 @@
+package token
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFileSet(t *testing.T) {
+	s := NewFileSet()
+	a := s.AddFile("a.c", "a.c", "int x;\nreturn x;\n")
+	b, err := s.ReadFile("<stdin>", "", strings.NewReader("return 1;"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		file   *File
+		offset int
+		want   string
+	}{
+		{a, 0, "a.c:1:1"},
+		{a, 4, "a.c:1:5"},
+		{a, 7, "a.c:2:1"},
+		{a, 16, "a.c:2:10"},
+		{a, len(a.Contents), "a.c:3:1"}, // EOF
+		{b, 0, "<stdin>:1:1"},
+		{b, 7, "<stdin>:1:8"},
+		{b, len(b.Contents), "<stdin>:1:10"},
+	} {
+		p := c.file.Pos(c.offset)
+		if f := s.File(p); f != c.file {
+			t.Errorf("File(%d) is %v, want %s", p, f, c.file.Name)
+		}
+		if got := s.Position(p).String(); got != c.want {
+			t.Errorf("Position(%d) = %s, want %s", p, got, c.want)
+		}
+		if got := c.file.Offset(p); got != c.offset {
+			t.Errorf("%s: Offset(Pos(%d)) = %d", c.file.Name, c.offset, got)
+		}
+	}
+	if f := s.File(NoPos); f != nil {
+		t.Errorf("File(NoPos) is %s", f.Name)
+	}
+	if p := s.Position(b.Pos(len(b.Contents) + 1)); p.IsValid() {
+		t.Errorf("position past the last file is %s", p)
+	}
+}
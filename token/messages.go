@@ -0,0 +1,233 @@
+package token
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Message catalogs
+//
+// Messages are written in English where they are reported, and go
+// through Session.Translate, which looks them up in the catalog of the
+// language selected with --lang or, by default, with the LC_ALL,
+// LC_MESSAGES and LANG environment variables, like gettext does.
+// English is the default, and messages missing from a catalog are
+// printed in English. Messages from the assembler, the linker and the
+// Go runtime, and the names of options, types and operators within
+// messages, are not translated.
+
+// Return the language selected by the locale environment variables.
+func languageFromEnvironment() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if locale := os.Getenv(name); locale != "" {
+			return LanguageFor(locale)
+		}
+	}
+	return ""
+}
+
+// Return the language of `locale`, such as "fr" for "fr_FR.UTF-8",
+// if it has a catalog, and "" for English otherwise.
+func LanguageFor(locale string) string {
+	if i := strings.IndexAny(locale, "_.@"); i >= 0 {
+		locale = locale[:i]
+	}
+	if _, ok := catalogs[locale]; !ok {
+		return ""
+	}
+	return locale
+}
+
+// Return the translation of the English `message`.
+func (s *Session) Translate(message string) string {
+	if translation, ok := catalogs[s.Language][message]; ok {
+		return translation
+	}
+	return message
+}
+
+// Format the translation of the English `format` with `args`.
+func (s *Session) Tr(format string, args ...any) string {
+	return fmt.Sprintf(s.Translate(format), args...)
+}
+
+// Translations of the messages, by language and English message.
+var catalogs = map[string]map[string]string{
+	"fr": {
+		"error":   "erreur",
+		"warning": "attention",
+		"note":    "note",
+		"integer constant is too large for its type": "la constante entière est trop grande pour son type",
+		"invalid token":                                               "symbole invalide",
+		"unsupported operator \"%s\"":                                 "opérateur « %s » non pris en charge",
+		"unterminated comment":                                        "commentaire non terminé",
+		"\"//\" comments require -std=c99 or later":                   "les commentaires « // » nécessitent -std=c99 ou plus récent",
+		"\"for\" loop initial declarations require -std=c99 or later": "les déclarations initiales de boucle « for » nécessitent -std=c99 ou plus récent",
+		"declaration of \"%s\" shadows a previous local":              "la déclaration de « %s » masque une variable locale précédente",
+		"redefinition of \"%s\"":                                      "redéfinition de « %s »",
+		"previous declaration is here":                                "la déclaration précédente est ici",
+		"invalid operands":                                            "opérandes invalides",
+		"unused variable \"%s\"":                                      "variable « %s » inutilisée",
+		"variable \"%s\" set but not used":                            "variable « %s » affectée mais non utilisée",
+		"control reaches end of non-void function":                    "le contrôle atteint la fin d'une fonction non void",
+		"expected \"%s\"":                                             "« %s » attendu",
+		"expected a variable name":                                    "nom de variable attendu",
+		"expected an identifier":                                      "identificateur attendu",
+		"expected an expression":                                      "expression attendue",
+		"undefined variable":                                          "variable non définie",
+		"division by zero":                                            "division par zéro",
+		"lvalue required as left operand of assignment":               "une lvalue est requise comme opérande gauche de l'affectation",
+		"lvalue required as unary \"&\" operand":                      "une lvalue est requise comme opérande de l'opérateur unaire « & »",
+		"invalid pointer dereference":                                 "déréférencement de pointeur invalide",
+		"initialization":                                              "initialisation",
+		"assignment":                                                  "affectation",
+		"%s of \"%s\" from \"%s\"":                                    "%s de « %s » à partir de « %s »",
+		"returning \"%s\" from a function with return type \"%s\"":    "renvoi de « %s » depuis une fonction dont le type de retour est « %s »",
+		"incompatible pointer types in %s":                            "types de pointeurs incompatibles dans %s",
+		"%s makes pointer from integer without a cast":                "%s transforme un entier en pointeur sans conversion explicite",
+		"%s makes integer from pointer without a cast":                "%s transforme un pointeur en entier sans conversion explicite",
+		"not addressable":                                             "l'adresse ne peut pas être prise",
+		"cannot read the standard input: %v":                          "impossible de lire l'entrée standard : %v",
+		"cannot read %s: %v":                                          "impossible de lire %s : %v",
+		"-emit-llvm requires -S":                                      "-emit-llvm nécessite -S",
+		"target \"%s\" requires -S":                                   "la cible « %s » nécessite -S",
+		"cannot specify -o with -S or -c and multiple files":          "impossible d'utiliser -o avec -S ou -c et plusieurs fichiers",
+		"cannot specify -MF or -MT with multiple files":               "impossible d'utiliser -MF ou -MT avec plusieurs fichiers",
+		"cannot specify -run with multiple files":                     "impossible d'utiliser -run avec plusieurs fichiers",
+		"cannot use -flisting with -emit-llvm":                        "impossible d'utiliser -flisting avec -emit-llvm",
+		"cannot use -flisting with -emit-qbe":                         "impossible d'utiliser -flisting avec -emit-qbe",
+		"cannot use -emit-qbe with -emit-llvm":                        "impossible d'utiliser -emit-qbe avec -emit-llvm",
+		"target \"%s\" does not support -emit-qbe":                    "la cible « %s » ne prend pas en charge -emit-qbe",
+		"qbe failed: %v":                                              "échec de qbe : %v",
+		"target \"%s\" does not support -flisting":                    "la cible « %s » ne prend pas en charge -flisting",
+		"cannot use -w with the standard input":                       "impossible d'utiliser -w avec l'entrée standard",
+		"removed %s":                                                  "supprimé %s",
+		"added %s":                                                    "ajouté %s",
+		"changed %s to %s":                                            "changé %s en %s",
+		"%s: linker input unused because linking not done":            "%s : fichier d'entrée de l'éditeur de liens inutilisé car l'édition de liens n'est pas faite",
+		"the integrated assembler does not support target \"%s\"":     "l'assembleur intégré ne prend pas en charge la cible « %s »",
+		"assembler: %v":                                               "assembleur : %v",
+		"assembler failed: %v":                                        "échec de l'assembleur : %v",
+		"preprocessor failed: %v":                                     "échec du préprocesseur : %v",
+		"linker failed: %v":                                           "échec de l'éditeur de liens : %v",
+		"cannot find the C runtime objects":                           "impossible de trouver les objets de démarrage du C",
+		"the program timed out":                                       "le programme a dépassé le délai",
+		"the program was killed by a signal":                          "le programme a été tué par un signal",
+		"unknown warning option \"%s\"":                               "option d'avertissement « %s » inconnue",
+		"unrecognized command-line option \"%s\"":                     "option de ligne de commande « %s » non reconnue",
+		"unsupported optimization level \"%s\"":                       "niveau d'optimisation « %s » non pris en charge",
+		"unknown target \"%s\"":                                       "cible « %s » inconnue",
+		"serving on http://%s":                                        "en service sur http://%s",
+		"method %s not allowed":                                       "méthode %s non autorisée",
+		"expected \"%s<kind> <name>\" before a constant":              "« %s<type> <nom> » attendu avant une constante",
+		"%s is not a string literal":                                  "%s n'est pas une chaîne littérale",
+		"unknown kind of program \"%s\"":                              "type de programme « %s » inconnu",
+		"unknown language \"%s\"":                                     "langue « %s » inconnue",
+		"response file \"%s\" includes itself":                        "le fichier de réponse « %s » s'inclut lui-même",
+		"backslash at the end of the file":                            "barre oblique inverse à la fin du fichier",
+		"missing terminating %c":                                      "%c de fin manquant",
+		"internal compiler error":                                     "erreur interne du compilateur",
+		"while %s%s":                                                  "pendant %s%s",
+		", in %s":                                                     ", dans %s",
+		", at %s:%d:%d":                                               ", à %s:%d:%d",
+		"tokenizing":                                                  "l'analyse lexicale",
+		"parsing":                                                     "l'analyse syntaxique",
+		"optimizing":                                                  "l'optimisation",
+		"generating code":                                             "la génération de code",
+		"interpreting":                                                "l'interprétation",
+		"assembling":                                                  "l'assemblage",
+		"linking":                                                     "l'édition de liens",
+		"gocc version %s":                                             "gocc version %s",
+		"The state of the compiler was written to %s.":                "L'état du compilateur a été écrit dans %s.",
+		"Please report this bug at %s,\nwith the input and the file above.": "Veuillez signaler ce bogue à %s,\navec l'entrée et le fichier ci-dessus.",
+	},
+	"es": {
+		"error":   "error",
+		"warning": "aviso",
+		"note":    "nota",
+		"integer constant is too large for its type": "la constante entera es demasiado grande para su tipo",
+		"invalid token":                                               "símbolo no válido",
+		"unsupported operator \"%s\"":                                 "operador «%s» no admitido",
+		"unterminated comment":                                        "comentario sin terminar",
+		"\"//\" comments require -std=c99 or later":                   "los comentarios «//» requieren -std=c99 o posterior",
+		"\"for\" loop initial declarations require -std=c99 or later": "las declaraciones iniciales de bucles «for» requieren -std=c99 o posterior",
+		"declaration of \"%s\" shadows a previous local":              "la declaración de «%s» oculta una variable local previa",
+		"redefinition of \"%s\"":                                      "redefinición de «%s»",
+		"previous declaration is here":                                "la declaración previa está aquí",
+		"invalid operands":                                            "operandos no válidos",
+		"unused variable \"%s\"":                                      "variable «%s» sin usar",
+		"variable \"%s\" set but not used":                            "se asigna la variable «%s» pero no se usa",
+		"control reaches end of non-void function":                    "el control alcanza el final de una función que no es void",
+		"expected \"%s\"":                                             "se esperaba «%s»",
+		"expected a variable name":                                    "se esperaba un nombre de variable",
+		"expected an identifier":                                      "se esperaba un identificador",
+		"expected an expression":                                      "se esperaba una expresión",
+		"undefined variable":                                          "variable no definida",
+		"division by zero":                                            "división por cero",
+		"lvalue required as left operand of assignment":               "se requiere un l-valor como operando izquierdo de la asignación",
+		"lvalue required as unary \"&\" operand":                      "se requiere un l-valor como operando del «&» unario",
+		"invalid pointer dereference":                                 "desreferencia de puntero no válida",
+		"initialization":                                              "inicialización",
+		"assignment":                                                  "asignación",
+		"%s of \"%s\" from \"%s\"":                                    "%s de «%s» desde «%s»",
+		"returning \"%s\" from a function with return type \"%s\"":    "se devuelve «%s» desde una función con tipo de retorno «%s»",
+		"incompatible pointer types in %s":                            "tipos de puntero incompatibles en %s",
+		"%s makes pointer from integer without a cast":                "%s crea un puntero desde un entero sin una conversión",
+		"%s makes integer from pointer without a cast":                "%s crea un entero desde un puntero sin una conversión",
+		"not addressable":                                             "no se puede tomar la dirección",
+		"cannot read the standard input: %v":                          "no se puede leer la entrada estándar: %v",
+		"cannot read %s: %v":                                          "no se puede leer %s: %v",
+		"-emit-llvm requires -S":                                      "-emit-llvm requiere -S",
+		"target \"%s\" requires -S":                                   "el objetivo «%s» requiere -S",
+		"cannot specify -o with -S or -c and multiple files":          "no se puede especificar -o con -S o -c y varios ficheros",
+		"cannot specify -MF or -MT with multiple files":               "no se puede especificar -MF o -MT con varios ficheros",
+		"cannot specify -run with multiple files":                     "no se puede especificar -run con varios ficheros",
+		"cannot use -flisting with -emit-llvm":                        "no se puede usar -flisting con -emit-llvm",
+		"cannot use -flisting with -emit-qbe":                         "no se puede usar -flisting con -emit-qbe",
+		"cannot use -emit-qbe with -emit-llvm":                        "no se puede usar -emit-qbe con -emit-llvm",
+		"target \"%s\" does not support -emit-qbe":                    "el objetivo «%s» no admite -emit-qbe",
+		"qbe failed: %v":                                              "falló qbe: %v",
+		"target \"%s\" does not support -flisting":                    "el objetivo «%s» no admite -flisting",
+		"cannot use -w with the standard input":                       "no se puede usar -w con la entrada estándar",
+		"removed %s":                                                  "eliminado %s",
+		"added %s":                                                    "añadido %s",
+		"changed %s to %s":                                            "cambiado %s por %s",
+		"%s: linker input unused because linking not done":            "%s: no se usa la entrada del enlazador porque no se enlaza",
+		"the integrated assembler does not support target \"%s\"":     "el ensamblador integrado no admite el objetivo «%s»",
+		"assembler: %v":                                               "ensamblador: %v",
+		"assembler failed: %v":                                        "falló el ensamblador: %v",
+		"preprocessor failed: %v":                                     "falló el preprocesador: %v",
+		"linker failed: %v":                                           "falló el enlazador: %v",
+		"cannot find the C runtime objects":                           "no se encuentran los objetos de arranque de C",
+		"the program timed out":                                       "el programa superó el tiempo límite",
+		"the program was killed by a signal":                          "el programa fue terminado por una señal",
+		"unknown warning option \"%s\"":                               "opción de aviso «%s» desconocida",
+		"unrecognized command-line option \"%s\"":                     "no se reconoce la opción de línea de órdenes «%s»",
+		"unsupported optimization level \"%s\"":                       "no se admite el nivel de optimización «%s»",
+		"unknown target \"%s\"":                                       "objetivo «%s» desconocido",
+		"serving on http://%s":                                        "sirviendo en http://%s",
+		"method %s not allowed":                                       "método %s no permitido",
+		"expected \"%s<kind> <name>\" before a constant":              "se esperaba «%s<tipo> <nombre>» antes de una constante",
+		"%s is not a string literal":                                  "%s no es una cadena literal",
+		"unknown kind of program \"%s\"":                              "tipo de programa «%s» desconocido",
+		"unknown language \"%s\"":                                     "idioma «%s» desconocido",
+		"response file \"%s\" includes itself":                        "el fichero de respuesta «%s» se incluye a sí mismo",
+		"backslash at the end of the file":                            "barra invertida al final del fichero",
+		"missing terminating %c":                                      "falta el carácter %c de terminación",
+		"internal compiler error":                                     "error interno del compilador",
+		"while %s%s":                                                  "durante %s%s",
+		", in %s":                                                     ", en %s",
+		", at %s:%d:%d":                                               ", en %s:%d:%d",
+		"tokenizing":                                                  "el análisis léxico",
+		"parsing":                                                     "el análisis sintáctico",
+		"optimizing":                                                  "la optimización",
+		"generating code":                                             "la generación de código",
+		"interpreting":                                                "la interpretación",
+		"assembling":                                                  "el ensamblado",
+		"linking":                                                     "el enlazado",
+		"gocc version %s":                                             "gocc versión %s",
+		"The state of the compiler was written to %s.":                "El estado del compilador se escribió en %s.",
+		"Please report this bug at %s,\nwith the input and the file above.": "Por favor, informe de este error en %s,\ncon la entrada y el fichero anterior.",
+	},
+}
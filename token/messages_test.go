@@ -0,0 +1,59 @@
+package token
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Formatting verbs, such as %s and %d.
+var verbPattern = regexp.MustCompile(`%[^%]`)
+
+// Check that each translation formats the same arguments as its
+// English message, and that each English message is still reported.
+func TestCatalogs(t *testing.T) {
+	var paths []string
+	for _, pattern := range []string{"../*/*.go", "../cmd/*/*.go"} {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, matches...)
+	}
+	var sources strings.Builder
+	for _, path := range paths {
+		if strings.HasSuffix(path, "_test.go") || filepath.Base(path) == "messages.go" {
+			continue
+		}
+		src, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sources.Write(src)
+	}
+	for language, catalog := range catalogs {
+		for message, translation := range catalog {
+			if !strings.Contains(sources.String(), strconv.Quote(message)) {
+				t.Errorf("%s: %q is not a message", language, message)
+			}
+			verbs := verbPattern.FindAllString(message, -1)
+			translated := verbPattern.FindAllString(translation, -1)
+			if strings.Join(verbs, " ") != strings.Join(translated, " ") {
+				t.Errorf("%s: %q formats %v, but %q formats %v", language, message, verbs, translation, translated)
+			}
+		}
+	}
+}
+
+func TestLanguageFor(t *testing.T) {
+	for locale, want := range map[string]string{
+		"fr_FR.UTF-8": "fr", "fr": "fr", "es_ES@euro": "es", "de_DE.UTF-8": "", "C": "", "POSIX": "", "": "",
+	} {
+		if got := LanguageFor(locale); got != want {
+			t.Errorf("languageFor(%q) = %q, want %q", locale, got, want)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package token
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// Reporting
+//
+// The lexer, the parser and the type checker only record diagnostics,
+// on the file they were found in. Rendering them is up to the Reporter
+// of the session, which File.Report hands those of a file to once the
+// front end is done with it, in source order and with their notes
+// attached. The driver prints them with a ConsoleReporter by default,
+// and writes them as JSON with -fdiagnostics-format=json. Tests and
+// programs embedding gocc collect them with a MemoryReporter instead.
+
+// A destination of diagnostics.
+type Reporter interface {
+	Report(d *Diagnostic)
+}
+
+// Reporter printing diagnostics like gcc does, see Diagnostic.Print.
+// They are colored if the session of their file says so.
+type ConsoleReporter struct {
+	Out io.Writer
+}
+
+func (r *ConsoleReporter) Report(d *Diagnostic) {
+	d.Print(r.Out)
+}
+
+// Reporter writing each diagnostic as a JSON object on a line of its
+// own, with the fields of gcc's -fdiagnostics-format=json:
+//
+//	{"kind":"warning","message":"unused variable \"a\"","option":"-Wunused-variable",
+//	 "locations":[{"caret":{"file":"a.c","line":1,"column":5},
+//	 "finish":{"file":"a.c","line":1,"column":5}}],"children":[]}
+//
+// "finish" is the last byte of the offending text, and "children"
+// holds the notes. Messages are in the language of the session.
+type JSONReporter struct {
+	Out io.Writer
+}
+
+type jsonDiagnostic struct {
+	Kind      string            `json:"kind"`
+	Message   string            `json:"message"`
+	Option    string            `json:"option,omitempty"`
+	Locations []jsonLocation    `json:"locations"`
+	Children  []*jsonDiagnostic `json:"children"`
+}
+
+type jsonLocation struct {
+	Caret  jsonPosition `json:"caret"`
+	Finish jsonPosition `json:"finish"`
+}
+
+type jsonPosition struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+func (r *JSONReporter) Report(d *Diagnostic) {
+	line, _ := json.Marshal(toJSON(d))
+	r.Out.Write(append(line, '\n'))
+}
+
+func toJSON(d *Diagnostic) *jsonDiagnostic {
+	position := func(offset int) jsonPosition {
+		pos := d.File.PositionFor(offset)
+		return jsonPosition{pos.Filename, pos.Line, pos.Column}
+	}
+	last := d.Begin
+	if d.Length > 1 {
+		last += d.Length - 1
+	}
+	j := &jsonDiagnostic{
+		Kind:      d.Severity.String(),
+		Message:   d.Message,
+		Locations: []jsonLocation{{position(d.Begin), position(last)}},
+		Children:  []*jsonDiagnostic{},
+	}
+	switch {
+	case d.Group != "" && d.Severity == SeverityError:
+		j.Option = "-Werror=" + d.Group
+	case d.Group != "":
+		j.Option = "-W" + d.Group
+	}
+	for _, n := range d.Notes {
+		j.Children = append(j.Children, toJSON(n))
+	}
+	return j
+}
+
+// Reporter keeping diagnostics in memory, in the order they are reported.
+type MemoryReporter struct {
+	Diagnostics []*Diagnostic
+}
+
+func (r *MemoryReporter) Report(d *Diagnostic) {
+	r.Diagnostics = append(r.Diagnostics, d)
+}
+
+// Report the diagnostics recorded for the file since it was last
+// reported to the reporter of its session, in source order. A session
+// without a reporter discards them.
+func (f *File) Report() {
+	for _, d := range f.sortedDiagnostics() {
+		if d.reported {
+			continue
+		}
+		d.reported = true
+		if f.Session.Reporter != nil {
+			f.Session.Reporter.Report(d)
+		}
+	}
+}
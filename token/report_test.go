@@ -0,0 +1,46 @@
+package token
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestReport(t *testing.T) {
+	memory := &MemoryReporter{}
+	s := NewSession()
+	s.Language = ""
+	s.Warnings["shadow"] = true
+	s.Reporter = memory
+	f := s.AddFile("a.c", "a.c", "int a;\nint a;\nreturn b;\n")
+	first, second, undefined := NewToken(f, IDENT, 4, 5), NewToken(f, IDENT, 11, 12), NewToken(f, IDENT, 21, 22)
+	undefined.SemanticErrorf("undefined variable")
+	second.Warnf("shadow", "declaration of \"%s\" shadows a previous local", "a").
+		Note(&first, "previous declaration is here")
+
+	f.Report()
+	f.Report()
+	if len(memory.Diagnostics) != 2 {
+		t.Fatalf("%d diagnostics reported, want 2", len(memory.Diagnostics))
+	}
+	if d := memory.Diagnostics[0]; d.Severity != SeverityWarning || len(d.Notes) != 1 {
+		t.Errorf("first diagnostic reported is %q, want the warning with its note", d.Error())
+	}
+	if d := memory.Diagnostics[1]; d.Error() != "a.c:3:8: error: undefined variable" {
+		t.Errorf("second diagnostic reported is %q", d.Error())
+	}
+	first.Errorf("expected \"%s\"", ";")
+	f.Report()
+	if len(memory.Diagnostics) != 3 || memory.Diagnostics[2].Position().Offset != 4 {
+		t.Errorf("the diagnostic recorded after the others was not reported alone")
+	}
+
+	var out bytes.Buffer
+	(&JSONReporter{&out}).Report(memory.Diagnostics[0])
+	want := `{"kind":"warning","message":"declaration of \"a\" shadows a previous local","option":"-Wshadow",` +
+		`"locations":[{"caret":{"file":"a.c","line":2,"column":5},"finish":{"file":"a.c","line":2,"column":5}}],` +
+		`"children":[{"kind":"note","message":"previous declaration is here",` +
+		`"locations":[{"caret":{"file":"a.c","line":1,"column":5},"finish":{"file":"a.c","line":1,"column":5}}],"children":[]}]}` + "\n"
+	if out.String() != want {
+		t.Errorf("JSON\n%s\nwant\n%s", out.String(), want)
+	}
+}
@@ -0,0 +1,113 @@
+package token
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"github.com/youngfr/gocc/arena"
+)
+
+// Sessions
+//
+// A session holds what the phases of a compilation share: the files it
+// reads, the options changing how they are read, checked and diagnosed,
+// and how far the compilation got. Files point to the session compiling
+// them, so each phase finds it through the file or the tokens it works
+// on. Nothing else in the compiler is both mutable and global, so
+// compilations in different sessions may run at the same time.
+
+type Session struct {
+	Files *FileSet // Files of the compilation
+
+	Standard         string          // Language standard selected with -std=, a key of Standards
+	Language         string          // Language of the messages, a key of `catalogs`, or "" for English
+	Color            bool            // Whether diagnostics are colored
+	Warnings         map[string]bool // Warning groups, and whether each is enabled
+	SuppressWarnings bool            // Whether -w was given, which disables all warnings
+	WarningsAsErrors bool            // Whether -Werror was given, which turns warnings into errors
+	Reporter         Reporter        // Destination of the diagnostics of the files, see File.Report
+
+	// Where --trace-parse logs entering and leaving each grammar
+	// function of the parser, or nil without it.
+	Trace io.Writer
+
+	// Whether --verify was given, which checks the AST for internal
+	// errors after parsing and after each AST pass.
+	VerifyAST bool
+
+	// First token of the statement being compiled, if any,
+	// for the report of an internal compiler error.
+	CurrentStatement *Token
+
+	// Arenas of the nodes of the files, see Free.
+	Arenas arena.Set
+}
+
+// Return a session with the defaults of the command line: gcc's
+// standard and warnings, the messages of the language of the locale,
+// and diagnostics printed to standard error, colored if it is a terminal.
+func NewSession() *Session {
+	s := &Session{
+		Standard: "gnu17",
+		Language: languageFromEnvironment(),
+		Color:    ColorFor("auto"),
+		Warnings: map[string]bool{},
+		Reporter: &ConsoleReporter{Out: os.Stderr},
+		Files:    NewFileSet(),
+	}
+	for group, enabled := range defaultWarnings {
+		s.Warnings[group] = enabled
+	}
+	return s
+}
+
+// Values accepted for -std=, and the year of the C standard each is
+// based on. The gnu ones also allow GNU extensions to it.
+var Standards = map[string]int{
+	"c89": 1989, "c90": 1989, "c99": 1999, "c11": 2011, "c17": 2017, "c18": 2017,
+	"gnu89": 1989, "gnu90": 1989, "gnu99": 1999, "gnu11": 2011, "gnu17": 2017, "gnu18": 2017,
+}
+
+// Return whether the selected standard includes that of `year`, such as 1999.
+func (s *Session) StandardAtLeast(year int) bool {
+	return Standards[s.Standard] >= year
+}
+
+// Return whether the selected standard allows GNU extensions.
+func (s *Session) GNUExtensions() bool {
+	return strings.HasPrefix(s.Standard, "gnu")
+}
+
+// Add a file holding `contents` to the files of the session,
+// see FileSet.AddFile.
+func (s *Session) AddFile(name string, path string, contents string) *File {
+	f := s.Files.AddFile(name, path, contents)
+	f.Session = s
+	return f
+}
+
+// Read a file from `r` and add it to the files of the session,
+// see FileSet.ReadFile.
+func (s *Session) ReadFile(name string, path string, r io.Reader) (*File, error) {
+	f, err := s.Files.ReadFile(name, path, r)
+	if err == nil {
+		f.Session = s
+	}
+	return f, err
+}
+
+// Free the nodes of the files once the compilation is over,
+// see the arena package. Those still in use remain valid.
+func (s *Session) Free() {
+	s.Arenas.Free()
+}
+
+// Return the arenas of the session of the file, or nil if it has none,
+// from which arena.Arena.New allocates on the heap.
+func (f *File) Arenas() *arena.Set {
+	if f.Session == nil {
+		return nil
+	}
+	return &f.Session.Arenas
+}
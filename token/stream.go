@@ -0,0 +1,70 @@
+package token
+
+// Token streams
+//
+// A stream reads the tokens made by the lexer from front to back, for
+// parsers to consume them without threading the rest of the tokens
+// through each grammar function. The tokens are a slice ending with EOF,
+// where each token knows its Index, so looking any number of tokens
+// ahead takes constant time. The stream never moves past EOF, so looking
+// ahead or reading at the end gives EOF again. The tokens are not
+// changed, so a parser trying one way to parse what follows can Mark the
+// position and Reset the stream to it to try another.
+
+type Stream struct {
+	tokens []Token // Ending with EOF
+	pos    int     // Index of the next token to be read
+}
+
+// Return a stream reading `tokens`, which end with EOF.
+func NewStream(tokens []Token) *Stream {
+	return &Stream{tokens: tokens}
+}
+
+// Return the token `n` tokens ahead without consuming anything. Peek(0)
+// is the next token to be read.
+func (s *Stream) Peek(n int) *Token {
+	if i := s.pos + n; i < len(s.tokens) {
+		return &s.tokens[i]
+	}
+	return &s.tokens[len(s.tokens)-1]
+}
+
+// Consume the next token and return it.
+func (s *Stream) Next() *Token {
+	tok := &s.tokens[s.pos]
+	if tok.Kind != EOF {
+		s.pos++
+	}
+	return tok
+}
+
+// Consume the next token and return it if it is `lexeme`, or return nil.
+func (s *Stream) Accept(lexeme string) *Token {
+	if s.tokens[s.pos].Lexeme != lexeme {
+		return nil
+	}
+	return s.Next()
+}
+
+// Consume the next token and return it if it is `lexeme`. Otherwise,
+// record a syntax error at it and return it unconsumed with the error.
+func (s *Stream) Expect(lexeme string) (*Token, error) {
+	if tok := s.Accept(lexeme); tok != nil {
+		return tok, nil
+	}
+	tok := &s.tokens[s.pos]
+	return tok, tok.Errorf("expected \"%s\"", lexeme)
+}
+
+// Return the position of the stream, the index of the next token, for
+// Reset to return to.
+func (s *Stream) Mark() int {
+	return s.pos
+}
+
+// Move the stream to `pos`, a position returned by Mark or the Index of
+// any of its tokens, so that it is the next token to be read.
+func (s *Stream) Reset(pos int) {
+	s.pos = pos
+}
@@ -0,0 +1,57 @@
+package token
+
+import "testing"
+
+// Return the tokens of `f`, which are one byte each and separated by
+// a space.
+func tokenList(f *File, kinds ...TokenKind) []Token {
+	var tokens []Token
+	for i, kind := range kinds {
+		tokens = append(tokens, NewToken(f, kind, 2*i, 2*i+1))
+	}
+	tokens = append(tokens, NewToken(f, EOF, len(f.Contents), len(f.Contents)))
+	for i := range tokens {
+		tokens[i].Index = i
+	}
+	return tokens
+}
+
+func TestStream(t *testing.T) {
+	s := NewSession()
+	s.Language = ""
+	f := s.AddFile("a.c", "", "( ) ;")
+	ts := NewStream(tokenList(f, LPAREN, RPAREN, SEMI))
+
+	if tok := ts.Peek(1); tok.Lexeme != ")" {
+		t.Errorf("Peek(1) is %q, want \")\"", tok.Lexeme)
+	}
+	if tok := ts.Peek(10); tok.Kind != EOF {
+		t.Errorf("Peek past the end is %q, want EOF", tok.Lexeme)
+	}
+	if ts.Accept(")") != nil {
+		t.Error("accepted \")\" at \"(\"")
+	}
+	mark := ts.Mark()
+	if tok := ts.Accept("("); tok == nil || tok.Index != mark {
+		t.Fatal("did not accept \"(\"")
+	}
+	if tok, err := ts.Expect(";"); err == nil || tok.Lexeme != ")" {
+		t.Errorf("expected \";\" at \")\" and got %q, %v", tok.Lexeme, err)
+	}
+	if len(f.Diagnostics) != 1 || f.Diagnostics[0].Message != "expected \";\"" {
+		t.Errorf("diagnostics %v, want the error of Expect", f.Diagnostics)
+	}
+	if _, err := ts.Expect(")"); err != nil {
+		t.Errorf("Expect after a failed one: %v", err)
+	}
+	ts.Reset(mark)
+	if tok := ts.Peek(0); tok.Lexeme != "(" {
+		t.Errorf("reset to %q, want \"(\"", tok.Lexeme)
+	}
+	for i := 0; i < 5; i++ {
+		ts.Next()
+	}
+	if tok := ts.Next(); tok.Kind != EOF {
+		t.Errorf("read %q past the end, want EOF", tok.Lexeme)
+	}
+}
@@ -0,0 +1,86 @@
+// Package token defines the tokens of C source files, the files they
+// come from, and the diagnostics reported at them.
+package token
+
+// Tokens
+
+type TokenKind int
+
+const (
+	ADD      TokenKind = iota // +
+	SUB                       // -
+	ASTERISK                  // *
+	DIV                       // /
+	ASG                       // =
+	EQL                       // ==
+	NOT                       // !
+	NEQ                       // !=
+	LSS                       // <
+	LEQ                       // <=
+	GTR                       // >
+	GEQ                       // >=
+	AND                       // &
+	LPAREN                    // (
+	RPAREN                    // )
+	LBRACE                    // {
+	RBRACE                    // }
+	SEMI                      // ;
+	COMMA                     // ,
+	IDENT                     // identifier
+	RETURN                    // return
+	IF                        // if
+	ELSE                      // else
+	FOR                       // for
+	WHILE                     // while
+	INT                       // int
+	NUM                       // number
+	EOF                       // EOF
+)
+
+type Token struct {
+	Kind   TokenKind // Token kind
+	Index  int       // Index in the tokens of its file
+	Value  int       // If kind == NUM, its value
+	Begin  int       // Starting index of lexeme
+	Length int       // Length of lexeme
+	Lexeme string    // A substring in the source that matches the pattern for a token
+	File   *File     // File the token was read from
+}
+
+// Return the 1-based line and column of the token.
+func (t *Token) Position() (line int, column int) {
+	return t.File.Position(t.Begin)
+}
+
+// Return the position of the token in the set of its file.
+func (t *Token) Pos() Pos {
+	return t.File.Pos(t.Begin)
+}
+
+// Return the token of `kind` from `begin` to `end` in `file`. Its Index
+// is left to the slice of tokens it is added to, such as the lexer's.
+func NewToken(file *File, kind TokenKind, begin int, end int) Token {
+	return Token{
+		Kind:   kind,
+		Value:  0,
+		Begin:  begin,
+		Length: end - begin,
+		Lexeme: file.Contents[begin:end],
+		File:   file,
+	}
+}
+
+// Printing, for debugging
+
+var tokenNames = map[TokenKind]string{
+	ADD: "ADD", SUB: "SUB", ASTERISK: "ASTERISK", DIV: "DIV", ASG: "ASG",
+	EQL: "EQL", NOT: "NOT", NEQ: "NEQ", LSS: "LSS", LEQ: "LEQ", GTR: "GTR",
+	GEQ: "GEQ", AND: "AND", LPAREN: "LPAREN", RPAREN: "RPAREN",
+	LBRACE: "LBRACE", RBRACE: "RBRACE", SEMI: "SEMI", COMMA: "COMMA",
+	IDENT: "IDENT", RETURN: "RETURN", IF: "IF", ELSE: "ELSE", FOR: "FOR",
+	WHILE: "WHILE", INT: "INT", NUM: "NUM", EOF: "EOF",
+}
+
+func (kind TokenKind) String() string {
+	return tokenNames[kind]
+}
@@ -0,0 +1,56 @@
+// Package types represents the types of C expressions and variables.
+package types
+
+import (
+	"github.com/youngfr/gocc/token"
+)
+
+// Kind of a type.
+type TypeKind int
+
+const (
+	TPINT TypeKind = iota // int
+	TPPTR                 // pointer
+)
+
+// The type of an expression or variable. Types are compared with
+// Identical, as equal types need not be the same pointer.
+type Type struct {
+	Kind TypeKind     // Type kind
+	Size int          // sizeof() value
+	Base *Type        // Used if kind == TPPTR
+	Name *token.Token // Declaration
+}
+
+// Return whether `t` is int.
+func IsInt(t *Type) bool {
+	return t.Kind == TPINT
+}
+
+// Return the type of pointers to `base`.
+func PointerTo(base *Type) *Type {
+	return &Type{
+		Kind: TPPTR,
+		Size: 8,
+		Base: base,
+	}
+}
+
+// The int type. Declarations name copies of it.
+var Int = &Type{Kind: TPINT, Size: 4}
+
+// Return whether `a` and `b` are the same type.
+func Identical(a *Type, b *Type) bool {
+	if a.Kind != b.Kind {
+		return false
+	}
+	return a.Kind != TPPTR || Identical(a.Base, b.Base)
+}
+
+// Return the type as written in C, such as "int*".
+func (t *Type) String() string {
+	if t.Kind == TPPTR {
+		return t.Base.String() + "*"
+	}
+	return "int"
+}